@@ -0,0 +1,8 @@
+package api
+
+import _ "embed"
+
+// OpenAPISpec — контракт HTTP API сервера в формате OpenAPI 3
+//
+//go:embed openapi.json
+var OpenAPISpec []byte
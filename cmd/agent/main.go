@@ -11,7 +11,12 @@ import (
 	"time"
 
 	"github.com/akorablin/yandex-practicum-metrics/internal/agent"
+	"github.com/akorablin/yandex-practicum-metrics/internal/certs"
 	"github.com/akorablin/yandex-practicum-metrics/internal/config"
+	"github.com/akorablin/yandex-practicum-metrics/internal/encryption"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
 )
 
 func main() {
@@ -31,11 +36,68 @@ func run() error {
 	collector := agent.NewCollector()
 
 	// Создаем "отправщик" метрик
+	var opts []agent.Option
+	transportCreds := insecure.NewCredentials()
+	scheme := "http://"
+	if cfg.TLSCAFile != "" || cfg.TLSCertFile != "" {
+		tlsConfig, err := certs.NewClientTLS(cfg.TLSCAFile, cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to configure tls: %w", err)
+		}
+		opts = append(opts, agent.WithTLS(tlsConfig))
+		transportCreds = credentials.NewTLS(tlsConfig)
+		scheme = "https://"
+	}
 	serverURL := cfg.Address
 	if !strings.Contains(serverURL, "http://") && !strings.Contains(serverURL, "https://") {
-		serverURL = "http://" + serverURL
+		serverURL = scheme + serverURL
+	}
+	if cfg.Key != "" {
+		opts = append(opts, agent.WithKey(cfg.Key))
+	}
+	if cfg.ID != "" {
+		opts = append(opts, agent.WithAgentID(cfg.ID))
+	}
+	if cfg.Compress != "" {
+		opts = append(opts, agent.WithCompression(cfg.Compress))
+	}
+	grpcOpts := []grpc.DialOption{}
+
+	// Адрес исходящего интерфейса нужен серверу для проверки доверенной подсети
+	target := strings.TrimPrefix(strings.TrimPrefix(cfg.Address, "http://"), "https://")
+	if cfg.Transport == "grpc" {
+		target = cfg.GRPCAddress
+	}
+	if ip, err := agent.OutboundIP(target); err != nil {
+		log.Printf("X-Real-IP is not set: %v", err)
+	} else {
+		opts = append(opts, agent.WithRealIP(ip.String()))
+		grpcOpts = append(grpcOpts, grpc.WithPerRPCCredentials(agent.RealIPCredentials(ip.String())))
+	}
+	if cfg.Token != "" {
+		opts = append(opts, agent.WithToken(cfg.Token))
+		grpcOpts = append(grpcOpts, grpc.WithPerRPCCredentials(agent.TokenCredentials(cfg.Token)))
+	}
+	if cfg.ID != "" {
+		grpcOpts = append(grpcOpts, grpc.WithPerRPCCredentials(agent.AgentIDCredentials(cfg.ID)))
+	}
+	if cfg.CryptoKey != "" {
+		publicKey, err := encryption.LoadPublicKey(cfg.CryptoKey)
+		if err != nil {
+			return fmt.Errorf("failed to load crypto key: %w", err)
+		}
+		opts = append(opts, agent.WithEncryption(publicKey))
+	}
+	if cfg.Transport == "grpc" {
+		grpcOpts = append(grpcOpts, grpc.WithTransportCredentials(transportCreds))
+		conn, err := grpc.NewClient(cfg.GRPCAddress, grpcOpts...)
+		if err != nil {
+			return fmt.Errorf("failed to create grpc client: %w", err)
+		}
+		defer conn.Close()
+		opts = append(opts, agent.WithGRPC(conn))
 	}
-	sender := agent.NewSender(serverURL)
+	sender := agent.NewSender(serverURL, opts...)
 
 	// Запускаем агент
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
@@ -79,7 +141,7 @@ func run() error {
 			case <-ticker.C:
 				gauges := collector.GetGauges()
 				counters := collector.GetCounters()
-				err := sender.SendAllMetricsJSON(ctx, gauges, counters)
+				err := sender.SendAll(ctx, gauges, counters)
 				if err != nil {
 					log.Printf("Failed to send metrics after retries: %v", err)
 				} else {
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/certs"
+)
+
+// Выпускает локальный CA и сертификаты сервера и агента для разработки и тестов
+func main() {
+	dir := flag.String("dir", "certs", "output directory")
+	hosts := flag.String("hosts", "localhost,127.0.0.1", "comma-separated server DNS names and IP addresses")
+	validFor := flag.Duration("valid-for", 365*24*time.Hour, "certificate validity period")
+	flag.Parse()
+
+	bundle, err := certs.GenerateDev(strings.Split(*hosts, ","), *validFor)
+	if err != nil {
+		log.Fatalf("Failed to generate certificates: %v", err)
+	}
+	if err := bundle.WriteFiles(*dir); err != nil {
+		log.Fatalf("Failed to write certificates: %v", err)
+	}
+	fmt.Printf("Certificates written to %s\n", *dir)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/encryption"
+)
+
+// keygen выпускает пару ключей RSA: private.pem передаётся серверу (-crypto-key), public.pem — агентам
+func keygen(args []string) error {
+	fs := flag.NewFlagSet("keygen", flag.ExitOnError)
+	dir := fs.String("out", "keys", "output directory")
+	bits := fs.Int("bits", 4096, "rsa key size")
+	fs.Parse(args)
+
+	if *bits < 2048 {
+		return fmt.Errorf("rsa key size must be at least 2048, got %d", *bits)
+	}
+
+	privatePEM, publicPEM, err := encryption.GenerateKeyPair(*bits)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(*dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(*dir, "private.pem"), privatePEM, 0o600); err != nil {
+		return fmt.Errorf("failed to write private key: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(*dir, "public.pem"), publicPEM, 0o644); err != nil {
+		return fmt.Errorf("failed to write public key: %w", err)
+	}
+
+	fmt.Printf("Keys written to %s\n", *dir)
+	return nil
+}
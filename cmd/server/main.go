@@ -8,22 +8,50 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/akorablin/yandex-practicum-metrics/internal/alert"
+	"github.com/akorablin/yandex-practicum-metrics/internal/auth"
+	"github.com/akorablin/yandex-practicum-metrics/internal/certs"
 	"github.com/akorablin/yandex-practicum-metrics/internal/config"
 	"github.com/akorablin/yandex-practicum-metrics/internal/config/db"
 	"github.com/akorablin/yandex-practicum-metrics/internal/config/logger"
+	"github.com/akorablin/yandex-practicum-metrics/internal/encryption"
+	"github.com/akorablin/yandex-practicum-metrics/internal/graphite"
+	"github.com/akorablin/yandex-practicum-metrics/internal/grpcserver"
 	"github.com/akorablin/yandex-practicum-metrics/internal/handler"
+	"github.com/akorablin/yandex-practicum-metrics/internal/health"
+	"github.com/akorablin/yandex-practicum-metrics/internal/history"
 	"github.com/akorablin/yandex-practicum-metrics/internal/middleware"
+	"github.com/akorablin/yandex-practicum-metrics/internal/ratelimit"
 	dbRepo "github.com/akorablin/yandex-practicum-metrics/internal/repository/db"
 	memoryRepo "github.com/akorablin/yandex-practicum-metrics/internal/repository/memory"
+	"github.com/akorablin/yandex-practicum-metrics/internal/selfmetrics"
+	"github.com/akorablin/yandex-practicum-metrics/internal/statsd"
 	"github.com/akorablin/yandex-practicum-metrics/internal/storage"
 	fileStorage "github.com/akorablin/yandex-practicum-metrics/internal/storage/file"
+	"github.com/akorablin/yandex-practicum-metrics/internal/stream"
+	"github.com/akorablin/yandex-practicum-metrics/internal/trusted"
 	"go.uber.org/zap"
 )
 
 func main() {
+	// Подкоманда keygen выпускает ключи для -crypto-key, token — токены доступа
+	if len(os.Args) > 1 && os.Args[1] == "keygen" {
+		if err := keygen(os.Args[2:]); err != nil {
+			log.Fatalf("Failed to generate keys: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "token" {
+		if err := tokengen(os.Args[2:]); err != nil {
+			log.Fatalf("Failed to generate token: %v", err)
+		}
+		return
+	}
+
 	if err := run(); err != nil {
 		panic(err)
 	}
@@ -42,41 +70,137 @@ func run() error {
 		return fmt.Errorf("ошибка инициализации логирования: %w", err)
 	}
 
+	// Собственные метрики сервера: запросы, операции хранилища, снимки и повторы Postgres
+	var recorder *selfmetrics.Recorder
+	if cfg.SelfMetricsInterval > 0 {
+		recorder = selfmetrics.New(cfg)
+	}
+
 	// Инициализируем хранилище (БД или оперативная память)
 	var DB *sql.DB
 	var repo storage.Storage
+	backend := "memory"
 	log.Printf("Подключение к БД: %s", cfg.DataBaseDSN)
 	if DB, err = db.Init(cfg.DataBaseDSN); err != nil {
 		log.Printf("БД недоступна: %v", err)
 		repo = memoryRepo.New(cfg)
 	} else {
 		log.Printf("БД доступна!")
-		repo = dbRepo.New(cfg, DB)
+		repo = dbRepo.New(cfg, DB, dbRepo.WithSelfMetrics(recorder))
+		backend = "postgres"
 		defer DB.Close()
 	}
+	if recorder != nil {
+		repo = selfmetrics.NewStorage(repo, recorder, backend)
+	}
+
+	// Обновления, противоречащие типу из метаданных, отклоняются до публикации
+	if cfg.EnforceMetadataType {
+		repo = storage.NewTypeCheckingStorage(repo)
+	}
+
+	// Все обновления метрик публикуются подписчикам GET /stream
+	hub := stream.NewHub(cfg.StreamBufferSize)
+	repo = stream.NewPublishingStorage(repo, hub)
+
+	// Загружаем правила алертинга
+	var alerts *alert.Manager
+	if cfg.AlertRulesFile != "" {
+		if alerts, err = alert.NewManager(cfg, repo); err != nil {
+			return fmt.Errorf("failed to load alert rules: %w", err)
+		}
+	}
+
+	// Проверки готовности: хранилище регистрируют обработчики, снимок и фоновые задачи — ниже
+	checks := health.New()
+
+	// История значений нужна для запросов PromQL
+	opts := []handler.Option{handler.WithStream(hub), handler.WithAlerts(alerts), handler.WithHealth(checks), handler.WithSelfMetrics(recorder)}
+	var hist *history.Store
+	if cfg.HistoryInterval > 0 {
+		hist = history.New(cfg, repo)
+		opts = append(opts, handler.WithHistory(hist))
+	}
+
+	// Токены доступа к HTTP и gRPC
+	tokens, err := auth.New(cfg.AuthTokens, cfg.AuthTokensFile)
+	if err != nil {
+		return fmt.Errorf("failed to load tokens: %w", err)
+	}
+	var grpcOpts []grpcserver.Option
+	if tokens.Enabled() {
+		opts = append(opts, handler.WithAuth(tokens))
+		grpcOpts = append(grpcOpts, grpcserver.WithAuth(tokens))
+	}
+
+	// Приём метрик только из доверенной подсети
+	if cfg.TrustedSubnet != "" {
+		checker, err := trusted.New(cfg.TrustedSubnet, cfg.TrustedProxies)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, handler.WithTrustedSubnet(checker))
+		grpcOpts = append(grpcOpts, grpcserver.WithTrustedSubnet(checker))
+	}
+
+	// Ограничение частоты запросов и квоты клиентов на приём метрик
+	var limiter *ratelimit.Limiter
+	if cfg.RateLimit > 0 {
+		limiter = ratelimit.NewLimiter(cfg.RateLimit, cfg.RateBurst)
+		opts = append(opts, handler.WithRateLimit(limiter))
+		grpcOpts = append(grpcOpts, grpcserver.WithRateLimit(limiter))
+	}
+	var quota *ratelimit.Quota
+	if cfg.QuotaBatchMetrics > 0 || cfg.QuotaSeries > 0 {
+		quota = ratelimit.NewQuota(cfg.QuotaBatchMetrics, cfg.QuotaSeries)
+		opts = append(opts, handler.WithQuota(quota))
+		grpcOpts = append(grpcOpts, grpcserver.WithQuota(quota))
+	}
+	grpcOpts = append(grpcOpts, grpcserver.WithSelfMetrics(recorder))
+
+	// Закрытый ключ для расшифровки тел запросов агента
+	if cfg.CryptoKey != "" {
+		privateKey, err := encryption.LoadPrivateKey(cfg.CryptoKey)
+		if err != nil {
+			return fmt.Errorf("failed to load crypto key: %w", err)
+		}
+		opts = append(opts, handler.WithDecryption(privateKey))
+	}
 
 	// Инициализируем обработчики запросов
-	handlers := handler.NewHandlers(repo, DB, Log)
+	handlers := handler.NewHandlers(cfg, repo, DB, Log, opts...)
 
 	// Загруженам метрики из файла
-	file := fileStorage.New(cfg, repo)
+	file := fileStorage.New(cfg, repo, fileStorage.WithSelfMetrics(recorder))
 	loadFileError := file.Load()
 	if loadFileError != nil {
 		return loadFileError
 	}
+	if cfg.FileStoragePath != "" {
+		checks.Register("file", func(context.Context) error { return file.Writable() })
+	}
 
 	// Получаем роутинг
 	r := handlers.GetRoutes()
 
-	// Обновление метрик
-	if cfg.StoreInterval > 0 {
+	// Обновление метрик; без пути к файлу снимки не пишутся и задача snapshot не регистрируется.
+	// ingestRepo — хранилище для приёмников StatsD, Graphite и gRPC
+	ingestRepo := repo
+	var syncRepo *fileStorage.SyncStorage
+	if cfg.FileStoragePath == "" {
+		log.Println("FILE_STORAGE_PATH is empty, metrics are kept in memory only")
+	} else if cfg.StoreInterval > 0 {
 		// Через интервал времени
-		ticker := time.NewTicker(time.Duration(cfg.StoreInterval) * time.Second)
+		interval := time.Duration(cfg.StoreInterval) * time.Second
+		ticker := time.NewTicker(interval)
 		defer ticker.Stop()
 
+		saver := checks.PeriodicJob("snapshot", interval)
 		go func() {
 			for range ticker.C {
-				if err := file.Save(); err != nil {
+				err := file.Save()
+				saver.Done(err)
+				if err != nil {
 					log.Printf("Failed to save metrics: %v", err)
 				} else {
 					log.Println("Metrics saved by StoreInterval")
@@ -84,8 +208,121 @@ func run() error {
 			}
 		}()
 	} else {
-		// Синхронно через middleware
+		// Синхронно: HTTP через middleware, остальные приёмники через обёртку хранилища
 		r = middleware.SyncSaving(r, file)
+		syncRepo = fileStorage.NewSyncStorage(repo, file)
+		ingestRepo = syncRepo
+	}
+
+	// Фоновые приёмники метрик работают до отмены контекста
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	var wg sync.WaitGroup
+
+	if syncRepo != nil {
+		job := checks.Job("snapshot")
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			job.Run(func() error { syncRepo.Run(ctx); return nil })
+		}()
+	}
+
+	// Приём метрик по протоколу StatsD
+	if cfg.StatsDAddress != "" {
+		statsdServer := statsd.New(cfg, ingestRepo)
+		job := checks.Job("statsd")
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := job.Run(func() error { return statsdServer.Run(ctx) }); err != nil {
+				log.Printf("StatsD listener failed: %v", err)
+			}
+		}()
+	}
+
+	// Приём метрик по протоколу Graphite
+	if cfg.GraphiteAddress != "" {
+		graphiteServer := graphite.New(cfg, ingestRepo)
+		job := checks.Job("graphite")
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := job.Run(func() error { return graphiteServer.Run(ctx) }); err != nil {
+				log.Printf("Graphite listener failed: %v", err)
+			}
+		}()
+	}
+
+	// gRPC-сервис метрик на отдельном порту
+	if cfg.GRPCAddress != "" {
+		grpcServer := grpcserver.New(cfg, ingestRepo, grpcOpts...)
+		job := checks.Job("grpc")
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := job.Run(func() error { return grpcServer.Run(ctx) }); err != nil {
+				log.Printf("gRPC server failed: %v", err)
+			}
+		}()
+	}
+
+	// Периодическое вычисление правил алертинга
+	if alerts != nil {
+		job := checks.Job("alerts")
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			job.Run(func() error { alerts.Run(ctx); return nil })
+		}()
+	}
+
+	// Перечитывание файла токенов при изменении
+	if cfg.AuthTokensFile != "" {
+		job := checks.Job("tokens")
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			job.Run(func() error { tokens.Run(ctx); return nil })
+		}()
+	}
+
+	// Очистка состояния неактивных клиентов
+	if limiter != nil {
+		job := checks.Job("ratelimit")
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			job.Run(func() error { limiter.Run(ctx); return nil })
+		}()
+	}
+	if quota != nil {
+		job := checks.Job("quota")
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			job.Run(func() error { quota.Run(ctx); return nil })
+		}()
+	}
+
+	// Периодическое снятие истории значений
+	if hist != nil {
+		job := checks.Job("history")
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			job.Run(func() error { hist.Run(ctx); return nil })
+		}()
+	}
+
+	// Периодическая запись собственных метрик
+	if recorder != nil {
+		job := checks.Job("selfmetrics")
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			job.Run(func() error { recorder.Run(ctx, repo); return nil })
+		}()
 	}
 
 	// Запускаем сервер
@@ -95,8 +332,20 @@ func run() error {
 		Addr:    cfg.Address,
 		Handler: r,
 	}
+	if cfg.TLSCertFile != "" {
+		if server.TLSConfig, err = certs.NewServerTLS(cfg.TLSCertFile, cfg.TLSKeyFile, cfg.TLSClientCAFile); err != nil {
+			return fmt.Errorf("failed to configure tls: %w", err)
+		}
+	}
 	go func() {
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if server.TLSConfig != nil {
+			// Сертификат уже загружен в TLSConfig
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Failed start server: %v", err)
 		}
 	}()
@@ -106,13 +355,16 @@ func run() error {
 	// Отключаем сервер
 	<-quit
 	log.Println("Received shutdown signal...")
+	stop()
+	wg.Wait()
+	hub.Close()
 	log.Println("Saving metrics...")
 	if err := file.Save(); err != nil {
 		log.Printf("Failed to save metrics: %v", err)
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	if err := server.Shutdown(ctx); err != nil {
+	if err := server.Shutdown(shutdownCtx); err != nil {
 		log.Fatalf("Failed to stop server: %v", err)
 	}
 	log.Println("Server stopped")
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/auth"
+)
+
+// tokengen выпускает токен и печатает запись для файла токенов: сам токен сервер не хранит
+func tokengen(args []string) error {
+	fs := flag.NewFlagSet("token", flag.ExitOnError)
+	name := fs.String("name", "agent", "token name")
+	scopes := fs.String("scopes", auth.ScopeWrite, "comma-separated scopes: read, write, admin")
+	fs.Parse(args)
+
+	token, err := auth.GenerateToken()
+	if err != nil {
+		return err
+	}
+	entry, err := json.Marshal(auth.Token{
+		Name:   *name,
+		Hash:   auth.HashToken(token),
+		Scopes: strings.Split(*scopes, ","),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal token: %w", err)
+	}
+
+	fmt.Println("Token:", token)
+	fmt.Println("Tokens file entry:", string(entry))
+	return nil
+}
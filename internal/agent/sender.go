@@ -3,29 +3,132 @@ package agent
 import (
 	"bytes"
 	"context"
+	"crypto/rsa"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/akorablin/yandex-practicum-metrics/internal/compression"
+	"github.com/akorablin/yandex-practicum-metrics/internal/encryption"
 	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
+	"github.com/akorablin/yandex-practicum-metrics/internal/problem"
+	"github.com/akorablin/yandex-practicum-metrics/internal/ratelimit"
+	"github.com/akorablin/yandex-practicum-metrics/internal/sign"
+	"github.com/akorablin/yandex-practicum-metrics/internal/trusted"
+	pb "github.com/akorablin/yandex-practicum-metrics/pkg/api/metricspb"
+	"google.golang.org/grpc"
 )
 
 type Sender struct {
 	client      *http.Client
 	baseURL     string
 	retryConfig RetryConfig
+	grpc        pb.MetricsServiceClient
+	key         string
+	publicKey   *rsa.PublicKey
+	token       string
+	realIP      string
+	agentID     string
+	encoding    string
 }
 
-func NewSender(baseURL string) *Sender {
-	return &Sender{
+// Option настраивает дополнительные параметры Sender
+type Option func(*Sender)
+
+// WithGRPC переключает SendAll на отправку пакета через gRPC-сервис MetricsService
+func WithGRPC(conn grpc.ClientConnInterface) Option {
+	return func(s *Sender) {
+		s.grpc = pb.NewMetricsServiceClient(conn)
+	}
+}
+
+// WithKey включает подпись HMAC-SHA256: тела запросов подписываются в заголовке HashSHA256,
+// JSON-метрики — в поле hash
+func WithKey(key string) Option {
+	return func(s *Sender) {
+		s.key = key
+	}
+}
+
+// WithTLS отправляет HTTP-запросы с заданной конфигурацией TLS (CA сервера и клиентский сертификат)
+func WithTLS(cfg *tls.Config) Option {
+	return func(s *Sender) {
+		// Клон сохраняет прокси из окружения, тайм-ауты и пул соединений стандартного транспорта
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = cfg
+		s.client.Transport = transport
+	}
+}
+
+// WithEncryption шифрует тела JSON-запросов открытым ключом сервера
+func WithEncryption(pub *rsa.PublicKey) Option {
+	return func(s *Sender) {
+		s.publicKey = pub
+	}
+}
+
+// WithToken передаёт bearer-токен в заголовке Authorization
+func WithToken(token string) Option {
+	return func(s *Sender) {
+		s.token = token
+	}
+}
+
+// WithRealIP передаёт адрес агента в заголовке X-Real-IP для проверки доверенной подсети
+func WithRealIP(ip string) Option {
+	return func(s *Sender) {
+		s.realIP = ip
+	}
+}
+
+// WithAgentID передаёт идентификатор агента в заголовке X-Agent-ID
+func WithAgentID(id string) Option {
+	return func(s *Sender) {
+		s.agentID = id
+	}
+}
+
+// WithCompression сжимает тела JSON-запросов выбранным алгоритмом (gzip, deflate, zstd, br)
+func WithCompression(encoding string) Option {
+	return func(s *Sender) {
+		s.encoding = encoding
+	}
+}
+
+// WithRetryConfig задаёт число попыток и паузы между ними
+func WithRetryConfig(cfg RetryConfig) Option {
+	return func(s *Sender) {
+		s.retryConfig = cfg
+	}
+}
+
+// OutboundIP возвращает адрес интерфейса, через который идёт трафик к серверу.
+// UDP-сокет только выбирает маршрут, пакеты не отправляются
+func OutboundIP(address string) (net.IP, error) {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve outbound address: %w", err)
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP, nil
+}
+
+func NewSender(baseURL string, opts ...Option) *Sender {
+	s := &Sender{
 		client:      &http.Client{},
 		baseURL:     baseURL,
 		retryConfig: DefaultRetryConfig(),
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 type RetryConfig struct {
@@ -85,6 +188,11 @@ func (s *Sender) sendMetric(url, metricType, metricName string) error {
 	}
 
 	req.Header.Set("Content-Type", "text/plain")
+	s.setHeaders(req)
+	if s.key != "" {
+		// У запроса нет тела, поэтому подписывается путь
+		req.Header.Set(sign.Header, sign.Sum([]byte(req.URL.Path), s.key))
+	}
 	resp, err := s.client.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to send request: %w", err)
@@ -92,17 +200,35 @@ func (s *Sender) sendMetric(url, metricType, metricName string) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		if p, ok := problem.Parse(resp.Header.Get("Content-Type"), body); ok {
+			return fmt.Errorf("server rejected %s %s: %w", metricType, metricName, p)
+		}
 		return fmt.Errorf("server returned status %d for %s %s", resp.StatusCode, metricType, metricName)
 	}
 
 	return nil
 }
 
-func (s *Sender) SendAllMetricsJSON(ctx context.Context, gauge map[string]float64, counter map[string]int64) error {
-	totalMetrics := len(gauge) + len(counter)
+// SendAll отправляет все метрики одним пакетом через выбранный транспорт
+func (s *Sender) SendAll(ctx context.Context, gauge map[string]float64, counter map[string]int64) error {
+	if s.grpc == nil {
+		return s.SendAllMetricsJSON(ctx, gauge, counter)
+	}
+
+	log.Printf("Sending %d gauge metrics and %d counter metrics via gRPC", len(gauge), len(counter))
+	return s.SendBatchGRPC(ctx, buildMetrics(gauge, counter))
+}
 
+func (s *Sender) SendAllMetricsJSON(ctx context.Context, gauge map[string]float64, counter map[string]int64) error {
 	log.Printf("Sending %d gauge metrics and %d counter metrics", len(gauge), len(counter))
 
+	return s.SendBatchJSON(ctx, buildMetrics(gauge, counter))
+}
+
+func buildMetrics(gauge map[string]float64, counter map[string]int64) []models.Metrics {
+	totalMetrics := len(gauge) + len(counter)
+
 	var metricItem models.Metrics
 	data := make([]models.Metrics, 0, totalMetrics)
 
@@ -126,7 +252,7 @@ func (s *Sender) SendAllMetricsJSON(ctx context.Context, gauge map[string]float6
 		data = append(data, metricItem)
 	}
 
-	return s.SendBatchJSON(ctx, data)
+	return data
 }
 
 func (s *Sender) SendGaugeJSON(ctx context.Context, name string, value float64) error {
@@ -137,6 +263,7 @@ func (s *Sender) SendGaugeJSON(ctx context.Context, name string, value float64)
 		MType: "gauge",
 		Value: &value,
 	}
+	s.signMetric(&data)
 	jsonData, err := json.Marshal(data)
 	if err != nil {
 		return fmt.Errorf("invalid json: %w", err)
@@ -153,6 +280,7 @@ func (s *Sender) SendCounterJSON(ctx context.Context, name string, value int64)
 		MType: "counter",
 		Delta: &value,
 	}
+	s.signMetric(&data)
 	jsonData, err := json.Marshal(data)
 	if err != nil {
 		return fmt.Errorf("invalid json: %w", err)
@@ -164,6 +292,9 @@ func (s *Sender) SendCounterJSON(ctx context.Context, name string, value int64)
 func (s *Sender) SendBatchJSON(ctx context.Context, data []models.Metrics) error {
 	url := fmt.Sprintf("%s/updates/", s.baseURL)
 
+	for i := range data {
+		s.signMetric(&data[i])
+	}
 	jsonData, err := json.Marshal(data)
 	if err != nil {
 		return fmt.Errorf("invalid json: %w", err)
@@ -173,42 +304,124 @@ func (s *Sender) SendBatchJSON(ctx context.Context, data []models.Metrics) error
 }
 
 func (s *Sender) sendMetricJSON(ctx context.Context, url string, data []byte) error {
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(data))
+	// Подпись считается по открытому телу: сервер проверяет её после расшифровки
+	var hash string
+	if s.key != "" {
+		hash = sign.Sum(data, s.key)
+	}
+	// Сжатие до шифрования: зашифрованные данные уже не сжимаются
+	body := data
+	if s.encoding != "" {
+		compressed, err := compression.Compress(s.encoding, data)
+		if err != nil {
+			return fmt.Errorf("failed to compress body: %w", err)
+		}
+		body = compressed
+	}
+	if s.publicKey != nil {
+		encrypted, err := encryption.Encrypt(s.publicKey, body)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt body: %w", err)
+		}
+		body = encrypted
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(body))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	resp, err := s.retryRequest(ctx, req)
+	if s.encoding != "" {
+		req.Header.Set("Content-Encoding", s.encoding)
+	}
+	if hash != "" {
+		req.Header.Set(sign.Header, hash)
+	}
+	if s.publicKey != nil {
+		req.Header.Set(encryption.Header, encryption.Scheme)
+	}
+	s.setHeaders(req)
+	resp, respBody, err := s.retryRequest(ctx, req)
 	if err != nil {
 		return fmt.Errorf("failed to send request: %w", err)
 	}
-	defer resp.Body.Close()
-
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("%s", string(body))
+		if p, ok := problem.Parse(resp.Header.Get("Content-Type"), respBody); ok {
+			return fmt.Errorf("server rejected metrics: %w", p)
+		}
+		return fmt.Errorf("%s", string(respBody))
+	}
+
+	// Подписанный ответ проверяется, чтобы не принять ответ от подменённого сервера
+	if hash := resp.Header.Get(sign.Header); s.key != "" && hash != "" && !sign.Valid(respBody, s.key, hash) {
+		return fmt.Errorf("invalid response signature")
 	}
 
 	return nil
 }
 
-func (s *Sender) retryRequest(ctx context.Context, request *http.Request) (*http.Response, error) {
+func (s *Sender) setHeaders(req *http.Request) {
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	if s.realIP != "" {
+		req.Header.Set(trusted.Header, s.realIP)
+	}
+	if s.agentID != "" {
+		req.Header.Set(ratelimit.AgentIDHeader, s.agentID)
+	}
+}
+
+func (s *Sender) signMetric(m *models.Metrics) {
+	if s.key != "" {
+		m.Hash = sign.MetricHash(*m, s.key)
+	}
+}
+
+// retryRequest повторяет запрос при сетевых ошибках и при ответах problem+json с временной
+// причиной (см. problem.Retryable); пауза берётся из Retry-After, если сервер его передал
+func (s *Sender) retryRequest(ctx context.Context, request *http.Request) (*http.Response, []byte, error) {
 	var lastErr error
 	for attempt := 0; attempt < s.retryConfig.MaxAttempts; attempt++ {
+		delay := s.retryConfig.InitialDelay + (time.Duration(attempt) * s.retryConfig.DelayStep)
+
+		// Тело запроса прочитано предыдущей попыткой, его нужно пересоздать
+		if attempt > 0 && request.GetBody != nil {
+			body, err := request.GetBody()
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to reset request body: %w", err)
+			}
+			request.Body = body
+		}
+
 		resp, err := s.client.Do(request)
 		if err == nil {
-			return resp, nil
+			body, readErr := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if readErr != nil {
+				return nil, nil, fmt.Errorf("failed to read response: %w", readErr)
+			}
+			p, ok := problem.Parse(resp.Header.Get("Content-Type"), body)
+			if !ok || !p.Retryable() {
+				return resp, body, nil
+			}
+			err = p
+			if seconds, convErr := strconv.Atoi(resp.Header.Get("Retry-After")); convErr == nil && seconds > 0 {
+				delay = time.Duration(seconds) * time.Second
+			}
 		}
 		lastErr = err
 
-		delay := s.retryConfig.InitialDelay + (time.Duration(attempt) * s.retryConfig.DelayStep)
+		if attempt == s.retryConfig.MaxAttempts-1 {
+			break
+		}
 		select {
 		case <-ctx.Done():
-			return nil, fmt.Errorf("операция отменена: %w", ctx.Err())
+			return nil, nil, fmt.Errorf("операция отменена: %w", ctx.Err())
 		case <-time.After(delay):
 		}
 	}
 
-	return nil, fmt.Errorf("все %d попыток завершились ошибкой, последняя ошибка: %w", s.retryConfig.MaxAttempts, lastErr)
+	return nil, nil, fmt.Errorf("все %d попыток завершились ошибкой, последняя ошибка: %w", s.retryConfig.MaxAttempts, lastErr)
 }
@@ -0,0 +1,120 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
+	"github.com/akorablin/yandex-practicum-metrics/internal/sign"
+	pb "github.com/akorablin/yandex-practicum-metrics/pkg/api/metricspb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Максимальное число метрик в одном сообщении потока UpdateBatch
+const grpcBatchChunkSize = 100
+
+// metadataCredentials добавляет постоянные метаданные к каждому gRPC-вызову
+type metadataCredentials map[string]string
+
+func (m metadataCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return m, nil
+}
+
+// Метаданные передаются и без TLS, как заголовки в HTTP-транспорте
+func (m metadataCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+// TokenCredentials передаёт bearer-токен в метаданных каждого gRPC-вызова
+func TokenCredentials(token string) credentials.PerRPCCredentials {
+	return metadataCredentials{"authorization": "Bearer " + token}
+}
+
+// RealIPCredentials передаёт адрес агента в метаданных x-real-ip
+func RealIPCredentials(ip string) credentials.PerRPCCredentials {
+	return metadataCredentials{"x-real-ip": ip}
+}
+
+// AgentIDCredentials передаёт идентификатор агента в метаданных x-agent-id
+func AgentIDCredentials(id string) credentials.PerRPCCredentials {
+	return metadataCredentials{"x-agent-id": id}
+}
+
+func (s *Sender) SendBatchGRPC(ctx context.Context, data []models.Metrics) error {
+	if s.grpc == nil {
+		return fmt.Errorf("grpc transport is not configured")
+	}
+
+	metrics := make([]*pb.Metric, 0, len(data))
+	for _, m := range data {
+		metric := &pb.Metric{Id: m.ID}
+		switch m.MType {
+		case models.Gauge:
+			metric.Type, metric.Value = pb.MType_GAUGE, *m.Value
+		case models.Counter:
+			metric.Type, metric.Delta = pb.MType_COUNTER, *m.Delta
+		}
+		metrics = append(metrics, metric)
+	}
+
+	// Сервер сохраняет поток только целиком при EOF, поэтому оборванный пакет можно повторить
+	if s.key != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, sign.MetadataKey, sign.BatchHash(data, s.key))
+	}
+	return s.retryGRPC(ctx, func() error {
+		return s.sendBatchGRPC(ctx, metrics)
+	})
+}
+
+func (s *Sender) sendBatchGRPC(ctx context.Context, metrics []*pb.Metric) error {
+	stream, err := s.grpc.UpdateBatch(ctx)
+	if err != nil {
+		return err
+	}
+
+	for start := 0; start < len(metrics); start += grpcBatchChunkSize {
+		end := min(start+grpcBatchChunkSize, len(metrics))
+		if err := stream.Send(&pb.UpdateBatchRequest{Metrics: metrics[start:end]}); err != nil {
+			// Настоящая причина ошибки возвращается из CloseAndRecv
+			break
+		}
+	}
+
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		return err
+	}
+	if resp.GetReceived() != int64(len(metrics)) {
+		return fmt.Errorf("server received %d of %d metrics", resp.GetReceived(), len(metrics))
+	}
+	return nil
+}
+
+// retryGRPC повторяет вызов только для временных ошибок транспорта
+func (s *Sender) retryGRPC(ctx context.Context, call func() error) error {
+	var lastErr error
+	for attempt := 0; attempt < s.retryConfig.MaxAttempts; attempt++ {
+		err := call()
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+
+		if code := status.Code(err); code != codes.Unavailable && code != codes.DeadlineExceeded {
+			return fmt.Errorf("неповторяемая ошибка: %w", err)
+		}
+
+		delay := s.retryConfig.InitialDelay + (time.Duration(attempt) * s.retryConfig.DelayStep)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("операция отменена: %w", ctx.Err())
+		case <-time.After(delay):
+		}
+	}
+
+	return fmt.Errorf("все %d попыток завершились ошибкой, последняя ошибка: %w", s.retryConfig.MaxAttempts, lastErr)
+}
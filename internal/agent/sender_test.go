@@ -1,12 +1,19 @@
 package agent_test
 
 import (
+	"context"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/akorablin/yandex-practicum-metrics/internal/agent"
+	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
+	"github.com/akorablin/yandex-practicum-metrics/internal/problem"
 )
 
 func TestNewSender(t *testing.T) {
@@ -120,3 +127,49 @@ func TestSendAllMetrics(t *testing.T) {
 		}
 	}
 }
+
+func TestSendBatchJSONProblemRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		status   int
+		attempts int
+		wantErr  bool
+	}{
+		{"Временная ошибка повторяется", problem.CodeStorageUnavailable, http.StatusServiceUnavailable, 2, false},
+		{"Ошибка валидации не повторяется", problem.CodeValidationFailed, http.StatusBadRequest, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if len(body) == 0 {
+					t.Error("Request body is empty on retry")
+				}
+				if attempts.Add(1) == 1 {
+					problem.Write(w, r, tt.status, tt.code, "failed")
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+
+			sender := agent.NewSender(server.URL, agent.WithRetryConfig(agent.RetryConfig{MaxAttempts: 3, InitialDelay: time.Millisecond}))
+			value := 1.0
+			err := sender.SendBatchJSON(context.Background(), []models.Metrics{{ID: "Alloc", MType: models.Gauge, Value: &value}})
+
+			if got := int(attempts.Load()); got != tt.attempts {
+				t.Errorf("Expected %d attempts, got %d", tt.attempts, got)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SendBatchJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var p *problem.Problem
+			if tt.wantErr && (!errors.As(err, &p) || p.Code != tt.code) {
+				t.Errorf("Expected problem with code %s, got %v", tt.code, err)
+			}
+		})
+	}
+}
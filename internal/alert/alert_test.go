@@ -0,0 +1,168 @@
+package alert_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/alert"
+	"github.com/akorablin/yandex-practicum-metrics/internal/config"
+	"github.com/akorablin/yandex-practicum-metrics/internal/repository/memory"
+)
+
+func TestParseRule(t *testing.T) {
+	rule, err := alert.ParseRule(alert.RuleConfig{Name: "HighHeap", Expr: "HeapAlloc > 1e9 for 2m"})
+	if err != nil {
+		t.Fatalf("ParseRule() failed: %v", err)
+	}
+	if rule.Metric != "HeapAlloc" || rule.Rate || rule.Op != ">" || rule.Threshold != 1e9 || rule.For != 2*time.Minute {
+		t.Errorf("Unexpected rule: %+v", rule)
+	}
+
+	rule, err = alert.ParseRule(alert.RuleConfig{Name: "AgentDown", Expr: "rate(PollCount) == 0", For: "1m"})
+	if err != nil {
+		t.Fatalf("ParseRule() failed: %v", err)
+	}
+	if rule.Metric != "PollCount" || !rule.Rate || rule.Op != "==" || rule.For != time.Minute {
+		t.Errorf("Unexpected rule: %+v", rule)
+	}
+
+	invalid := []alert.RuleConfig{
+		{Name: "", Expr: "A > 1"},
+		{Name: "NoOp", Expr: "A 1"},
+		{Name: "BadThreshold", Expr: "A > x"},
+		{Name: "BadDuration", Expr: "A > 1 for soon"},
+		{Name: "TwoDurations", Expr: "A > 1 for 1m", For: "2m"},
+	}
+	for _, cfg := range invalid {
+		if _, err := alert.ParseRule(cfg); !errors.Is(err, alert.ErrInvalidRule) {
+			t.Errorf("ParseRule(%+v): expected ErrInvalidRule, got %v", cfg, err)
+		}
+	}
+}
+
+func TestManagerLifecycle(t *testing.T) {
+	// Webhook запоминает полученные статусы
+	var mu sync.Mutex
+	var received []string
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n alert.Notification
+		json.NewDecoder(r.Body).Decode(&n)
+		mu.Lock()
+		received = append(received, n.Status)
+		mu.Unlock()
+	}))
+	defer webhook.Close()
+
+	rulesFile := filepath.Join(t.TempDir(), "rules.json")
+	rules := fmt.Sprintf(`{"webhooks":[%q],"rules":[{"name":"HighHeap","expr":"HeapAlloc > 100 for 1m"}]}`, webhook.URL)
+	if err := os.WriteFile(rulesFile, []byte(rules), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.ServerConfig{AlertRulesFile: rulesFile}
+	repo := memory.New(cfg)
+	manager, err := alert.NewManager(cfg, repo)
+	if err != nil {
+		t.Fatalf("NewManager() failed: %v", err)
+	}
+
+	start := time.Now()
+	steps := []struct {
+		heap  float64
+		after time.Duration
+		state string
+	}{
+		{50, 0, alert.StateInactive},
+		{200, 10 * time.Second, alert.StatePending},
+		{200, 40 * time.Second, alert.StatePending},
+		{200, 80 * time.Second, alert.StateFiring},
+		{50, 90 * time.Second, alert.StateResolved},
+	}
+	for _, step := range steps {
+		repo.UpdateGauge("HeapAlloc", step.heap)
+		manager.Evaluate(start.Add(step.after))
+		if state := manager.Alerts()[0].State; state != step.state {
+			t.Fatalf("After %v: expected state %s, got %s", step.after, step.state, state)
+		}
+	}
+
+	// Уведомления отправляются асинхронно
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		n := len(received)
+		mu.Unlock()
+		if n == 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 2 || received[0] != alert.StateFiring || received[1] != alert.StateResolved {
+		t.Errorf("Expected [firing resolved] notifications, got %v", received)
+	}
+}
+
+func TestRateCounterReset(t *testing.T) {
+	rulesFile := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(rulesFile, []byte(`{"rules":[{"name":"NoTraffic","expr":"rate(Requests) < 0.1"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.ServerConfig{AlertRulesFile: rulesFile}
+	repo := memory.New(cfg)
+	manager, err := alert.NewManager(cfg, repo)
+	if err != nil {
+		t.Fatalf("NewManager() failed: %v", err)
+	}
+
+	start := time.Now()
+	repo.UpdateCounter("Requests", 100)
+	manager.Evaluate(start)
+
+	// Счётчик пересоздан после перезапуска источника: 5 запросов за 10 секунд, а не -9.5 в секунду
+	repo.DeleteMetric("counter", "Requests")
+	repo.UpdateCounter("Requests", 5)
+	manager.Evaluate(start.Add(10 * time.Second))
+
+	a := manager.Alerts()[0]
+	if a.Value == nil || *a.Value != 0.5 {
+		t.Fatalf("Expected rate 0.5 after reset, got %v", a.Value)
+	}
+	if a.State != alert.StateInactive {
+		t.Errorf("Expected inactive after reset, got %s", a.State)
+	}
+}
+
+func TestNotifierCloseDeadline(t *testing.T) {
+	// Webhook не отвечает, пока тест не завершится
+	release := make(chan struct{})
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer webhook.Close()
+	defer close(release)
+
+	notifier := alert.NewNotifier([]string{webhook.URL})
+	for range 3 {
+		notifier.Notify(alert.Alert{Name: "HighHeap", State: alert.StateFiring})
+	}
+
+	start := time.Now()
+	notifier.Close(100 * time.Millisecond)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Close() took %v, expected to stop at the deadline", elapsed)
+	}
+}
@@ -0,0 +1,196 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/config"
+	"github.com/akorablin/yandex-practicum-metrics/internal/storage"
+)
+
+// Состояния алерта
+const (
+	StateInactive = "inactive"
+	StatePending  = "pending"
+	StateFiring   = "firing"
+	StateResolved = "resolved"
+)
+
+type Alert struct {
+	Name       string     `json:"name"`
+	Expr       string     `json:"expr"`
+	For        string     `json:"for"`
+	State      string     `json:"state"`
+	Value      *float64   `json:"value,omitempty"`
+	ActiveAt   *time.Time `json:"activeAt,omitempty"`
+	FiredAt    *time.Time `json:"firedAt,omitempty"`
+	ResolvedAt *time.Time `json:"resolvedAt,omitempty"`
+}
+
+type sample struct {
+	value float64
+	at    time.Time
+}
+
+type ruleState struct {
+	rule  Rule
+	alert Alert
+	prev  *sample // предыдущее значение метрики для rate()
+}
+
+// Manager периодически вычисляет правила по текущим значениям хранилища
+// и отправляет уведомления при переходах в firing и resolved
+type Manager struct {
+	cfg      *config.ServerConfig
+	storage  storage.Storage
+	notifier *Notifier
+
+	mu     sync.RWMutex
+	states []*ruleState
+}
+
+func NewManager(cfg *config.ServerConfig, repo storage.Storage) (*Manager, error) {
+	rules, webhooks, err := LoadRules(cfg.AlertRulesFile)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manager{
+		cfg:      cfg,
+		storage:  repo,
+		notifier: NewNotifier(webhooks),
+	}
+	for _, rule := range rules {
+		m.states = append(m.states, &ruleState{
+			rule:  rule,
+			alert: Alert{Name: rule.Name, Expr: rule.Expr, For: rule.For.String(), State: StateInactive},
+		})
+	}
+	return m, nil
+}
+
+// Run вычисляет правила до отмены контекста, затем досылает поставленные в очередь уведомления
+func (m *Manager) Run(ctx context.Context) {
+	log.Printf("Alert manager started with %d rules", len(m.states))
+	ticker := time.NewTicker(time.Duration(m.cfg.AlertEvalInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			m.notifier.Close(DrainTimeout)
+			log.Println("Alert manager stopped")
+			return
+		case now := <-ticker.C:
+			m.Evaluate(now)
+		}
+	}
+}
+
+// Alerts возвращает текущее состояние всех правил
+func (m *Manager) Alerts() []Alert {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	alerts := make([]Alert, 0, len(m.states))
+	for _, state := range m.states {
+		alerts = append(alerts, state.alert)
+	}
+	return alerts
+}
+
+func (m *Manager) Evaluate(now time.Time) {
+	m.mu.Lock()
+	var notifications []Alert
+	for _, state := range m.states {
+		if m.evaluateRule(state, now) {
+			notifications = append(notifications, state.alert)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, alert := range notifications {
+		log.Printf("Alert %s is %s", alert.Name, alert.State)
+		m.notifier.Notify(alert)
+	}
+}
+
+// evaluateRule обновляет состояние правила и сообщает, нужно ли отправить уведомление
+func (m *Manager) evaluateRule(state *ruleState, now time.Time) bool {
+	value, ok := m.value(state, now)
+	alert := &state.alert
+	if ok {
+		alert.Value = &value
+	} else {
+		alert.Value = nil
+	}
+
+	// Нет данных — условие считается невыполненным
+	if !ok || !state.rule.Matches(value) {
+		switch alert.State {
+		case StateFiring:
+			alert.State = StateResolved
+			alert.ResolvedAt = &now
+			return true
+		case StatePending:
+			alert.State = StateInactive
+			alert.ActiveAt = nil
+		}
+		return false
+	}
+
+	switch alert.State {
+	case StateInactive, StateResolved:
+		alert.State = StatePending
+		alert.ActiveAt = &now
+		alert.FiredAt = nil
+		alert.ResolvedAt = nil
+	}
+	if alert.State == StatePending && now.Sub(*alert.ActiveAt) >= state.rule.For {
+		alert.State = StateFiring
+		alert.FiredAt = &now
+		return true
+	}
+	return false
+}
+
+// value возвращает значение метрики (gauge, затем counter) или её скорость изменения в секунду.
+// Как rate() в PromQL, уменьшение значения считается сбросом счётчика (перезапуск, пересоздание метрики),
+// и приращением за интервал становится всё текущее значение
+func (m *Manager) value(state *ruleState, now time.Time) (float64, bool) {
+	current, ok := m.lookup(state.rule.Metric)
+	if !state.rule.Rate {
+		return current, ok
+	}
+
+	prev := state.prev
+	if ok {
+		state.prev = &sample{value: current, at: now}
+	} else {
+		state.prev = nil
+	}
+	if !ok || prev == nil || !now.After(prev.at) {
+		return 0, false
+	}
+	delta := current - prev.value
+	if delta < 0 {
+		delta = current
+	}
+	return delta / now.Sub(prev.at).Seconds(), true
+}
+
+func (m *Manager) lookup(name string) (float64, bool) {
+	if value, err := m.storage.GetGauge(name); err == nil {
+		return value, true
+	} else if !errors.Is(err, storage.ErrMetricNotFound) {
+		return 0, false
+	}
+
+	if value, err := m.storage.GetCounter(name); err == nil {
+		return float64(value), true
+	}
+	return 0, false
+}
@@ -0,0 +1,163 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type RetryConfig struct {
+	MaxAttempts  int
+	InitialDelay time.Duration
+	DelayStep    time.Duration
+}
+
+func DefaultRetryConfig() RetryConfig {
+	return RetryConfig{
+		MaxAttempts:  3,
+		InitialDelay: 1 * time.Second,
+		DelayStep:    2 * time.Second,
+	}
+}
+
+// Notification — тело запроса к webhook
+type Notification struct {
+	Status string  `json:"status"`
+	Alerts []Alert `json:"alerts"`
+}
+
+// Размер очереди неотправленных уведомлений
+const notifyQueueSize = 100
+
+// DrainTimeout — сколько остановка сервера ждёт досылки очереди уведомлений
+const DrainTimeout = 5 * time.Second
+
+type delivery struct {
+	name string
+	body []byte
+}
+
+// Notifier отправляет уведомления в фоновом обработчике, чтобы не задерживать вычисление правил.
+// Уведомления доставляются по очереди, поэтому resolved никогда не опережает firing
+type Notifier struct {
+	client      *http.Client
+	webhooks    []string
+	retryConfig RetryConfig
+	queue       chan delivery
+	done        chan struct{}
+	closeOnce   sync.Once
+
+	// ctx отменяется, когда досылка при остановке не уложилась в срок
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewNotifier(webhooks []string) *Notifier {
+	ctx, cancel := context.WithCancel(context.Background())
+	n := &Notifier{
+		client:      &http.Client{Timeout: 10 * time.Second},
+		webhooks:    webhooks,
+		retryConfig: DefaultRetryConfig(),
+		queue:       make(chan delivery, notifyQueueSize),
+		done:        make(chan struct{}),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+	go n.worker()
+	return n
+}
+
+func (n *Notifier) Notify(alert Alert) {
+	if len(n.webhooks) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(Notification{Status: alert.State, Alerts: []Alert{alert}})
+	if err != nil {
+		log.Printf("Failed to marshal alert %s: %v", alert.Name, err)
+		return
+	}
+
+	select {
+	case n.queue <- delivery{name: alert.Name, body: body}:
+	default:
+		log.Printf("Alert notification queue is full, dropping %s", alert.Name)
+	}
+}
+
+// Close досылает уже поставленные в очередь уведомления не дольше timeout,
+// затем прерывает текущую отправку, отбрасывает остаток очереди и останавливает обработчик
+func (n *Notifier) Close(timeout time.Duration) {
+	n.closeOnce.Do(func() {
+		close(n.queue)
+	})
+	defer n.cancel()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-n.done:
+	case <-timer.C:
+		log.Printf("Alert notifications were not delivered in %v, dropping the rest", timeout)
+		n.cancel()
+		<-n.done
+	}
+}
+
+func (n *Notifier) worker() {
+	defer close(n.done)
+	for d := range n.queue {
+		if n.ctx.Err() != nil {
+			continue
+		}
+		for _, url := range n.webhooks {
+			if err := n.send(n.ctx, url, d.body); err != nil {
+				log.Printf("Failed to notify %s about alert %s: %v", url, d.name, err)
+			}
+		}
+	}
+}
+
+func (n *Notifier) send(ctx context.Context, url string, body []byte) error {
+	var lastErr error
+	for attempt := 0; attempt < n.retryConfig.MaxAttempts; attempt++ {
+		lastErr = n.post(ctx, url, body)
+		if lastErr == nil {
+			return nil
+		}
+
+		if attempt < n.retryConfig.MaxAttempts-1 {
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("операция отменена: %w", ctx.Err())
+			case <-time.After(n.retryConfig.InitialDelay + (time.Duration(attempt) * n.retryConfig.DelayStep)):
+			}
+		}
+	}
+
+	return fmt.Errorf("все %d попыток завершились ошибкой, последняя ошибка: %w", n.retryConfig.MaxAttempts, lastErr)
+}
+
+func (n *Notifier) post(ctx context.Context, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package alert
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidRule = errors.New("invalid alert rule")
+
+// Файл правил:
+//
+//	{
+//	  "webhooks": ["http://alerts.local/hook"],
+//	  "rules": [
+//	    {"name": "HighHeap", "expr": "HeapAlloc > 1e9 for 2m"},
+//	    {"name": "AgentDown", "expr": "rate(PollCount) == 0", "for": "1m"}
+//	  ]
+//	}
+type RulesFile struct {
+	Webhooks []string     `json:"webhooks"`
+	Rules    []RuleConfig `json:"rules"`
+}
+
+type RuleConfig struct {
+	Name string `json:"name"`
+	Expr string `json:"expr"`
+	For  string `json:"for,omitempty"`
+}
+
+// Rule — разобранное условие вида <metric> <op> <threshold> или rate(<metric>) <op> <threshold>
+type Rule struct {
+	Name      string
+	Expr      string
+	Metric    string
+	Rate      bool
+	Op        string
+	Threshold float64
+	For       time.Duration
+}
+
+var exprPattern = regexp.MustCompile(`^\s*(?:(rate)\(\s*([^()\s]+)\s*\)|([^()\s]+))\s*(>=|<=|==|!=|>|<)\s*(\S+?)(?:\s+for\s+(\S+))?\s*$`)
+
+func ParseRule(cfg RuleConfig) (Rule, error) {
+	if cfg.Name == "" {
+		return Rule{}, fmt.Errorf("%w: name is required", ErrInvalidRule)
+	}
+
+	match := exprPattern.FindStringSubmatch(cfg.Expr)
+	if match == nil {
+		return Rule{}, fmt.Errorf("%w %s: cannot parse expression %q", ErrInvalidRule, cfg.Name, cfg.Expr)
+	}
+
+	rule := Rule{
+		Name:   cfg.Name,
+		Expr:   strings.TrimSpace(cfg.Expr),
+		Metric: match[3],
+		Rate:   match[1] == "rate",
+		Op:     match[4],
+	}
+	if rule.Rate {
+		rule.Metric = match[2]
+	}
+
+	threshold, err := strconv.ParseFloat(match[5], 64)
+	if err != nil {
+		return Rule{}, fmt.Errorf("%w %s: bad threshold %q", ErrInvalidRule, cfg.Name, match[5])
+	}
+	rule.Threshold = threshold
+
+	// Длительность может быть задана в выражении или отдельным полем
+	duration := cfg.For
+	if match[6] != "" {
+		if duration != "" {
+			return Rule{}, fmt.Errorf("%w %s: duration is set twice", ErrInvalidRule, cfg.Name)
+		}
+		duration = match[6]
+	}
+	if duration != "" {
+		rule.For, err = time.ParseDuration(duration)
+		if err != nil || rule.For < 0 {
+			return Rule{}, fmt.Errorf("%w %s: bad duration %q", ErrInvalidRule, cfg.Name, duration)
+		}
+	}
+
+	return rule, nil
+}
+
+func (r Rule) Matches(value float64) bool {
+	switch r.Op {
+	case ">":
+		return value > r.Threshold
+	case ">=":
+		return value >= r.Threshold
+	case "<":
+		return value < r.Threshold
+	case "<=":
+		return value <= r.Threshold
+	case "==":
+		return value == r.Threshold
+	case "!=":
+		return value != r.Threshold
+	}
+	return false
+}
+
+func LoadRules(path string) ([]Rule, []string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var file RulesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse alert rules: %w", err)
+	}
+
+	rules := make([]Rule, 0, len(file.Rules))
+	names := make(map[string]bool, len(file.Rules))
+	for _, cfg := range file.Rules {
+		rule, err := ParseRule(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		if names[rule.Name] {
+			return nil, nil, fmt.Errorf("%w: duplicate rule name %s", ErrInvalidRule, rule.Name)
+		}
+		names[rule.Name] = true
+		rules = append(rules, rule)
+	}
+
+	return rules, file.Webhooks, nil
+}
@@ -0,0 +1,223 @@
+// Package auth проверяет bearer-токены и их права (scopes).
+// Токены хранятся только в виде SHA-256, файл токенов перечитывается при изменении
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Права токена; admin включает все остальные
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+	ScopeAdmin = "admin"
+)
+
+// ReloadInterval — период проверки изменения файла токенов
+const ReloadInterval = 5 * time.Second
+
+var (
+	ErrUnauthorized = errors.New("missing or unknown token")
+	ErrForbidden    = errors.New("token has no required scope")
+)
+
+// Token — запись файла токенов: имя для журналов, SHA-256 токена и права
+type Token struct {
+	Name   string   `json:"name"`
+	Hash   string   `json:"hash"`
+	Scopes []string `json:"scopes"`
+}
+
+type tokensFile struct {
+	Tokens []Token `json:"tokens"`
+}
+
+// Store хранит токены из конфигурации и файла
+type Store struct {
+	mu      sync.RWMutex
+	inline  []Token
+	path    string
+	modTime time.Time
+	tokens  map[string]Token
+
+	// ticketKey подписывает билеты дашборда (см. IssueTicket)
+	ticketKey []byte
+}
+
+// New разбирает токены из конфигурации в формате name:sha256:scope+scope,... и загружает файл токенов
+func New(inline, path string) (*Store, error) {
+	s := &Store{path: path, ticketKey: make([]byte, 32)}
+	if _, err := rand.Read(s.ticketKey); err != nil {
+		return nil, fmt.Errorf("failed to generate ticket key: %w", err)
+	}
+	for _, item := range strings.Split(inline, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		parts := strings.Split(item, ":")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid token %q: expected name:sha256:scopes", item)
+		}
+		s.inline = append(s.inline, Token{Name: parts[0], Hash: parts[1], Scopes: strings.Split(parts[2], "+")})
+	}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Enabled сообщает, задан ли хотя бы один источник токенов
+func (s *Store) Enabled() bool {
+	return len(s.inline) > 0 || s.path != ""
+}
+
+// Reload перечитывает файл токенов. При ошибке остаются прежние токены
+func (s *Store) Reload() error {
+	all := slices.Clone(s.inline)
+	var modTime time.Time
+	if s.path != "" {
+		info, err := os.Stat(s.path)
+		if err != nil {
+			return fmt.Errorf("failed to stat tokens file: %w", err)
+		}
+		data, err := os.ReadFile(s.path)
+		if err != nil {
+			return fmt.Errorf("failed to read tokens file: %w", err)
+		}
+		var file tokensFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			return fmt.Errorf("failed to parse tokens file: %w", err)
+		}
+		all = append(all, file.Tokens...)
+		modTime = info.ModTime()
+	}
+
+	tokens := make(map[string]Token, len(all))
+	for _, t := range all {
+		if err := validate(t); err != nil {
+			return err
+		}
+		tokens[strings.ToLower(t.Hash)] = t
+	}
+
+	s.mu.Lock()
+	s.tokens = tokens
+	s.modTime = modTime
+	s.mu.Unlock()
+	return nil
+}
+
+func validate(t Token) error {
+	if hash, err := hex.DecodeString(t.Hash); err != nil || len(hash) != sha256.Size {
+		return fmt.Errorf("token %q: hash must be hex sha-256", t.Name)
+	}
+	for _, scope := range t.Scopes {
+		if scope != ScopeRead && scope != ScopeWrite && scope != ScopeAdmin {
+			return fmt.Errorf("token %q: unknown scope %q", t.Name, scope)
+		}
+	}
+	return nil
+}
+
+// Authorize находит токен и проверяет, что у него есть право scope
+func (s *Store) Authorize(token, scope string) (Token, error) {
+	if token == "" {
+		return Token{}, ErrUnauthorized
+	}
+	s.mu.RLock()
+	t, ok := s.tokens[HashToken(token)]
+	s.mu.RUnlock()
+	if !ok {
+		return Token{}, ErrUnauthorized
+	}
+	if !slices.Contains(t.Scopes, scope) && !slices.Contains(t.Scopes, ScopeAdmin) {
+		return t, ErrForbidden
+	}
+	return t, nil
+}
+
+// Run перечитывает файл токенов при изменении до отмены контекста
+func (s *Store) Run(ctx context.Context) {
+	if s.path == "" {
+		return
+	}
+	ticker := time.NewTicker(ReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(s.path)
+			if err != nil {
+				log.Printf("Failed to stat tokens file: %v", err)
+				continue
+			}
+			s.mu.RLock()
+			changed := !info.ModTime().Equal(s.modTime)
+			s.mu.RUnlock()
+			if !changed {
+				continue
+			}
+			if err := s.Reload(); err != nil {
+				log.Printf("Failed to reload tokens: %v", err)
+			} else {
+				log.Println("Tokens reloaded")
+			}
+		}
+	}
+}
+
+type contextKey struct{}
+
+// NewContext сохраняет проверенный токен в контексте запроса
+func NewContext(ctx context.Context, t Token) context.Context {
+	return context.WithValue(ctx, contextKey{}, t)
+}
+
+// FromContext возвращает токен, проверенный middleware или перехватчиком
+func FromContext(ctx context.Context) (Token, bool) {
+	t, ok := ctx.Value(contextKey{}).(Token)
+	return t, ok
+}
+
+// hasName сообщает, что токен с таким именем не отозван
+func (s *Store) hasName(name string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, t := range s.tokens {
+		if t.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// HashToken возвращает SHA-256 токена в шестнадцатеричном виде — так токен хранится в конфигурации
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateToken возвращает случайный токен из 32 байт
+func GenerateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
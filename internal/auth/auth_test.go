@@ -0,0 +1,77 @@
+package auth_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/auth"
+)
+
+func TestAuthorize(t *testing.T) {
+	store, err := auth.New(
+		"agent:"+auth.HashToken("agent-token")+":write,"+
+			"grafana:"+auth.HashToken("grafana-token")+":read,"+
+			"ops:"+auth.HashToken("ops-token")+":admin", "")
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		scope   string
+		wantErr error
+	}{
+		{"Агент пишет", "agent-token", auth.ScopeWrite, nil},
+		{"Агент не читает", "agent-token", auth.ScopeRead, auth.ErrForbidden},
+		{"Grafana читает", "grafana-token", auth.ScopeRead, nil},
+		{"Admin включает все права", "ops-token", auth.ScopeWrite, nil},
+		{"Неизвестный токен", "other", auth.ScopeRead, auth.ErrUnauthorized},
+		{"Без токена", "", auth.ScopeRead, auth.ErrUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := store.Authorize(tt.token, tt.scope); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Authorize() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	write := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("WriteFile() failed: %v", err)
+		}
+	}
+
+	write(`{"tokens":[{"name":"agent","hash":"` + auth.HashToken("old") + `","scopes":["write"]}]}`)
+	store, err := auth.New("", path)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	write(`{"tokens":[{"name":"agent","hash":"` + auth.HashToken("new") + `","scopes":["write"]}]}`)
+	if err := store.Reload(); err != nil {
+		t.Fatalf("Reload() failed: %v", err)
+	}
+	if _, err := store.Authorize("old", auth.ScopeWrite); !errors.Is(err, auth.ErrUnauthorized) {
+		t.Errorf("Revoked token still accepted: %v", err)
+	}
+	if _, err := store.Authorize("new", auth.ScopeWrite); err != nil {
+		t.Errorf("New token rejected: %v", err)
+	}
+
+	// Ошибка в файле не сбрасывает действующие токены
+	write(`{"tokens":[{"name":"agent","hash":"plain-token","scopes":["write"]}]}`)
+	if err := store.Reload(); err == nil {
+		t.Error("Reload() accepted unhashed token")
+	}
+	if _, err := store.Authorize("new", auth.ScopeWrite); err != nil {
+		t.Errorf("Token lost after failed reload: %v", err)
+	}
+}
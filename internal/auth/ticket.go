@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TicketCookie — cookie с билетом дашборда: EventSource и fetch отправляют его сами,
+// поэтому долгоживущий токен не нужно передавать в адресе каждого запроса
+const TicketCookie = "metrics_ticket"
+
+// TicketTTL — срок действия билета; билет продлевается, пока дашборд открыт
+const TicketTTL = 15 * time.Minute
+
+var ErrInvalidTicket = errors.New("invalid or expired ticket")
+
+// IssueTicket выпускает билет только с правом read для токена t.
+// Билет подписан ключом процесса и перестаёт действовать после перезапуска сервера
+func (s *Store) IssueTicket(t Token, now time.Time) string {
+	payload := t.Name + "|" + strconv.FormatInt(now.Add(TicketTTL).Unix(), 10)
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+	return encoded + "." + s.ticketMAC(encoded)
+}
+
+// Ticket проверяет билет и возвращает токен с правом read и время истечения билета
+func (s *Store) Ticket(ticket string, now time.Time) (Token, time.Time, error) {
+	encoded, mac, ok := strings.Cut(ticket, ".")
+	if !ok || !hmac.Equal([]byte(mac), []byte(s.ticketMAC(encoded))) {
+		return Token{}, time.Time{}, ErrInvalidTicket
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Token{}, time.Time{}, ErrInvalidTicket
+	}
+	name, expiry, ok := strings.Cut(string(payload), "|")
+	unix, err := strconv.ParseInt(expiry, 10, 64)
+	if !ok || err != nil || !now.Before(time.Unix(unix, 0)) || !s.hasName(name) {
+		return Token{}, time.Time{}, ErrInvalidTicket
+	}
+	return Token{Name: name, Scopes: []string{ScopeRead}}, time.Unix(unix, 0), nil
+}
+
+func (s *Store) ticketMAC(encoded string) string {
+	h := hmac.New(sha256.New, s.ticketKey)
+	h.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,86 @@
+package certs_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/certs"
+	"github.com/akorablin/yandex-practicum-metrics/internal/middleware"
+)
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	bundle, err := certs.GenerateDev([]string{"127.0.0.1"}, time.Hour)
+	if err != nil {
+		t.Fatalf("GenerateDev() failed: %v", err)
+	}
+	if err := bundle.WriteFiles(dir); err != nil {
+		t.Fatalf("WriteFiles() failed: %v", err)
+	}
+	path := func(name string) string { return filepath.Join(dir, name) }
+
+	// Второй CA нужен, чтобы проверить отказ серверу с чужим сертификатом
+	otherDir := t.TempDir()
+	other, err := certs.GenerateDev([]string{"127.0.0.1"}, time.Hour)
+	if err != nil {
+		t.Fatalf("GenerateDev() failed: %v", err)
+	}
+	if err := other.WriteFiles(otherDir); err != nil {
+		t.Fatalf("WriteFiles() failed: %v", err)
+	}
+
+	serverTLS, err := certs.NewServerTLS(path(certs.ServerCertFile), path(certs.ServerKeyFile), path(certs.CAFile))
+	if err != nil {
+		t.Fatalf("NewServerTLS() failed: %v", err)
+	}
+	// Сертификат при рукопожатии необязателен, приём метрик требует его в middleware
+	server := httptest.NewUnstartedServer(middleware.ClientCert(true)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+	server.TLS = serverTLS
+	server.StartTLS()
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		caFile   string
+		certFile string
+		keyFile  string
+		method   string
+		path     string
+		status   int
+		wantErr  bool
+	}{
+		{"Клиентский сертификат и CA сервера", path(certs.CAFile), path(certs.ClientCertFile), path(certs.ClientKeyFile), http.MethodPost, "/update/gauge/Alloc/1", http.StatusOK, false},
+		{"Приём без клиентского сертификата", path(certs.CAFile), "", "", http.MethodPost, "/update/gauge/Alloc/1", http.StatusForbidden, false},
+		{"Проверка готовности без клиентского сертификата", path(certs.CAFile), "", "", http.MethodGet, "/readyz", http.StatusOK, false},
+		{"Сертификат чужого CA", path(certs.CAFile), filepath.Join(otherDir, certs.ClientCertFile), filepath.Join(otherDir, certs.ClientKeyFile), http.MethodGet, "/readyz", 0, true},
+		{"Сервер не из закреплённого CA", filepath.Join(otherDir, certs.CAFile), path(certs.ClientCertFile), path(certs.ClientKeyFile), http.MethodGet, "/readyz", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientTLS, err := certs.NewClientTLS(tt.caFile, tt.certFile, tt.keyFile)
+			if err != nil {
+				t.Fatalf("NewClientTLS() failed: %v", err)
+			}
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+
+			req, _ := http.NewRequest(tt.method, server.URL+tt.path, nil)
+			resp, err := client.Do(req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Do() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, resp.StatusCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Имена файлов, которые записывает WriteFiles
+const (
+	CAFile         = "ca.pem"
+	ServerCertFile = "server.pem"
+	ServerKeyFile  = "server-key.pem"
+	ClientCertFile = "client.pem"
+	ClientKeyFile  = "client-key.pem"
+)
+
+// Bundle — локальный CA и выпущенные им сертификаты сервера и клиента в формате PEM
+type Bundle struct {
+	CACert     []byte
+	ServerCert []byte
+	ServerKey  []byte
+	ClientCert []byte
+	ClientKey  []byte
+}
+
+// GenerateDev выпускает CA и сертификаты для разработки и тестов.
+// hosts — DNS-имена и IP-адреса, на которые выписывается сертификат сервера
+func GenerateDev(hosts []string, validFor time.Duration) (*Bundle, error) {
+	notBefore := time.Now().Add(-time.Minute)
+	notAfter := notBefore.Add(validFor)
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate CA key: %w", err)
+	}
+	caTemplate := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "metrics dev CA"},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := issue(caTemplate, caTemplate, caKey, caKey)
+	if err != nil {
+		return nil, err
+	}
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CA certificate: %w", err)
+	}
+
+	bundle := &Bundle{CACert: encodePEM("CERTIFICATE", caDER)}
+
+	serverTemplate := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "metrics server"},
+		NotBefore:   notBefore,
+		NotAfter:    notAfter,
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			serverTemplate.IPAddresses = append(serverTemplate.IPAddresses, ip)
+		} else {
+			serverTemplate.DNSNames = append(serverTemplate.DNSNames, host)
+		}
+	}
+	if bundle.ServerCert, bundle.ServerKey, err = issueLeaf(serverTemplate, caCert, caKey); err != nil {
+		return nil, err
+	}
+
+	clientTemplate := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "metrics agent"},
+		NotBefore:   notBefore,
+		NotAfter:    notAfter,
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if bundle.ClientCert, bundle.ClientKey, err = issueLeaf(clientTemplate, caCert, caKey); err != nil {
+		return nil, err
+	}
+
+	return bundle, nil
+}
+
+// WriteFiles сохраняет сертификаты в каталог dir; ключи доступны только владельцу
+func (b *Bundle) WriteFiles(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	files := []struct {
+		name string
+		data []byte
+		perm os.FileMode
+	}{
+		{CAFile, b.CACert, 0o644},
+		{ServerCertFile, b.ServerCert, 0o644},
+		{ServerKeyFile, b.ServerKey, 0o600},
+		{ClientCertFile, b.ClientCert, 0o644},
+		{ClientKeyFile, b.ClientKey, 0o600},
+	}
+	for _, f := range files {
+		if err := os.WriteFile(filepath.Join(dir, f.name), f.data, f.perm); err != nil {
+			return fmt.Errorf("failed to write %s: %w", f.name, err)
+		}
+	}
+	return nil
+}
+
+func issueLeaf(template, ca *x509.Certificate, caKey *ecdsa.PrivateKey) (certPEM, keyPEM []byte, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	der, err := issue(template, ca, key, caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal key: %w", err)
+	}
+	return encodePEM("CERTIFICATE", der), encodePEM("PRIVATE KEY", keyDER), nil
+}
+
+func issue(template, parent *x509.Certificate, key, parentKey *ecdsa.PrivateKey) ([]byte, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate serial number: %w", err)
+	}
+	template.SerialNumber = serial
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create certificate %q: %w", template.Subject.CommonName, err)
+	}
+	return der, nil
+}
+
+func encodePEM(blockType string, der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+}
@@ -0,0 +1,124 @@
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Имена алгоритмов в заголовках Content-Encoding и Accept-Encoding
+const (
+	Gzip     = "gzip"
+	Deflate  = "deflate"
+	Zstd     = "zstd"
+	Brotli   = "br"
+	Identity = "identity"
+)
+
+// Предел памяти декодера zstd: окно больше этого значения считается атакой
+const zstdMaxMemory = 64 << 20
+
+var ErrUnsupported = errors.New("unsupported content encoding")
+
+// Preferred задаёт порядок выбора сервером среди одинаково приемлемых для клиента алгоритмов
+var Preferred = []string{Zstd, Brotli, Gzip, Deflate}
+
+// Supported сообщает, поддерживается ли алгоритм
+func Supported(encoding string) bool {
+	switch encoding {
+	case Gzip, Deflate, Zstd, Brotli:
+		return true
+	}
+	return false
+}
+
+// NewReader распаковывает поток. deflate в HTTP — это формат zlib (RFC 9110)
+func NewReader(encoding string, r io.Reader) (io.ReadCloser, error) {
+	switch encoding {
+	case Gzip:
+		return gzip.NewReader(r)
+	case Deflate:
+		return zlib.NewReader(r)
+	case Zstd:
+		dec, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(zstdMaxMemory))
+		if err != nil {
+			return nil, err
+		}
+		return dec.IOReadCloser(), nil
+	case Brotli:
+		return io.NopCloser(brotli.NewReader(r)), nil
+	}
+	return nil, ErrUnsupported
+}
+
+// NewWriter сжимает поток. Close дописывает завершающий блок, но не закрывает w
+func NewWriter(encoding string, w io.Writer) (io.WriteCloser, error) {
+	switch encoding {
+	case Gzip:
+		return gzip.NewWriter(w), nil
+	case Deflate:
+		return zlib.NewWriter(w), nil
+	case Zstd:
+		return zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+	case Brotli:
+		return brotli.NewWriter(w), nil
+	}
+	return nil, ErrUnsupported
+}
+
+// Compress сжимает данные целиком
+func Compress(encoding string, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := NewWriter(encoding, &buf)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Negotiate выбирает алгоритм по заголовку Accept-Encoding с учётом весов q.
+// Пустая строка означает, что ответ отправляется без сжатия
+func Negotiate(acceptEncoding string) string {
+	weights := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		weights[name] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoding := range Preferred {
+		q, ok := weights[encoding]
+		if !ok {
+			q = weights["*"]
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
@@ -0,0 +1,67 @@
+package compression_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/compression"
+)
+
+func TestRoundTrip(t *testing.T) {
+	data := bytes.Repeat([]byte(`{"id":"Alloc","type":"gauge","value":1}`), 100)
+
+	for _, encoding := range compression.Preferred {
+		t.Run(encoding, func(t *testing.T) {
+			compressed, err := compression.Compress(encoding, data)
+			if err != nil {
+				t.Fatalf("Compress: %v", err)
+			}
+			if len(compressed) >= len(data) {
+				t.Errorf("Compressed size %d is not smaller than %d", len(compressed), len(data))
+			}
+
+			r, err := compression.NewReader(encoding, bytes.NewReader(compressed))
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer r.Close()
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("ReadAll: %v", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Error("Decompressed data differs from original")
+			}
+		})
+	}
+
+	if _, err := compression.NewReader("lz4", bytes.NewReader(nil)); err != compression.ErrUnsupported {
+		t.Errorf("Expected ErrUnsupported, got %v", err)
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept string
+		want   string
+	}{
+		{"Без заголовка", "", ""},
+		{"Только gzip", "gzip", "gzip"},
+		{"Предпочтение сервера", "gzip, deflate, br, zstd", "zstd"},
+		{"Веса клиента", "gzip;q=1.0, br;q=0.5", "gzip"},
+		{"Запрет через q=0", "zstd;q=0, *", "br"},
+		{"Неизвестный алгоритм", "lz4", ""},
+		{"Только identity", "identity", ""},
+		{"Регистр не важен", "GZIP", "gzip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := compression.Negotiate(tt.accept); got != tt.want {
+				t.Errorf("Negotiate(%q) = %q, want %q", tt.accept, got, tt.want)
+			}
+		})
+	}
+}
@@ -6,6 +6,20 @@ import (
 	"os"
 	"strconv"
 	"time"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/compression"
+)
+
+const (
+	TypeConflictSeparate = "separate"
+	TypeConflictReject   = "reject"
+)
+
+// Чем идентифицируется клиент для ограничения частоты и квот
+const (
+	RateLimitByIP    = "ip"
+	RateLimitByToken = "token"
+	RateLimitByAgent = "agent"
 )
 
 type ServerConfig struct {
@@ -15,12 +29,116 @@ type ServerConfig struct {
 	FileStoragePath string
 	Restore         bool
 	DataBaseDSN     string
+
+	// Приём метрик по протоколу StatsD (пустой адрес — приём отключён)
+	StatsDAddress       string
+	StatsDFlushInterval int
+
+	// Приём метрик по протоколу Graphite (пустой адрес — приём отключён)
+	GraphiteAddress        string
+	GraphiteCounters       string
+	GraphiteMaxConnections int
+	// Соединение без данных дольше стольких секунд закрывается (0 — без ограничения)
+	GraphiteIdleTimeout int
+
+	// Тип метрики, в который превращаются целые и дробные поля InfluxDB line protocol
+	InfluxIntegerType string
+	InfluxFloatType   string
+
+	// gRPC-сервис метрик (пустой адрес — сервис отключён)
+	GRPCAddress string
+
+	// Размер буфера подписчика потока обновлений GET /stream
+	StreamBufferSize int
+
+	// Правила алертинга (пустой путь — алертинг отключён) и интервал их вычисления
+	AlertRulesFile    string
+	AlertEvalInterval int
+
+	// История значений метрик: интервал снятия (0 — история отключена) и срок хранения в секундах
+	HistoryInterval  int
+	HistoryRetention int
+
+	// Интервал записи собственных метрик сервера в хранилище (0 — самоинструментирование отключено)
+	SelfMetricsInterval int
+
+	// Отклонять обновления, тип которых противоречит метаданным метрики
+	EnforceMetadataType bool
+
+	// Поведение при совпадении ID у gauge и counter: separate — независимые метрики, reject — ошибка
+	TypeConflictPolicy string
+
+	// Ключ подписи HMAC-SHA256 (пустой — подпись не проверяется)
+	Key string
+	// Маршруты приёма через запятую, где подпись необязательна: Telegraf (/write) и OTel (/v1/metrics)
+	// не умеют подписывать запросы. Присланная подпись на них всё равно проверяется
+	UnsignedRoutes string
+
+	// Сертификат и ключ TLS (пустые — HTTP без шифрования) и CA клиентских сертификатов для mTLS
+	TLSCertFile     string
+	TLSKeyFile      string
+	TLSClientCAFile string
+
+	// Закрытый ключ RSA для расшифровки тел запросов агента (пустой — расшифровка отключена)
+	CryptoKey string
+
+	// Токены доступа: список name:sha256:scope+scope через запятую и файл токенов (оба пустые — доступ открыт)
+	AuthTokens     string
+	AuthTokensFile string
+
+	// Подсеть агентов в формате CIDR (пустая — приём от любых адресов) и подсети прокси,
+	// чьим заголовкам X-Real-IP и X-Forwarded-For можно верить
+	TrustedSubnet  string
+	TrustedProxies string
+
+	// Ограничение частоты запросов на приём метрик на клиента (0 — без ограничения), размер всплеска
+	// и способ идентификации клиента: ip, token или agent (заголовок X-Agent-ID)
+	RateLimit   float64
+	RateBurst   int
+	RateLimitBy string
+
+	// Квоты клиента: метрик в одном запросе и различных рядов за час (0 — без квоты)
+	QuotaBatchMetrics int
+	QuotaSeries       int
+
+	// Лимиты запроса: сжатое и распакованное тело в байтах, метрик в пакете и длина ID (0 — без лимита)
+	MaxBodySize         int
+	MaxDecompressedSize int
+	MaxBatchSize        int
+	MaxIDLength         int
+
+	// Ответы короче этого размера в байтах не сжимаются
+	CompressMinSize int
 }
 
 type AgentConfig struct {
 	Address        string
 	PollInterval   time.Duration
 	ReportInterval time.Duration
+
+	// Транспорт отправки метрик: http или grpc
+	Transport   string
+	GRPCAddress string
+
+	// Ключ подписи HMAC-SHA256 (пустой — запросы не подписываются)
+	Key string
+
+	// CA сервера (пустой — системные CA) и клиентский сертификат для mTLS
+	TLSCAFile   string
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// Открытый ключ RSA сервера для шифрования тел запросов (пустой — без шифрования)
+	CryptoKey string
+
+	// Bearer-токен с правом write
+	Token string
+
+	// Идентификатор агента в заголовке X-Agent-ID (по умолчанию имя хоста)
+	ID string
+
+	// Алгоритм сжатия тел запросов: gzip, deflate, zstd, br (пустой — без сжатия)
+	Compress string
 }
 
 func getEnvOrDefaultString(envVar string, defaultValue string) string {
@@ -39,6 +157,15 @@ func getEnvOrDefaultInt(envVar string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvOrDefaultFloat(envVar string, defaultValue float64) float64 {
+	if value, ok := os.LookupEnv(envVar); ok {
+		if parsedValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return parsedValue
+		}
+	}
+	return defaultValue
+}
+
 func getEnvOrDefaultBool(envVar string, defaultValue bool) bool {
 	if value, ok := os.LookupEnv(envVar); ok {
 		if parsedValue, err := strconv.ParseBool(value); err == nil {
@@ -66,6 +193,61 @@ func GetServerConfig() (*ServerConfig, error) {
 		FileStoragePath: getEnvOrDefaultString("FILE_STORAGE_PATH", "tmp/metrics.json"),
 		Restore:         getEnvOrDefaultBool("RESTORE", true),
 		DataBaseDSN:     getEnvOrDefaultString("DATABASE_DSN", ""),
+
+		StatsDAddress:       getEnvOrDefaultString("STATSD_ADDRESS", ""),
+		StatsDFlushInterval: getEnvOrDefaultInt("STATSD_FLUSH_INTERVAL", 10),
+
+		GraphiteAddress:        getEnvOrDefaultString("GRAPHITE_ADDRESS", ""),
+		GraphiteCounters:       getEnvOrDefaultString("GRAPHITE_COUNTERS", ""),
+		GraphiteMaxConnections: getEnvOrDefaultInt("GRAPHITE_MAX_CONNECTIONS", 100),
+		GraphiteIdleTimeout:    getEnvOrDefaultInt("GRAPHITE_IDLE_TIMEOUT", 300),
+
+		InfluxIntegerType: getEnvOrDefaultString("INFLUX_INTEGER_TYPE", "gauge"),
+		InfluxFloatType:   getEnvOrDefaultString("INFLUX_FLOAT_TYPE", "gauge"),
+
+		GRPCAddress: getEnvOrDefaultString("GRPC_ADDRESS", ""),
+
+		StreamBufferSize: getEnvOrDefaultInt("STREAM_BUFFER_SIZE", 256),
+
+		AlertRulesFile:    getEnvOrDefaultString("ALERT_RULES_FILE", ""),
+		AlertEvalInterval: getEnvOrDefaultInt("ALERT_EVAL_INTERVAL", 15),
+
+		HistoryInterval:  getEnvOrDefaultInt("HISTORY_INTERVAL", 0),
+		HistoryRetention: getEnvOrDefaultInt("HISTORY_RETENTION", 21600),
+
+		SelfMetricsInterval: getEnvOrDefaultInt("SELF_METRICS_INTERVAL", 0),
+
+		EnforceMetadataType: getEnvOrDefaultBool("ENFORCE_METADATA_TYPE", false),
+		TypeConflictPolicy:  getEnvOrDefaultString("TYPE_CONFLICT_POLICY", TypeConflictSeparate),
+
+		Key:            getEnvOrDefaultString("KEY", ""),
+		UnsignedRoutes: getEnvOrDefaultString("UNSIGNED_ROUTES", ""),
+
+		TLSCertFile:     getEnvOrDefaultString("TLS_CERT_FILE", ""),
+		TLSKeyFile:      getEnvOrDefaultString("TLS_KEY_FILE", ""),
+		TLSClientCAFile: getEnvOrDefaultString("TLS_CLIENT_CA_FILE", ""),
+
+		CryptoKey: getEnvOrDefaultString("CRYPTO_KEY", ""),
+
+		AuthTokens:     getEnvOrDefaultString("AUTH_TOKENS", ""),
+		AuthTokensFile: getEnvOrDefaultString("AUTH_TOKENS_FILE", ""),
+
+		TrustedSubnet:  getEnvOrDefaultString("TRUSTED_SUBNET", ""),
+		TrustedProxies: getEnvOrDefaultString("TRUSTED_PROXIES", "127.0.0.0/8,::1/128"),
+
+		RateLimit:   getEnvOrDefaultFloat("RATE_LIMIT", 0),
+		RateBurst:   getEnvOrDefaultInt("RATE_BURST", 20),
+		RateLimitBy: getEnvOrDefaultString("RATE_LIMIT_BY", RateLimitByIP),
+
+		QuotaBatchMetrics: getEnvOrDefaultInt("QUOTA_BATCH_METRICS", 0),
+		QuotaSeries:       getEnvOrDefaultInt("QUOTA_SERIES", 0),
+
+		MaxBodySize:         getEnvOrDefaultInt("MAX_BODY_SIZE", 10<<20),
+		MaxDecompressedSize: getEnvOrDefaultInt("MAX_DECOMPRESSED_SIZE", 32<<20),
+		MaxBatchSize:        getEnvOrDefaultInt("MAX_BATCH_SIZE", 10000),
+		MaxIDLength:         getEnvOrDefaultInt("MAX_ID_LENGTH", 256),
+
+		CompressMinSize: getEnvOrDefaultInt("COMPRESS_MIN_SIZE", 1024),
 	}
 
 	// Настройки из командной строки
@@ -75,6 +257,43 @@ func GetServerConfig() (*ServerConfig, error) {
 	fileStoragePath := flag.String("f", cfg.FileStoragePath, "file storage path")
 	restore := flag.Bool("r", cfg.Restore, "restore")
 	dataBaseDSN := flag.String("d", cfg.DataBaseDSN, "database dsn")
+	statsDAddress := flag.String("statsd", cfg.StatsDAddress, "statsd udp listen address")
+	statsDFlushInterval := flag.Int("statsd-flush", cfg.StatsDFlushInterval, "statsd flush interval")
+	graphiteAddress := flag.String("graphite", cfg.GraphiteAddress, "graphite tcp listen address")
+	graphiteCounters := flag.String("graphite-counters", cfg.GraphiteCounters, "comma-separated graphite path patterns stored as counters")
+	graphiteMaxConnections := flag.Int("graphite-max-conns", cfg.GraphiteMaxConnections, "graphite max concurrent connections")
+	graphiteIdleTimeout := flag.Int("graphite-idle-timeout", cfg.GraphiteIdleTimeout, "graphite idle connection timeout in seconds (0 disables)")
+	influxIntegerType := flag.String("influx-int", cfg.InfluxIntegerType, "metric type for influx integer fields (gauge or counter)")
+	influxFloatType := flag.String("influx-float", cfg.InfluxFloatType, "metric type for influx float fields (gauge or counter)")
+	grpcAddress := flag.String("grpc", cfg.GRPCAddress, "grpc listen address")
+	streamBufferSize := flag.Int("stream-buffer", cfg.StreamBufferSize, "stream subscriber buffer size")
+	alertRulesFile := flag.String("alert-rules", cfg.AlertRulesFile, "alert rules file path")
+	alertEvalInterval := flag.Int("alert-interval", cfg.AlertEvalInterval, "alert rules evaluation interval")
+	historyInterval := flag.Int("history-interval", cfg.HistoryInterval, "history sampling interval (0 disables history, default)")
+	historyRetention := flag.Int("history-retention", cfg.HistoryRetention, "history retention")
+	selfMetricsInterval := flag.Int("self-metrics-interval", cfg.SelfMetricsInterval, "server self metrics flush interval (0 disables self metrics, default)")
+	enforceMetadataType := flag.Bool("enforce-meta-type", cfg.EnforceMetadataType, "reject updates contradicting metadata type")
+	typeConflictPolicy := flag.String("type-conflict", cfg.TypeConflictPolicy, "gauge and counter with the same id: separate or reject")
+	key := flag.String("k", cfg.Key, "hmac-sha256 signing key")
+	unsignedRoutes := flag.String("unsigned-routes", cfg.UnsignedRoutes, "comma-separated ingest routes accepted without signature (e.g. /write,/v1/metrics)")
+	tlsCertFile := flag.String("tls-cert", cfg.TLSCertFile, "tls certificate file")
+	tlsKeyFile := flag.String("tls-key", cfg.TLSKeyFile, "tls private key file")
+	tlsClientCAFile := flag.String("tls-client-ca", cfg.TLSClientCAFile, "ca file to verify client certificates (enables mtls)")
+	cryptoKey := flag.String("crypto-key", cfg.CryptoKey, "rsa private key file to decrypt request bodies")
+	authTokens := flag.String("auth-tokens", cfg.AuthTokens, "comma-separated tokens name:sha256:scope+scope")
+	authTokensFile := flag.String("auth-tokens-file", cfg.AuthTokensFile, "tokens file path (reloaded on change)")
+	trustedSubnet := flag.String("t", cfg.TrustedSubnet, "trusted agent subnet (cidr)")
+	trustedProxies := flag.String("trusted-proxies", cfg.TrustedProxies, "comma-separated proxy subnets whose ip headers are trusted")
+	rateLimit := flag.Float64("rate-limit", cfg.RateLimit, "ingest requests per second per client (0 disables)")
+	rateBurst := flag.Int("rate-burst", cfg.RateBurst, "ingest request burst per client")
+	rateLimitBy := flag.String("rate-limit-by", cfg.RateLimitBy, "client identity for limits: ip, token or agent")
+	quotaBatchMetrics := flag.Int("quota-batch", cfg.QuotaBatchMetrics, "max metrics per ingest request (0 disables)")
+	quotaSeries := flag.Int("quota-series", cfg.QuotaSeries, "max distinct series per client per hour (0 disables)")
+	maxBodySize := flag.Int("max-body", cfg.MaxBodySize, "max request body size in bytes as received (0 disables)")
+	maxDecompressedSize := flag.Int("max-decompressed", cfg.MaxDecompressedSize, "max decompressed request body size in bytes (0 disables)")
+	maxBatchSize := flag.Int("max-batch", cfg.MaxBatchSize, "max metrics per batch (0 disables)")
+	maxIDLength := flag.Int("max-id-length", cfg.MaxIDLength, "max metric id length (0 disables)")
+	compressMinSize := flag.Int("compress-min-size", cfg.CompressMinSize, "min response size in bytes to compress")
 	flag.Parse()
 
 	// Валидация командной строки
@@ -84,6 +303,76 @@ func GetServerConfig() (*ServerConfig, error) {
 		flag.PrintDefaults()
 		return nil, fmt.Errorf("unknown arguments provided")
 	}
+	if *statsDAddress != "" && *statsDFlushInterval <= 0 {
+		fmt.Fprintf(os.Stderr, "Error: statsd flush interval must be positive, got %d\n", *statsDFlushInterval)
+		return nil, fmt.Errorf("incorrect statsDFlushInterval")
+	}
+	if *graphiteAddress != "" && *graphiteMaxConnections <= 0 {
+		fmt.Fprintf(os.Stderr, "Error: graphite max connections must be positive, got %d\n", *graphiteMaxConnections)
+		return nil, fmt.Errorf("incorrect graphiteMaxConnections")
+	}
+	if *graphiteIdleTimeout < 0 {
+		fmt.Fprintf(os.Stderr, "Error: graphite idle timeout must not be negative, got %d\n", *graphiteIdleTimeout)
+		return nil, fmt.Errorf("incorrect graphiteIdleTimeout")
+	}
+	for _, metricType := range []string{*influxIntegerType, *influxFloatType} {
+		if metricType != "gauge" && metricType != "counter" {
+			fmt.Fprintf(os.Stderr, "Error: influx field type must be gauge or counter, got %s\n", metricType)
+			return nil, fmt.Errorf("incorrect influx field type")
+		}
+	}
+	if *streamBufferSize <= 0 {
+		fmt.Fprintf(os.Stderr, "Error: stream buffer size must be positive, got %d\n", *streamBufferSize)
+		return nil, fmt.Errorf("incorrect streamBufferSize")
+	}
+	if *alertRulesFile != "" && *alertEvalInterval <= 0 {
+		fmt.Fprintf(os.Stderr, "Error: alert evaluation interval must be positive, got %d\n", *alertEvalInterval)
+		return nil, fmt.Errorf("incorrect alertEvalInterval")
+	}
+	if *historyInterval > 0 && *historyRetention < *historyInterval {
+		fmt.Fprintf(os.Stderr, "Error: history retention must be at least history interval, got %d\n", *historyRetention)
+		return nil, fmt.Errorf("incorrect historyRetention")
+	}
+	if *selfMetricsInterval < 0 {
+		fmt.Fprintf(os.Stderr, "Error: self metrics interval must not be negative, got %d\n", *selfMetricsInterval)
+		return nil, fmt.Errorf("incorrect selfMetricsInterval")
+	}
+	if *typeConflictPolicy != TypeConflictSeparate && *typeConflictPolicy != TypeConflictReject {
+		fmt.Fprintf(os.Stderr, "Error: type conflict policy must be separate or reject, got %s\n", *typeConflictPolicy)
+		return nil, fmt.Errorf("incorrect typeConflictPolicy")
+	}
+	if *rateLimit < 0 {
+		fmt.Fprintf(os.Stderr, "Error: rate limit must not be negative, got %v\n", *rateLimit)
+		return nil, fmt.Errorf("incorrect rateLimit")
+	}
+	if *rateLimit > 0 && *rateBurst <= 0 {
+		fmt.Fprintf(os.Stderr, "Error: rate burst must be positive, got %d\n", *rateBurst)
+		return nil, fmt.Errorf("incorrect rateBurst")
+	}
+	if *rateLimitBy != RateLimitByIP && *rateLimitBy != RateLimitByToken && *rateLimitBy != RateLimitByAgent {
+		fmt.Fprintf(os.Stderr, "Error: rate limit identity must be ip, token or agent, got %s\n", *rateLimitBy)
+		return nil, fmt.Errorf("incorrect rateLimitBy")
+	}
+	if *quotaBatchMetrics < 0 || *quotaSeries < 0 {
+		fmt.Fprintf(os.Stderr, "Error: quotas must not be negative\n")
+		return nil, fmt.Errorf("incorrect quota")
+	}
+	if *maxBodySize < 0 || *maxDecompressedSize < 0 || *maxBatchSize < 0 || *maxIDLength < 0 {
+		fmt.Fprintf(os.Stderr, "Error: request limits must not be negative\n")
+		return nil, fmt.Errorf("incorrect request limit")
+	}
+	if *compressMinSize < 0 {
+		fmt.Fprintf(os.Stderr, "Error: compress min size must not be negative, got %d\n", *compressMinSize)
+		return nil, fmt.Errorf("incorrect compressMinSize")
+	}
+	if (*tlsCertFile == "") != (*tlsKeyFile == "") {
+		fmt.Fprintf(os.Stderr, "Error: tls certificate and key must be set together\n")
+		return nil, fmt.Errorf("incorrect tls certificate")
+	}
+	if *tlsClientCAFile != "" && *tlsCertFile == "" {
+		fmt.Fprintf(os.Stderr, "Error: tls client ca requires tls certificate and key\n")
+		return nil, fmt.Errorf("incorrect tlsClientCAFile")
+	}
 
 	// Сохраняем настройки
 	cfg.Address = *serverAddress
@@ -92,6 +381,43 @@ func GetServerConfig() (*ServerConfig, error) {
 	cfg.FileStoragePath = *fileStoragePath
 	cfg.Restore = *restore
 	cfg.DataBaseDSN = *dataBaseDSN
+	cfg.StatsDAddress = *statsDAddress
+	cfg.StatsDFlushInterval = *statsDFlushInterval
+	cfg.GraphiteAddress = *graphiteAddress
+	cfg.GraphiteCounters = *graphiteCounters
+	cfg.GraphiteMaxConnections = *graphiteMaxConnections
+	cfg.GraphiteIdleTimeout = *graphiteIdleTimeout
+	cfg.InfluxIntegerType = *influxIntegerType
+	cfg.InfluxFloatType = *influxFloatType
+	cfg.GRPCAddress = *grpcAddress
+	cfg.StreamBufferSize = *streamBufferSize
+	cfg.AlertRulesFile = *alertRulesFile
+	cfg.AlertEvalInterval = *alertEvalInterval
+	cfg.HistoryInterval = *historyInterval
+	cfg.HistoryRetention = *historyRetention
+	cfg.SelfMetricsInterval = *selfMetricsInterval
+	cfg.EnforceMetadataType = *enforceMetadataType
+	cfg.TypeConflictPolicy = *typeConflictPolicy
+	cfg.Key = *key
+	cfg.UnsignedRoutes = *unsignedRoutes
+	cfg.TLSCertFile = *tlsCertFile
+	cfg.TLSKeyFile = *tlsKeyFile
+	cfg.TLSClientCAFile = *tlsClientCAFile
+	cfg.CryptoKey = *cryptoKey
+	cfg.AuthTokens = *authTokens
+	cfg.AuthTokensFile = *authTokensFile
+	cfg.TrustedSubnet = *trustedSubnet
+	cfg.TrustedProxies = *trustedProxies
+	cfg.RateLimit = *rateLimit
+	cfg.RateBurst = *rateBurst
+	cfg.RateLimitBy = *rateLimitBy
+	cfg.QuotaBatchMetrics = *quotaBatchMetrics
+	cfg.QuotaSeries = *quotaSeries
+	cfg.MaxBodySize = *maxBodySize
+	cfg.MaxDecompressedSize = *maxDecompressedSize
+	cfg.MaxBatchSize = *maxBatchSize
+	cfg.MaxIDLength = *maxIDLength
+	cfg.CompressMinSize = *compressMinSize
 
 	// Отображение настроек
 	fmt.Println("Server Address:", cfg.Address)
@@ -100,6 +426,41 @@ func GetServerConfig() (*ServerConfig, error) {
 	fmt.Println("File Storage Path:", cfg.FileStoragePath)
 	fmt.Println("Restore:", cfg.Restore)
 	fmt.Println("DataBaseDSN:", cfg.DataBaseDSN)
+	fmt.Println("StatsD Address:", cfg.StatsDAddress)
+	fmt.Println("StatsD Flush Interval:", cfg.StatsDFlushInterval)
+	fmt.Println("Graphite Address:", cfg.GraphiteAddress)
+	fmt.Println("Graphite Counters:", cfg.GraphiteCounters)
+	fmt.Println("Graphite Max Connections:", cfg.GraphiteMaxConnections)
+	fmt.Println("Graphite Idle Timeout:", cfg.GraphiteIdleTimeout)
+	fmt.Println("Influx Integer Type:", cfg.InfluxIntegerType)
+	fmt.Println("Influx Float Type:", cfg.InfluxFloatType)
+	fmt.Println("gRPC Address:", cfg.GRPCAddress)
+	fmt.Println("Stream Buffer Size:", cfg.StreamBufferSize)
+	fmt.Println("Alert Rules File:", cfg.AlertRulesFile)
+	fmt.Println("Alert Eval Interval:", cfg.AlertEvalInterval)
+	fmt.Println("History Interval:", cfg.HistoryInterval)
+	fmt.Println("History Retention:", cfg.HistoryRetention)
+	fmt.Println("Self Metrics Interval:", cfg.SelfMetricsInterval)
+	fmt.Println("Enforce Metadata Type:", cfg.EnforceMetadataType)
+	fmt.Println("Type Conflict Policy:", cfg.TypeConflictPolicy)
+	fmt.Println("Signing Enabled:", cfg.Key != "")
+	fmt.Println("Unsigned Routes:", cfg.UnsignedRoutes)
+	fmt.Println("TLS Certificate:", cfg.TLSCertFile)
+	fmt.Println("TLS Client CA:", cfg.TLSClientCAFile)
+	fmt.Println("Crypto Key:", cfg.CryptoKey)
+	fmt.Println("Auth Tokens File:", cfg.AuthTokensFile)
+	fmt.Println("Trusted Subnet:", cfg.TrustedSubnet)
+	fmt.Println("Trusted Proxies:", cfg.TrustedProxies)
+	fmt.Println("Rate Limit:", cfg.RateLimit)
+	fmt.Println("Rate Burst:", cfg.RateBurst)
+	fmt.Println("Rate Limit By:", cfg.RateLimitBy)
+	fmt.Println("Quota Batch Metrics:", cfg.QuotaBatchMetrics)
+	fmt.Println("Quota Series:", cfg.QuotaSeries)
+	fmt.Println("Max Body Size:", cfg.MaxBodySize)
+	fmt.Println("Max Decompressed Size:", cfg.MaxDecompressedSize)
+	fmt.Println("Max Batch Size:", cfg.MaxBatchSize)
+	fmt.Println("Max ID Length:", cfg.MaxIDLength)
+	fmt.Println("Compress Min Size:", cfg.CompressMinSize)
 
 	return cfg, nil
 }
@@ -110,6 +471,19 @@ func GetAgentConfig() (*AgentConfig, error) {
 		Address:        getEnvOrDefaultString("ADDRESS", "localhost:8080"),
 		PollInterval:   getEnvOrDefaultTimeDuration("POLL_INTERVAL", 2*time.Second),
 		ReportInterval: getEnvOrDefaultTimeDuration("REPORT_INTERVAL", 10*time.Second),
+		Transport:      getEnvOrDefaultString("TRANSPORT", "http"),
+		GRPCAddress:    getEnvOrDefaultString("GRPC_ADDRESS", "localhost:3200"),
+		Key:            getEnvOrDefaultString("KEY", ""),
+		TLSCAFile:      getEnvOrDefaultString("TLS_CA_FILE", ""),
+		TLSCertFile:    getEnvOrDefaultString("TLS_CERT_FILE", ""),
+		TLSKeyFile:     getEnvOrDefaultString("TLS_KEY_FILE", ""),
+		CryptoKey:      getEnvOrDefaultString("CRYPTO_KEY", ""),
+		Token:          getEnvOrDefaultString("AUTH_TOKEN", ""),
+		ID:             getEnvOrDefaultString("AGENT_ID", ""),
+		Compress:       getEnvOrDefaultString("COMPRESS", compression.Gzip),
+	}
+	if cfg.ID == "" {
+		cfg.ID, _ = os.Hostname()
 	}
 
 	// Настройки из командной строки
@@ -117,6 +491,16 @@ func GetAgentConfig() (*AgentConfig, error) {
 	flag.StringVar(&cfg.Address, "a", cfg.Address, "server address")
 	flag.IntVar(&pollInterval, "p", int(cfg.PollInterval.Seconds()), "poll interval")
 	flag.IntVar(&reportInterval, "r", int(cfg.ReportInterval.Seconds()), "report interval")
+	flag.StringVar(&cfg.Transport, "transport", cfg.Transport, "transport (http or grpc)")
+	flag.StringVar(&cfg.GRPCAddress, "grpc", cfg.GRPCAddress, "grpc server address")
+	flag.StringVar(&cfg.Key, "k", cfg.Key, "hmac-sha256 signing key")
+	flag.StringVar(&cfg.TLSCAFile, "tls-ca", cfg.TLSCAFile, "ca file to verify server certificate")
+	flag.StringVar(&cfg.TLSCertFile, "tls-cert", cfg.TLSCertFile, "client tls certificate file")
+	flag.StringVar(&cfg.TLSKeyFile, "tls-key", cfg.TLSKeyFile, "client tls private key file")
+	flag.StringVar(&cfg.CryptoKey, "crypto-key", cfg.CryptoKey, "server rsa public key file to encrypt request bodies")
+	flag.StringVar(&cfg.Token, "token", cfg.Token, "bearer token")
+	flag.StringVar(&cfg.ID, "id", cfg.ID, "agent id")
+	flag.StringVar(&cfg.Compress, "compress", cfg.Compress, "request body compression (gzip, deflate, zstd, br or empty)")
 	flag.Parse()
 
 	// Валидация командной строки
@@ -134,6 +518,23 @@ func GetAgentConfig() (*AgentConfig, error) {
 		fmt.Fprintf(os.Stderr, "Error: report interval must be positive, got %d\n", reportInterval)
 		return nil, fmt.Errorf("incorrect reportInterval")
 	}
+	if cfg.Transport != "http" && cfg.Transport != "grpc" {
+		fmt.Fprintf(os.Stderr, "Error: transport must be http or grpc, got %s\n", cfg.Transport)
+		return nil, fmt.Errorf("incorrect transport")
+	}
+	// gRPC-сообщения не шифруются ключом RSA: конфиденциальность там обеспечивает только TLS
+	if cfg.Transport == "grpc" && cfg.CryptoKey != "" {
+		fmt.Fprintf(os.Stderr, "Error: crypto key is not supported with grpc transport, use tls instead\n")
+		return nil, fmt.Errorf("incorrect cryptoKey")
+	}
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		fmt.Fprintf(os.Stderr, "Error: tls certificate and key must be set together\n")
+		return nil, fmt.Errorf("incorrect tls certificate")
+	}
+	if cfg.Compress != "" && !compression.Supported(cfg.Compress) {
+		fmt.Fprintf(os.Stderr, "Error: compress must be gzip, deflate, zstd or br, got %s\n", cfg.Compress)
+		return nil, fmt.Errorf("incorrect compress")
+	}
 
 	// Сохраняем настройки
 	cfg.PollInterval = time.Duration(pollInterval) * time.Second
@@ -143,6 +544,15 @@ func GetAgentConfig() (*AgentConfig, error) {
 	fmt.Println("Server Address:", cfg.Address)
 	fmt.Println("Poll Level:", cfg.PollInterval)
 	fmt.Println("Report Interval:", cfg.ReportInterval)
+	fmt.Println("Transport:", cfg.Transport)
+	fmt.Println("gRPC Address:", cfg.GRPCAddress)
+	fmt.Println("Signing Enabled:", cfg.Key != "")
+	fmt.Println("TLS CA:", cfg.TLSCAFile)
+	fmt.Println("TLS Certificate:", cfg.TLSCertFile)
+	fmt.Println("Crypto Key:", cfg.CryptoKey)
+	fmt.Println("Token Set:", cfg.Token != "")
+	fmt.Println("Agent ID:", cfg.ID)
+	fmt.Println("Compress:", cfg.Compress)
 
 	return cfg, nil
 }
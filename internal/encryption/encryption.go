@@ -0,0 +1,158 @@
+// Package encryption шифрует тела запросов агента открытым ключом RSA сервера.
+// Тело шифруется AES-256-GCM случайным ключом, а сам ключ — RSA-OAEP (SHA-256),
+// поэтому размер тела не ограничен размером ключа RSA
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Header помечает зашифрованное тело запроса, значение — Scheme
+const (
+	Header = "X-Encryption"
+	Scheme = "rsa-oaep-aes-gcm"
+)
+
+// ErrDecrypt возвращается для повреждённых или зашифрованных другим ключом данных
+var ErrDecrypt = errors.New("failed to decrypt payload")
+
+const aesKeySize = 32
+
+// Encrypt шифрует данные: зашифрованный ключ AES || nonce || шифротекст GCM
+func Encrypt(pub *rsa.PublicKey, plaintext []byte) ([]byte, error) {
+	key := make([]byte, aesKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	encryptedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, key, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt key: %w", err)
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	out := make([]byte, 0, len(encryptedKey)+len(nonce)+len(plaintext)+gcm.Overhead())
+	out = append(out, encryptedKey...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, plaintext, nil), nil
+}
+
+// Decrypt расшифровывает данные, сформированные Encrypt
+func Decrypt(priv *rsa.PrivateKey, data []byte) ([]byte, error) {
+	keySize := priv.Size()
+	if len(data) < keySize {
+		return nil, ErrDecrypt
+	}
+	key, err := rsa.DecryptOAEP(sha256.New(), nil, priv, data[:keySize], nil)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	rest := data[keySize:]
+	if len(rest) < gcm.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	plaintext, err := gcm.Open(nil, rest[:gcm.NonceSize()], rest[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// GenerateKeyPair возвращает закрытый (PKCS#8) и открытый (PKIX) ключи в формате PEM
+func GenerateKeyPair(bits int) (privatePEM, publicPEM []byte, err error) {
+	priv, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal private key: %w", err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal public key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), nil
+}
+
+// LoadPublicKey читает открытый ключ RSA из PEM (PKIX или PKCS#1)
+func LoadPublicKey(path string) (*rsa.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+	pub, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key in %s is not RSA", path)
+	}
+	return pub, nil
+}
+
+// LoadPrivateKey читает закрытый ключ RSA из PEM (PKCS#8 или PKCS#1)
+func LoadPrivateKey(path string) (*rsa.PrivateKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	priv, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key in %s is not RSA", path)
+	}
+	return priv, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", path)
+	}
+	return block, nil
+}
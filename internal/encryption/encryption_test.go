@@ -0,0 +1,78 @@
+package encryption_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/encryption"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	dir := t.TempDir()
+	privatePEM, publicPEM, err := encryption.GenerateKeyPair(2048)
+	if err != nil {
+		t.Fatalf("GenerateKeyPair() failed: %v", err)
+	}
+	os.WriteFile(filepath.Join(dir, "private.pem"), privatePEM, 0o600)
+	os.WriteFile(filepath.Join(dir, "public.pem"), publicPEM, 0o644)
+
+	priv, err := encryption.LoadPrivateKey(filepath.Join(dir, "private.pem"))
+	if err != nil {
+		t.Fatalf("LoadPrivateKey() failed: %v", err)
+	}
+	pub, err := encryption.LoadPublicKey(filepath.Join(dir, "public.pem"))
+	if err != nil {
+		t.Fatalf("LoadPublicKey() failed: %v", err)
+	}
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() failed: %v", err)
+	}
+
+	// Тело больше, чем помещается в один блок RSA-OAEP
+	large := bytes.Repeat([]byte(`{"id":"Alloc","type":"gauge","value":1.5},`), 1000)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		priv    *rsa.PrivateKey
+		tamper  bool
+		wantErr bool
+	}{
+		{"Короткое тело", []byte(`[]`), priv, false, false},
+		{"Большое тело", large, priv, false, false},
+		{"Чужой ключ", large, other, false, true},
+		{"Изменённый шифротекст", large, priv, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encrypted, err := encryption.Encrypt(pub, tt.data)
+			if err != nil {
+				t.Fatalf("Encrypt() failed: %v", err)
+			}
+			if tt.tamper {
+				encrypted[len(encrypted)-1] ^= 0xff
+			}
+
+			got, err := encryption.Decrypt(tt.priv, encrypted)
+			if tt.wantErr {
+				if !errors.Is(err, encryption.ErrDecrypt) {
+					t.Fatalf("Decrypt() error = %v, want ErrDecrypt", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decrypt() failed: %v", err)
+			}
+			if !bytes.Equal(got, tt.data) {
+				t.Error("Decrypt() returned different data")
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package graphite
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidLine = errors.New("invalid graphite line")
+
+type Point struct {
+	Path      string
+	Value     float64
+	Timestamp time.Time
+}
+
+// ParseLine разбирает строку вида "path value timestamp"; timestamp может отсутствовать или быть равен -1
+func ParseLine(line string) (Point, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 || len(fields) > 3 {
+		return Point{}, fmt.Errorf("%w: %q", ErrInvalidLine, line)
+	}
+
+	value, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return Point{}, fmt.Errorf("%w: bad value %q", ErrInvalidLine, fields[1])
+	}
+
+	point := Point{Path: fields[0], Value: value, Timestamp: time.Now()}
+	if len(fields) == 3 && fields[2] != "-1" {
+		ts, err := strconv.ParseFloat(fields[2], 64)
+		if err != nil {
+			return Point{}, fmt.Errorf("%w: bad timestamp %q", ErrInvalidLine, fields[2])
+		}
+		sec, frac := math.Modf(ts)
+		point.Timestamp = time.Unix(int64(sec), int64(frac*1e9))
+	}
+
+	return point, nil
+}
@@ -0,0 +1,43 @@
+package graphite_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/graphite"
+)
+
+func TestParseLine(t *testing.T) {
+	point, err := graphite.ParseLine("collectd.host1.load.shortterm 0.42 1700000000")
+	if err != nil {
+		t.Fatalf("ParseLine() failed: %v", err)
+	}
+	if point.Path != "collectd.host1.load.shortterm" {
+		t.Errorf("Expected path 'collectd.host1.load.shortterm', got '%s'", point.Path)
+	}
+	if point.Value != 0.42 {
+		t.Errorf("Expected value 0.42, got %g", point.Value)
+	}
+	if !point.Timestamp.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("Unexpected timestamp %v", point.Timestamp)
+	}
+
+	// Метка времени необязательна
+	if _, err := graphite.ParseLine("app.requests 10"); err != nil {
+		t.Errorf("ParseLine() without timestamp failed: %v", err)
+	}
+	if _, err := graphite.ParseLine("app.requests 10 -1"); err != nil {
+		t.Errorf("ParseLine() with timestamp -1 failed: %v", err)
+	}
+}
+
+func TestParseLineInvalid(t *testing.T) {
+	lines := []string{"", "app.requests", "app.requests abc 1700000000", "app.requests nan", "app.requests 1 now", "a b c d"}
+
+	for _, line := range lines {
+		if _, err := graphite.ParseLine(line); !errors.Is(err, graphite.ErrInvalidLine) {
+			t.Errorf("ParseLine(%q): expected ErrInvalidLine, got %v", line, err)
+		}
+	}
+}
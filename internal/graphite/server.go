@@ -0,0 +1,169 @@
+package graphite
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/config"
+	"github.com/akorablin/yandex-practicum-metrics/internal/storage"
+)
+
+type Server struct {
+	cfg      *config.ServerConfig
+	storage  storage.Storage
+	counters []string
+
+	mu     sync.Mutex
+	conns  map[net.Conn]struct{}
+	closed bool // соединения, принятые после остановки, сразу закрываются
+}
+
+func New(cfg *config.ServerConfig, repo storage.Storage) *Server {
+	var counters []string
+	for _, pattern := range strings.Split(cfg.GraphiteCounters, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			counters = append(counters, pattern)
+		}
+	}
+
+	return &Server{
+		cfg:      cfg,
+		storage:  repo,
+		counters: counters,
+		conns:    make(map[net.Conn]struct{}),
+	}
+}
+
+// Run принимает TCP-соединения до отмены контекста.
+// Число одновременно обслуживаемых соединений ограничено GraphiteMaxConnections
+func (s *Server) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.cfg.GraphiteAddress)
+	if err != nil {
+		return fmt.Errorf("failed to listen graphite: %w", err)
+	}
+
+	// При остановке закрываем слушатель и все активные соединения
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+		s.mu.Lock()
+		s.closed = true
+		for conn := range s.conns {
+			conn.Close()
+		}
+		s.mu.Unlock()
+	}()
+
+	log.Printf("Graphite listener started on %s", listener.Addr())
+	slots := make(chan struct{}, s.cfg.GraphiteMaxConnections)
+	var wg sync.WaitGroup
+	for {
+		// Ждём свободный слот, прежде чем принимать новое соединение
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		conn, err := listener.Accept()
+		if err != nil {
+			<-slots
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				break
+			}
+			log.Printf("Graphite accept error: %v", err)
+			continue
+		}
+
+		if !s.track(conn, true) {
+			<-slots
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			defer s.track(conn, false)
+			s.handle(conn)
+		}()
+	}
+
+	wg.Wait()
+	log.Println("Graphite listener stopped")
+	return nil
+}
+
+// track добавляет или убирает соединение; после остановки новое соединение закрывается и не добавляется
+func (s *Server) track(conn net.Conn, add bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if add && !s.closed {
+		s.conns[conn] = struct{}{}
+		return true
+	}
+	delete(s.conns, conn)
+	conn.Close()
+	return false
+}
+
+// handle читает строки до закрытия соединения; соединение без данных дольше
+// GraphiteIdleTimeout закрывается, чтобы не занимать слот
+func (s *Server) handle(conn net.Conn) {
+	idle := time.Duration(s.cfg.GraphiteIdleTimeout) * time.Second
+	scanner := bufio.NewScanner(conn)
+	for {
+		if idle > 0 {
+			conn.SetReadDeadline(time.Now().Add(idle))
+		}
+		if !scanner.Scan() {
+			break
+		}
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		point, err := ParseLine(line)
+		if err != nil {
+			log.Printf("Graphite parse error from %s: %v", conn.RemoteAddr(), err)
+			continue
+		}
+		if err := s.store(point); err != nil {
+			log.Printf("Failed to store graphite metric %s: %v", point.Path, err)
+		}
+	}
+	if err := scanner.Err(); errors.Is(err, os.ErrDeadlineExceeded) {
+		log.Printf("Graphite connection from %s closed after %v idle", conn.RemoteAddr(), idle)
+	} else if err != nil && !errors.Is(err, net.ErrClosed) {
+		log.Printf("Graphite read error from %s: %v", conn.RemoteAddr(), err)
+	}
+}
+
+func (s *Server) store(point Point) error {
+	if s.isCounter(point.Path) {
+		return s.storage.UpdateCounter(point.Path, int64(math.Round(point.Value)))
+	}
+	return s.storage.UpdateGauge(point.Path, point.Value)
+}
+
+func (s *Server) isCounter(metricPath string) bool {
+	for _, pattern := range s.counters {
+		if ok, _ := path.Match(pattern, metricPath); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package graphite_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/config"
+	"github.com/akorablin/yandex-practicum-metrics/internal/graphite"
+	"github.com/akorablin/yandex-practicum-metrics/internal/repository/memory"
+)
+
+func freeAddress(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+func TestServerIdleAndShutdown(t *testing.T) {
+	cfg := &config.ServerConfig{GraphiteAddress: freeAddress(t), GraphiteMaxConnections: 2, GraphiteIdleTimeout: 1}
+	repo := memory.New(cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- graphite.New(cfg, repo).Run(ctx) }()
+
+	dial := func() net.Conn {
+		for range 50 {
+			if conn, err := net.Dial("tcp", cfg.GraphiteAddress); err == nil {
+				return conn
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		t.Fatal("Graphite listener did not start")
+		return nil
+	}
+
+	// Простаивающее соединение закрывается сервером
+	idle := dial()
+	defer idle.Close()
+	idle.SetReadDeadline(time.Now().Add(3 * time.Second))
+	if _, err := idle.Read(make([]byte, 1)); err == nil || isTimeout(err) {
+		t.Errorf("Expected idle connection to be closed by server, got %v", err)
+	}
+
+	// Остановка не ждёт подключённых клиентов
+	active := dial()
+	defer active.Close()
+	active.Write([]byte("servers.web1.cpu 1 1700000000\n"))
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run() failed: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run() did not stop with a connected client")
+	}
+	if value, err := repo.GetGauge("servers.web1.cpu"); err != nil || value != 1 {
+		t.Errorf("Expected stored gauge, got %v (%v)", value, err)
+	}
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,88 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/auth"
+	pb "github.com/akorablin/yandex-practicum-metrics/pkg/api/metricspb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Права, нужные методам MetricsService
+var methodScopes = map[string]string{
+	pb.MetricsService_Update_FullMethodName:      auth.ScopeWrite,
+	pb.MetricsService_UpdateBatch_FullMethodName: auth.ScopeWrite,
+	pb.MetricsService_Get_FullMethodName:         auth.ScopeRead,
+	pb.MetricsService_List_FullMethodName:        auth.ScopeRead,
+}
+
+// Option настраивает дополнительные параметры Server
+type Option func(*Server)
+
+// WithAuth требует bearer-токен в метаданных authorization
+func WithAuth(store *auth.Store) Option {
+	return func(s *Server) {
+		s.auth = store
+	}
+}
+
+// UnaryAuthInterceptor проверяет токен унарных вызовов и сохраняет его в контексте
+func UnaryAuthInterceptor(store *auth.Store) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		token, err := authorize(ctx, store, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(auth.NewContext(ctx, token), req)
+	}
+}
+
+// StreamAuthInterceptor проверяет токен потоковых вызовов и сохраняет его в контексте
+func StreamAuthInterceptor(store *auth.Store) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		token, err := authorize(ss.Context(), store, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: auth.NewContext(ss.Context(), token)})
+	}
+}
+
+// contextStream подменяет контекст потока
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+func authorize(ctx context.Context, store *auth.Store, method string) (auth.Token, error) {
+	scope, ok := methodScopes[method]
+	if !ok {
+		// Неизвестные методы доступны только администратору
+		scope = auth.ScopeAdmin
+	}
+
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			token, _ = strings.CutPrefix(values[0], "Bearer ")
+		}
+	}
+
+	t, err := store.Authorize(token, scope)
+	switch {
+	case errors.Is(err, auth.ErrUnauthorized):
+		return auth.Token{}, status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, auth.ErrForbidden):
+		return auth.Token{}, status.Errorf(codes.PermissionDenied, "%s scope required", scope)
+	}
+	return t, nil
+}
@@ -0,0 +1,143 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"time"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/auth"
+	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
+	"github.com/akorablin/yandex-practicum-metrics/internal/ratelimit"
+	"github.com/akorablin/yandex-practicum-metrics/internal/selfmetrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// WithRateLimit ограничивает частоту вызовов записи для каждого клиента, как в HTTP
+func WithRateLimit(limiter *ratelimit.Limiter) Option {
+	return func(s *Server) {
+		s.limiter = limiter
+	}
+}
+
+// WithQuota включает квоты клиента на размер пакета и число рядов
+func WithQuota(quota *ratelimit.Quota) Option {
+	return func(s *Server) {
+		s.quota = quota
+	}
+}
+
+// WithSelfMetrics учитывает отклонённые вызовы в собственных метриках сервера
+func WithSelfMetrics(recorder *selfmetrics.Recorder) Option {
+	return func(s *Server) {
+		s.recorder = recorder
+	}
+}
+
+// UnaryRateLimitInterceptor ограничивает частоту унарных вызовов записи
+func UnaryRateLimitInterceptor(limiter *ratelimit.Limiter, identify func(context.Context) string, onThrottle func(reason string)) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := allow(ctx, limiter, identify, onThrottle, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamRateLimitInterceptor ограничивает частоту потоковых вызовов записи; поток считается одним запросом
+func StreamRateLimitInterceptor(limiter *ratelimit.Limiter, identify func(context.Context) string, onThrottle func(reason string)) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := allow(ss.Context(), limiter, identify, onThrottle, info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+func allow(ctx context.Context, limiter *ratelimit.Limiter, identify func(context.Context) string, onThrottle func(reason string), method string) error {
+	if methodScopes[method] != auth.ScopeWrite {
+		return nil
+	}
+	if ok, wait := limiter.Allow(identify(ctx)); !ok {
+		onThrottle(ratelimit.ReasonRate)
+		return status.Errorf(codes.ResourceExhausted, "too many requests, retry after %ds", retrySeconds(wait))
+	}
+	return nil
+}
+
+// clientID определяет клиента для ограничений так же, как HTTP-обработчики
+func (s *Server) clientID(ctx context.Context) string {
+	var token string
+	if t, ok := auth.FromContext(ctx); ok {
+		token = t.Name
+	}
+	var agentID string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		agentID = first(md.Get(ratelimit.AgentIDHeader))
+	}
+	return ratelimit.ClientKey(s.cfg.RateLimitBy, s.clientIP(ctx), token, agentID)
+}
+
+// clientIP — адрес клиента с учётом доверенных прокси, если задана доверенная подсеть
+func (s *Server) clientIP(ctx context.Context) string {
+	var remoteAddr string
+	if p, ok := peer.FromContext(ctx); ok {
+		remoteAddr = p.Addr.String()
+	}
+	if s.trusted != nil {
+		var realIP, forwardedFor string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			realIP = first(md.Get("x-real-ip"))
+			forwardedFor = first(md.Get("x-forwarded-for"))
+		}
+		return s.trusted.ClientIP(remoteAddr, realIP, forwardedFor).String()
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
+
+// admit проверяет квоты клиента перед сохранением метрик
+func (s *Server) admit(ctx context.Context, metrics []models.Metrics) error {
+	if s.quota == nil {
+		return nil
+	}
+
+	err := s.quota.Admit(s.clientID(ctx), metrics)
+	var quotaErr *ratelimit.QuotaError
+	if !errors.As(err, &quotaErr) {
+		return nil
+	}
+
+	s.throttled(quotaErr.Reason)
+	msg := quotaErr.Error()
+	if quotaErr.RetryAfter > 0 {
+		msg = fmt.Sprintf("%s, retry after %ds", msg, retrySeconds(quotaErr.RetryAfter))
+	}
+	return status.Error(codes.ResourceExhausted, msg)
+}
+
+// recordSeries учитывает ряды успешно сохранённых метрик в квоте клиента
+func (s *Server) recordSeries(ctx context.Context, metrics []models.Metrics) {
+	if s.quota != nil {
+		s.quota.Record(s.clientID(ctx), metrics)
+	}
+}
+
+// throttled учитывает отклонённый вызов в счётчике ThrottledRequests;reason=...
+func (s *Server) throttled(reason string) {
+	s.recorder.Add(ratelimit.ThrottledMetric, map[string]string{"reason": reason}, 1)
+}
+
+// retrySeconds округляет ожидание вверх до целых секунд, как Retry-After в HTTP
+func retrySeconds(wait time.Duration) int {
+	return max(int(math.Ceil(wait.Seconds())), 1)
+}
@@ -0,0 +1,281 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sort"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/auth"
+	"github.com/akorablin/yandex-practicum-metrics/internal/certs"
+	"github.com/akorablin/yandex-practicum-metrics/internal/config"
+	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
+	"github.com/akorablin/yandex-practicum-metrics/internal/ratelimit"
+	"github.com/akorablin/yandex-practicum-metrics/internal/selfmetrics"
+	"github.com/akorablin/yandex-practicum-metrics/internal/storage"
+	"github.com/akorablin/yandex-practicum-metrics/internal/trusted"
+	pb "github.com/akorablin/yandex-practicum-metrics/pkg/api/metricspb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+// Server реализует gRPC-сервис MetricsService поверх того же хранилища, что и HTTP-обработчики
+type Server struct {
+	pb.UnimplementedMetricsServiceServer
+
+	cfg     *config.ServerConfig
+	storage storage.Storage
+	auth    *auth.Store
+	trusted *trusted.Checker
+
+	limiter  *ratelimit.Limiter
+	quota    *ratelimit.Quota
+	recorder *selfmetrics.Recorder
+}
+
+func New(cfg *config.ServerConfig, repo storage.Storage, opts ...Option) *Server {
+	s := &Server{
+		cfg:     cfg,
+		storage: repo,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Run обслуживает gRPC-запросы до отмены контекста
+func (s *Server) Run(ctx context.Context) error {
+	// TLS и mTLS настраиваются теми же сертификатами, что и HTTP-сервер
+	var opts []grpc.ServerOption
+	if s.cfg.TLSCertFile != "" {
+		tlsConfig, err := certs.NewServerTLS(s.cfg.TLSCertFile, s.cfg.TLSKeyFile, s.cfg.TLSClientCAFile)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+		// Сертификат при рукопожатии необязателен, поэтому запись требует его отдельно
+		if s.cfg.TLSClientCAFile != "" {
+			opts = append(opts,
+				grpc.ChainUnaryInterceptor(UnaryClientCertInterceptor()),
+				grpc.ChainStreamInterceptor(StreamClientCertInterceptor()),
+			)
+		}
+	}
+	// Размер одного сообщения ограничен так же, как тело HTTP-запроса
+	if s.cfg.MaxBodySize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(s.cfg.MaxBodySize))
+	}
+	if s.trusted != nil {
+		opts = append(opts,
+			grpc.ChainUnaryInterceptor(UnaryTrustedInterceptor(s.trusted)),
+			grpc.ChainStreamInterceptor(StreamTrustedInterceptor(s.trusted)),
+		)
+	}
+	if s.auth != nil && s.auth.Enabled() {
+		opts = append(opts,
+			grpc.ChainUnaryInterceptor(UnaryAuthInterceptor(s.auth)),
+			grpc.ChainStreamInterceptor(StreamAuthInterceptor(s.auth)),
+		)
+	}
+	// Частота проверяется после токена, чтобы клиента можно было определить по нему
+	if s.limiter != nil {
+		opts = append(opts,
+			grpc.ChainUnaryInterceptor(UnaryRateLimitInterceptor(s.limiter, s.clientID, s.throttled)),
+			grpc.ChainStreamInterceptor(StreamRateLimitInterceptor(s.limiter, s.clientID, s.throttled)),
+		)
+	}
+	// С общим ключом записи без подписи HMAC отклоняются, как в HTTP
+	if s.cfg.Key != "" {
+		opts = append(opts,
+			grpc.ChainUnaryInterceptor(UnarySignInterceptor(s.cfg.Key)),
+			grpc.ChainStreamInterceptor(StreamSignInterceptor(s.cfg.Key)),
+		)
+	}
+
+	listener, err := net.Listen("tcp", s.cfg.GRPCAddress)
+	if err != nil {
+		return fmt.Errorf("failed to listen grpc: %w", err)
+	}
+
+	server := grpc.NewServer(opts...)
+	pb.RegisterMetricsServiceServer(server, s)
+
+	go func() {
+		<-ctx.Done()
+		server.GracefulStop()
+	}()
+
+	log.Printf("gRPC server started on %s", listener.Addr())
+	if err := server.Serve(listener); err != nil {
+		return fmt.Errorf("grpc server failed: %w", err)
+	}
+	log.Println("gRPC server stopped")
+	return nil
+}
+
+func (s *Server) Update(ctx context.Context, req *pb.UpdateRequest) (*pb.UpdateResponse, error) {
+	if err := s.update(ctx, req.GetMetric()); err != nil {
+		return nil, err
+	}
+
+	metric, err := s.get(req.GetMetric().GetId(), req.GetMetric().GetType())
+	if err != nil {
+		return nil, err
+	}
+	return &pb.UpdateResponse{Metric: metric}, nil
+}
+
+// UpdateBatch накапливает весь поток и сохраняет его одним атомарным пакетом при EOF:
+// если поток оборвётся на середине, ничего не сохранится и агент сможет безопасно повторить пакет
+func (s *Server) UpdateBatch(stream grpc.ClientStreamingServer[pb.UpdateBatchRequest, pb.UpdateBatchResponse]) error {
+	var batch []models.Metrics
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, metric := range req.GetMetrics() {
+			m, err := s.toModel(metric)
+			if err != nil {
+				return err
+			}
+			batch = append(batch, m)
+		}
+		if s.cfg.MaxBatchSize > 0 && len(batch) > s.cfg.MaxBatchSize {
+			return status.Errorf(codes.ResourceExhausted, "batch exceeds %d metrics", s.cfg.MaxBatchSize)
+		}
+	}
+
+	if len(batch) > 0 {
+		if err := s.admit(stream.Context(), batch); err != nil {
+			return err
+		}
+		if err := s.storeError(s.storage.UpdateMetricsBatch(stream.Context(), batch), "batch"); err != nil {
+			return err
+		}
+		s.recordSeries(stream.Context(), batch)
+	}
+	return stream.SendAndClose(&pb.UpdateBatchResponse{Received: int64(len(batch))})
+}
+
+func (s *Server) Get(ctx context.Context, req *pb.GetRequest) (*pb.GetResponse, error) {
+	metric, err := s.get(req.GetId(), req.GetType())
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetResponse{Metric: metric}, nil
+}
+
+func (s *Server) List(ctx context.Context, req *pb.ListRequest) (*pb.ListResponse, error) {
+	gauges, counters := s.storage.GetAllMetrics()
+
+	metrics := make([]*pb.Metric, 0, len(gauges)+len(counters))
+	for id, value := range gauges {
+		metrics = append(metrics, &pb.Metric{Id: id, Type: pb.MType_GAUGE, Value: value})
+	}
+	for id, delta := range counters {
+		metrics = append(metrics, &pb.Metric{Id: id, Type: pb.MType_COUNTER, Delta: delta})
+	}
+	sort.Slice(metrics, func(i, j int) bool {
+		if metrics[i].Id != metrics[j].Id {
+			return metrics[i].Id < metrics[j].Id
+		}
+		return metrics[i].Type < metrics[j].Type
+	})
+
+	return &pb.ListResponse{Metrics: metrics}, nil
+}
+
+func (s *Server) update(ctx context.Context, metric *pb.Metric) error {
+	m, err := s.toModel(metric)
+	if err != nil {
+		return err
+	}
+	if err := s.admit(ctx, []models.Metrics{m}); err != nil {
+		return err
+	}
+
+	if m.MType == models.Gauge {
+		err = s.storage.UpdateGauge(m.ID, *m.Value)
+	} else {
+		err = s.storage.UpdateCounter(m.ID, *m.Delta)
+	}
+	if err := s.storeError(err, m.ID); err != nil {
+		return err
+	}
+	s.recordSeries(ctx, []models.Metrics{m})
+	return nil
+}
+
+// toModel проверяет метрику из запроса и переводит её в модель хранилища
+func (s *Server) toModel(metric *pb.Metric) (models.Metrics, error) {
+	if metric.GetId() == "" {
+		return models.Metrics{}, status.Error(codes.InvalidArgument, "metric id is required")
+	}
+	if s.cfg.MaxIDLength > 0 && len(metric.GetId()) > s.cfg.MaxIDLength {
+		return models.Metrics{}, status.Errorf(codes.ResourceExhausted, "metric id exceeds %d bytes", s.cfg.MaxIDLength)
+	}
+
+	m := models.Metrics{ID: metric.GetId()}
+	switch metric.GetType() {
+	case pb.MType_GAUGE:
+		value := metric.GetValue()
+		m.MType, m.Value = models.Gauge, &value
+	case pb.MType_COUNTER:
+		delta := metric.GetDelta()
+		m.MType, m.Delta = models.Counter, &delta
+	default:
+		return models.Metrics{}, status.Errorf(codes.InvalidArgument, "unknown metric type: %s", metric.GetType())
+	}
+	return m, nil
+}
+
+// storeError переводит ошибку хранилища в статус gRPC
+func (s *Server) storeError(err error, id string) error {
+	if errors.Is(err, storage.ErrTypeMismatch) {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if err != nil {
+		log.Printf("Failed to store metric %s: %v", id, err)
+		return status.Error(codes.Internal, "failed to store metric")
+	}
+	return nil
+}
+
+func (s *Server) get(id string, mType pb.MType) (*pb.Metric, error) {
+	metric := &pb.Metric{Id: id, Type: mType}
+
+	var err error
+	switch mType {
+	case pb.MType_GAUGE:
+		metric.Value, err = s.storage.GetGauge(id)
+	case pb.MType_COUNTER:
+		metric.Delta, err = s.storage.GetCounter(id)
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unknown metric type: %s", mType)
+	}
+	if errors.Is(err, storage.ErrMetricNotFound) {
+		return nil, status.Errorf(codes.NotFound, "%s metric %s not found", typeName(mType), id)
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to get metric")
+	}
+	return metric, nil
+}
+
+func typeName(mType pb.MType) string {
+	if mType == pb.MType_COUNTER {
+		return models.Counter
+	}
+	return models.Gauge
+}
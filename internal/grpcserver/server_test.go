@@ -0,0 +1,306 @@
+package grpcserver_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/agent"
+	"github.com/akorablin/yandex-practicum-metrics/internal/auth"
+	"github.com/akorablin/yandex-practicum-metrics/internal/config"
+	"github.com/akorablin/yandex-practicum-metrics/internal/grpcserver"
+	"github.com/akorablin/yandex-practicum-metrics/internal/ratelimit"
+	"github.com/akorablin/yandex-practicum-metrics/internal/repository/memory"
+	"github.com/akorablin/yandex-practicum-metrics/internal/selfmetrics"
+	pb "github.com/akorablin/yandex-practicum-metrics/pkg/api/metricspb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newClientConn(t *testing.T) *grpc.ClientConn {
+	t.Helper()
+	return newConn(t, nil)
+}
+
+func newConn(t *testing.T, serverOpts []grpc.ServerOption, dialOpts ...grpc.DialOption) *grpc.ClientConn {
+	t.Helper()
+
+	cfg := &config.ServerConfig{}
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer(serverOpts...)
+	pb.RegisterMetricsServiceServer(server, grpcserver.New(cfg, memory.New(cfg)))
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	dialOpts = append(dialOpts,
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	conn, err := grpc.NewClient("passthrough:///bufnet", dialOpts...)
+	if err != nil {
+		t.Fatalf("grpc.NewClient() failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestUpdateAndGet(t *testing.T) {
+	client := pb.NewMetricsServiceClient(newClientConn(t))
+	ctx := context.Background()
+
+	for range 2 {
+		_, err := client.Update(ctx, &pb.UpdateRequest{Metric: &pb.Metric{Id: "PollCount", Type: pb.MType_COUNTER, Delta: 3}})
+		if err != nil {
+			t.Fatalf("Update() failed: %v", err)
+		}
+	}
+
+	resp, err := client.Get(ctx, &pb.GetRequest{Id: "PollCount", Type: pb.MType_COUNTER})
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if resp.GetMetric().GetDelta() != 6 {
+		t.Errorf("Expected PollCount = 6, got %d", resp.GetMetric().GetDelta())
+	}
+
+	// Несуществующая метрика
+	_, err = client.Get(ctx, &pb.GetRequest{Id: "Unknown", Type: pb.MType_GAUGE})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound, got %v", err)
+	}
+}
+
+func TestUpdateBatchAppliedAtEOF(t *testing.T) {
+	client := pb.NewMetricsServiceClient(newClientConn(t))
+	counter := &pb.Metric{Id: "PollCount", Type: pb.MType_COUNTER, Delta: 1}
+
+	// Поток прерывается ошибкой во второй части: первая не должна сохраниться
+	stream, err := client.UpdateBatch(context.Background())
+	if err != nil {
+		t.Fatalf("UpdateBatch() failed: %v", err)
+	}
+	stream.Send(&pb.UpdateBatchRequest{Metrics: []*pb.Metric{counter}})
+	stream.Send(&pb.UpdateBatchRequest{Metrics: []*pb.Metric{{Type: pb.MType_GAUGE}}})
+	if _, err := stream.CloseAndRecv(); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Expected InvalidArgument, got %v", err)
+	}
+	if _, err := client.Get(context.Background(), &pb.GetRequest{Id: "PollCount", Type: pb.MType_COUNTER}); status.Code(err) != codes.NotFound {
+		t.Fatalf("Expected nothing stored after broken stream, got %v", err)
+	}
+
+	// Повтор целиком сохраняет пакет один раз
+	stream, err = client.UpdateBatch(context.Background())
+	if err != nil {
+		t.Fatalf("UpdateBatch() failed: %v", err)
+	}
+	for range 2 {
+		if err := stream.Send(&pb.UpdateBatchRequest{Metrics: []*pb.Metric{counter}}); err != nil {
+			t.Fatalf("Send() failed: %v", err)
+		}
+	}
+	if resp, err := stream.CloseAndRecv(); err != nil || resp.GetReceived() != 2 {
+		t.Fatalf("CloseAndRecv() = %v, %v", resp, err)
+	}
+	resp, err := client.Get(context.Background(), &pb.GetRequest{Id: "PollCount", Type: pb.MType_COUNTER})
+	if err != nil || resp.GetMetric().GetDelta() != 2 {
+		t.Errorf("Expected PollCount = 2, got %v (%v)", resp, err)
+	}
+}
+
+func TestSenderGRPCTransport(t *testing.T) {
+	conn := newClientConn(t)
+	sender := agent.NewSender("", agent.WithGRPC(conn))
+
+	gauges := map[string]float64{"Alloc": 1.5, "HeapAlloc": 2.5}
+	counters := map[string]int64{"PollCount": 4}
+	if err := sender.SendAll(context.Background(), gauges, counters); err != nil {
+		t.Fatalf("SendAll() failed: %v", err)
+	}
+
+	resp, err := pb.NewMetricsServiceClient(conn).List(context.Background(), &pb.ListRequest{})
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if len(resp.GetMetrics()) != 3 {
+		t.Fatalf("Expected 3 metrics, got %d", len(resp.GetMetrics()))
+	}
+	if m := resp.GetMetrics()[0]; m.GetId() != "Alloc" || m.GetValue() != 1.5 {
+		t.Errorf("Unexpected first metric: %v", m)
+	}
+}
+
+func TestAuthInterceptors(t *testing.T) {
+	tokens, err := auth.New("agent:"+auth.HashToken("agent-token")+":write", "")
+	if err != nil {
+		t.Fatalf("auth.New() failed: %v", err)
+	}
+	serverOpts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(grpcserver.UnaryAuthInterceptor(tokens)),
+		grpc.ChainStreamInterceptor(grpcserver.StreamAuthInterceptor(tokens)),
+	}
+	ctx := context.Background()
+	metric := &pb.Metric{Id: "PollCount", Type: pb.MType_COUNTER, Delta: 1}
+
+	// Без токена
+	client := pb.NewMetricsServiceClient(newConn(t, serverOpts))
+	if _, err := client.Update(ctx, &pb.UpdateRequest{Metric: metric}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated, got %v", err)
+	}
+
+	// Токен агента позволяет писать, в том числе потоком, но не читать
+	conn := newConn(t, serverOpts, grpc.WithPerRPCCredentials(agent.TokenCredentials("agent-token")))
+	if err := agent.NewSender("", agent.WithGRPC(conn)).SendAll(ctx, nil, map[string]int64{"PollCount": 1}); err != nil {
+		t.Errorf("SendAll() failed: %v", err)
+	}
+	_, err = pb.NewMetricsServiceClient(conn).List(ctx, &pb.ListRequest{})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied, got %v", err)
+	}
+}
+
+func TestSignInterceptors(t *testing.T) {
+	serverOpts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(grpcserver.UnarySignInterceptor("secret")),
+		grpc.ChainStreamInterceptor(grpcserver.StreamSignInterceptor("secret")),
+	}
+	conn := newConn(t, serverOpts)
+	client := pb.NewMetricsServiceClient(conn)
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		sender *agent.Sender
+		code   codes.Code
+	}{
+		{"Без подписи", agent.NewSender("", agent.WithGRPC(conn)), codes.Unauthenticated},
+		{"Чужой ключ", agent.NewSender("", agent.WithGRPC(conn), agent.WithKey("other")), codes.Unauthenticated},
+		{"Верная подпись", agent.NewSender("", agent.WithGRPC(conn), agent.WithKey("secret")), codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.sender.SendAll(ctx, nil, map[string]int64{"PollCount": 1})
+			if status.Code(errors.Unwrap(err)) != tt.code && status.Code(err) != tt.code {
+				t.Errorf("Expected %v, got %v", tt.code, err)
+			}
+		})
+	}
+
+	// Подпись проверяется до сохранения: неподписанные пакеты не записались
+	resp, err := client.Get(ctx, &pb.GetRequest{Id: "PollCount", Type: pb.MType_COUNTER})
+	if err != nil || resp.GetMetric().GetDelta() != 1 {
+		t.Errorf("Expected PollCount = 1, got %v (%v)", resp, err)
+	}
+
+	// Унарный вызов без подписи
+	if _, err := client.Update(ctx, &pb.UpdateRequest{Metric: &pb.Metric{Id: "Alloc", Type: pb.MType_GAUGE, Value: 1}}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated, got %v", err)
+	}
+}
+
+func TestRateLimitAndQuota(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	cfg := &config.ServerConfig{GRPCAddress: address, RateLimitBy: config.RateLimitByAgent}
+	repo := memory.New(cfg)
+	recorder := selfmetrics.New(cfg)
+	server := grpcserver.New(cfg, repo,
+		grpcserver.WithRateLimit(ratelimit.NewLimiter(0.001, 1)),
+		grpcserver.WithQuota(ratelimit.NewQuota(2, 0)),
+		grpcserver.WithSelfMetrics(recorder),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- server.Run(ctx) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	dial := func(agentID string) *agent.Sender {
+		conn, err := grpc.NewClient(address,
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithPerRPCCredentials(agent.AgentIDCredentials(agentID)),
+			grpc.WithDefaultCallOptions(grpc.WaitForReady(true)),
+		)
+		if err != nil {
+			t.Fatalf("grpc.NewClient() failed: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return agent.NewSender("", agent.WithGRPC(conn))
+	}
+	agent1, agent2, agent3 := dial("agent-1"), dial("agent-2"), dial("agent-3")
+	gauges := map[string]float64{"A": 1, "B": 1, "C": 1}
+
+	tests := []struct {
+		name     string
+		sender   *agent.Sender
+		gauges   map[string]float64
+		counters map[string]int64
+		code     codes.Code
+	}{
+		{"Первый пакет", agent1, nil, map[string]int64{"PollCount": 1}, codes.OK},
+		{"Превышение частоты", agent1, nil, map[string]int64{"PollCount": 1}, codes.ResourceExhausted},
+		{"Другой агент", agent2, nil, map[string]int64{"PollCount": 1}, codes.OK},
+		{"Пакет больше квоты", agent3, gauges, nil, codes.ResourceExhausted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.sender.SendAll(context.Background(), tt.gauges, tt.counters)
+			if status.Code(errors.Unwrap(err)) != tt.code && status.Code(err) != tt.code {
+				t.Errorf("Expected %v, got %v", tt.code, err)
+			}
+		})
+	}
+
+	// Отказы учитываются в собственных метриках сервера, пакет сверх квоты не сохранён
+	if err := recorder.Flush(context.Background(), repo); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	for reason, want := range map[string]int64{ratelimit.ReasonRate: 1, ratelimit.ReasonBatch: 1} {
+		if got, err := repo.GetCounter("ThrottledRequests;reason=" + reason); err != nil || got != want {
+			t.Errorf("ThrottledRequests reason=%s = %d (%v), want %d", reason, got, err, want)
+		}
+	}
+	if value, err := repo.GetCounter("PollCount"); err != nil || value != 2 {
+		t.Errorf("Expected PollCount = 2, got %d (%v)", value, err)
+	}
+	if _, err := repo.GetGauge("A"); err == nil {
+		t.Error("Batch over quota must not be stored")
+	}
+}
+
+func TestClientCertInterceptors(t *testing.T) {
+	serverOpts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(grpcserver.UnaryClientCertInterceptor()),
+		grpc.ChainStreamInterceptor(grpcserver.StreamClientCertInterceptor()),
+	}
+	conn := newConn(t, serverOpts)
+	client := pb.NewMetricsServiceClient(conn)
+	ctx := context.Background()
+
+	// Без клиентского сертификата запись отклоняется, а чтение доступно
+	if _, err := client.Update(ctx, &pb.UpdateRequest{Metric: &pb.Metric{Id: "Alloc", Type: pb.MType_GAUGE, Value: 1}}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated, got %v", err)
+	}
+	stream, err := client.UpdateBatch(ctx)
+	if err != nil {
+		t.Fatalf("UpdateBatch() failed: %v", err)
+	}
+	if _, err := stream.CloseAndRecv(); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated for batch, got %v", err)
+	}
+	if _, err := client.List(ctx, &pb.ListRequest{}); err != nil {
+		t.Errorf("List() failed: %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/auth"
+	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
+	"github.com/akorablin/yandex-practicum-metrics/internal/sign"
+	pb "github.com/akorablin/yandex-practicum-metrics/pkg/api/metricspb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnarySignInterceptor проверяет подпись HMAC-SHA256 метрики в вызовах записи (метаданные hashsha256)
+func UnarySignInterceptor(key string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		update, ok := req.(*pb.UpdateRequest)
+		if !ok || methodScopes[info.FullMethod] == auth.ScopeRead {
+			return handler(ctx, req)
+		}
+		hash, err := signature(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !sign.ValidBatch([]models.Metrics{fromProto(update.GetMetric())}, key, hash) {
+			return nil, status.Error(codes.Unauthenticated, "invalid signature")
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamSignInterceptor проверяет подпись всего пакета UpdateBatch. Сервер сохраняет пакет
+// только при EOF, поэтому неверная подпись, возвращённая вместо EOF, отклоняет его целиком
+func StreamSignInterceptor(key string) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if methodScopes[info.FullMethod] == auth.ScopeRead {
+			return handler(srv, ss)
+		}
+		hash, err := signature(ss.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, &signedStream{ServerStream: ss, key: key, hash: hash})
+	}
+}
+
+type signedStream struct {
+	grpc.ServerStream
+	key     string
+	hash    string
+	metrics []models.Metrics
+}
+
+func (s *signedStream) RecvMsg(m any) error {
+	err := s.ServerStream.RecvMsg(m)
+	if errors.Is(err, io.EOF) {
+		if !sign.ValidBatch(s.metrics, s.key, s.hash) {
+			return status.Error(codes.Unauthenticated, "invalid signature")
+		}
+		return err
+	}
+	if req, ok := m.(*pb.UpdateBatchRequest); ok && err == nil {
+		for _, metric := range req.GetMetrics() {
+			s.metrics = append(s.metrics, fromProto(metric))
+		}
+	}
+	return err
+}
+
+func signature(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	hash := first(md.Get(sign.MetadataKey))
+	if hash == "" {
+		return "", status.Error(codes.Unauthenticated, "missing signature")
+	}
+	return hash, nil
+}
+
+// fromProto переводит метрику в модель без проверок — только для вычисления подписи
+func fromProto(metric *pb.Metric) models.Metrics {
+	m := models.Metrics{ID: metric.GetId()}
+	switch metric.GetType() {
+	case pb.MType_GAUGE:
+		value := metric.GetValue()
+		m.MType, m.Value = models.Gauge, &value
+	case pb.MType_COUNTER:
+		delta := metric.GetDelta()
+		m.MType, m.Delta = models.Counter, &delta
+	}
+	return m
+}
@@ -0,0 +1,67 @@
+package grpcserver
+
+import (
+	"context"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/auth"
+	"github.com/akorablin/yandex-practicum-metrics/internal/trusted"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// WithTrustedSubnet принимает метрики только от адресов доверенной подсети
+func WithTrustedSubnet(checker *trusted.Checker) Option {
+	return func(s *Server) {
+		s.trusted = checker
+	}
+}
+
+// UnaryTrustedInterceptor проверяет адрес клиента унарных вызовов записи
+func UnaryTrustedInterceptor(checker *trusted.Checker) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := checkSubnet(ctx, checker, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamTrustedInterceptor проверяет адрес клиента потоковых вызовов записи
+func StreamTrustedInterceptor(checker *trusted.Checker) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := checkSubnet(ss.Context(), checker, info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+func checkSubnet(ctx context.Context, checker *trusted.Checker, method string) error {
+	if methodScopes[method] == auth.ScopeRead {
+		return nil
+	}
+
+	var remoteAddr, realIP, forwardedFor string
+	if p, ok := peer.FromContext(ctx); ok {
+		remoteAddr = p.Addr.String()
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		realIP = first(md.Get("x-real-ip"))
+		forwardedFor = first(md.Get("x-forwarded-for"))
+	}
+
+	if !checker.Allowed(checker.ClientIP(remoteAddr, realIP, forwardedFor)) {
+		return status.Error(codes.PermissionDenied, "address is outside trusted subnet")
+	}
+	return nil
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
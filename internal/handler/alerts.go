@@ -0,0 +1,20 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/problem"
+)
+
+// alertsHandler возвращает состояние правил алертинга (GET /alerts)
+func (h *Handlers) alertsHandler(res http.ResponseWriter, req *http.Request) {
+	if h.alerts == nil {
+		problem.Write(res, req, http.StatusServiceUnavailable, problem.CodeFeatureDisabled, "alerting is disabled")
+		return
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(http.StatusOK)
+	json.NewEncoder(res).Encode(h.alerts.Alerts())
+}
@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
+	"github.com/akorablin/yandex-practicum-metrics/internal/problem"
+	"github.com/akorablin/yandex-practicum-metrics/internal/service"
+	"github.com/akorablin/yandex-practicum-metrics/internal/storage"
+	"github.com/go-chi/chi"
+)
+
+// apiValue — тело PUT и POST /api/v1/metrics/{type}/{name}
+type apiValue struct {
+	Value *float64 `json:"value,omitempty"`
+	Delta *int64   `json:"delta,omitempty"`
+}
+
+// apiListMetrics возвращает все метрики (GET /api/v1/metrics)
+func (h *Handlers) apiListMetrics(res http.ResponseWriter, req *http.Request) {
+	writeJSON(res, h.metrics.List())
+}
+
+// apiGetMetric возвращает метрику (GET /api/v1/metrics/{type}/{name})
+func (h *Handlers) apiGetMetric(res http.ResponseWriter, req *http.Request) {
+	m, err := h.metrics.Get(chi.URLParam(req, "type"), chi.URLParam(req, "name"))
+	if err != nil {
+		serviceError(res, req, err)
+		return
+	}
+	writeJSON(res, m)
+}
+
+// apiPutMetric записывает значение gauge (PUT /api/v1/metrics/gauge/{name}, тело {"value": ...})
+func (h *Handlers) apiPutMetric(res http.ResponseWriter, req *http.Request) {
+	mtype, name := chi.URLParam(req, "type"), chi.URLParam(req, "name")
+	if mtype != models.Gauge && mtype != models.Counter {
+		serviceError(res, req, storage.ErrInvalidType)
+		return
+	}
+	if mtype != models.Gauge {
+		methodNotAllowed(res, req, "counter is incremented with POST", http.MethodGet, http.MethodPost, http.MethodDelete)
+		return
+	}
+
+	body, ok := h.decodeAPIValue(res, req, models.Metrics{ID: name, MType: mtype})
+	if !ok {
+		return
+	}
+	if body.Value == nil {
+		problem.Write(res, req, http.StatusBadRequest, problem.CodeValidationFailed, "validation failed", "value is required for gauge")
+		return
+	}
+
+	m, err := h.metrics.SetGauge(name, *body.Value)
+	if err != nil {
+		serviceError(res, req, err)
+		return
+	}
+	h.recordSeries(req, []models.Metrics{m})
+	writeJSON(res, m)
+}
+
+// apiPostMetric увеличивает counter (POST /api/v1/metrics/counter/{name}, тело {"delta": ...})
+// и возвращает итоговое значение
+func (h *Handlers) apiPostMetric(res http.ResponseWriter, req *http.Request) {
+	mtype, name := chi.URLParam(req, "type"), chi.URLParam(req, "name")
+	if mtype != models.Gauge && mtype != models.Counter {
+		serviceError(res, req, storage.ErrInvalidType)
+		return
+	}
+	if mtype != models.Counter {
+		methodNotAllowed(res, req, "gauge is set with PUT", http.MethodGet, http.MethodPut, http.MethodDelete)
+		return
+	}
+
+	body, ok := h.decodeAPIValue(res, req, models.Metrics{ID: name, MType: mtype})
+	if !ok {
+		return
+	}
+	if body.Delta == nil {
+		problem.Write(res, req, http.StatusBadRequest, problem.CodeValidationFailed, "validation failed", "delta is required for counter")
+		return
+	}
+
+	m, err := h.metrics.AddCounter(name, *body.Delta)
+	if err != nil {
+		serviceError(res, req, err)
+		return
+	}
+	h.recordSeries(req, []models.Metrics{m})
+	writeJSON(res, m)
+}
+
+// apiDeleteMetric удаляет метрику (DELETE /api/v1/metrics/{type}/{name})
+func (h *Handlers) apiDeleteMetric(res http.ResponseWriter, req *http.Request) {
+	if err := h.metrics.Delete(chi.URLParam(req, "type"), chi.URLParam(req, "name")); err != nil {
+		serviceError(res, req, err)
+		return
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
+
+// decodeAPIValue разбирает тело запроса и проверяет лимиты и квоты для метрики m
+func (h *Handlers) decodeAPIValue(res http.ResponseWriter, req *http.Request, m models.Metrics) (apiValue, bool) {
+	var body apiValue
+	if !isJSONRequest(req) {
+		problem.Write(res, req, http.StatusUnsupportedMediaType, problem.CodeUnsupportedMediaType, "Content-Type must be application/json")
+		return body, false
+	}
+	defer req.Body.Close()
+
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		if !bodyTooLarge(res, req, err) {
+			problem.Write(res, req, http.StatusBadRequest, problem.CodeInvalidJSON, "invalid json")
+		}
+		return body, false
+	}
+	if !h.withinLimits(res, req, []models.Metrics{m}) || !h.admit(res, req, []models.Metrics{m}) {
+		return body, false
+	}
+	return body, true
+}
+
+func methodNotAllowed(res http.ResponseWriter, req *http.Request, detail string, allowed ...string) {
+	for _, method := range allowed {
+		res.Header().Add("Allow", method)
+	}
+	problem.Write(res, req, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, detail)
+}
+
+// serviceError переводит ошибки сервиса метрик в ответ problem+json
+func serviceError(res http.ResponseWriter, req *http.Request, err error) {
+	switch {
+	case errors.Is(err, storage.ErrMetricNotFound):
+		problem.Write(res, req, http.StatusNotFound, problem.CodeNotFound, "metric not found")
+	case errors.Is(err, storage.ErrInvalidType):
+		problem.Write(res, req, http.StatusBadRequest, problem.CodeUnknownMetricType, "unknown metric type, use gauge or counter")
+	case errors.Is(err, service.ErrEmptyName):
+		problem.Write(res, req, http.StatusBadRequest, problem.CodeInvalidRequest, err.Error())
+	case errors.Is(err, storage.ErrTypeMismatch):
+		problem.Write(res, req, http.StatusConflict, problem.CodeTypeMismatch, err.Error())
+	default:
+		problem.Write(res, req, http.StatusServiceUnavailable, problem.CodeStorageUnavailable, "failed to access metrics storage")
+	}
+}
@@ -0,0 +1,61 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/problem"
+)
+
+func TestAPIv1(t *testing.T) {
+	router := newRouter()
+
+	// Шаги выполняются по порядку: прежние маршруты и /api/v1 работают с одними и теми же метриками
+	steps := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		want   string
+	}{
+		{name: "Установка gauge", method: http.MethodPut, path: "/api/v1/metrics/gauge/Alloc", body: `{"value":1.5}`, status: http.StatusOK, want: `{"id":"Alloc","type":"gauge","value":1.5}`},
+		{name: "Увеличение counter", method: http.MethodPost, path: "/api/v1/metrics/counter/PollCount", body: `{"delta":2}`, status: http.StatusOK, want: `{"id":"PollCount","type":"counter","delta":2}`},
+		{name: "Увеличение через прежний маршрут", method: http.MethodPost, path: "/update/counter/PollCount/3", status: http.StatusOK},
+		{name: "Чтение", method: http.MethodGet, path: "/api/v1/metrics/counter/PollCount", status: http.StatusOK, want: `{"id":"PollCount","type":"counter","delta":5}`},
+		{name: "Чтение через прежний маршрут", method: http.MethodGet, path: "/value/gauge/Alloc", status: http.StatusOK, want: `1.5`},
+		{name: "Список", method: http.MethodGet, path: "/api/v1/metrics", status: http.StatusOK, want: `[{"id":"Alloc","type":"gauge","value":1.5},{"id":"PollCount","type":"counter","delta":5}]`},
+		{name: "PUT для counter", method: http.MethodPut, path: "/api/v1/metrics/counter/PollCount", body: `{"value":1}`, status: http.StatusMethodNotAllowed},
+		{name: "POST для gauge", method: http.MethodPost, path: "/api/v1/metrics/gauge/Alloc", body: `{"delta":1}`, status: http.StatusMethodNotAllowed},
+		{name: "PUT для неизвестного типа", method: http.MethodPut, path: "/api/v1/metrics/histogram/Alloc", body: `{"value":1}`, status: http.StatusBadRequest},
+		{name: "POST для неизвестного типа", method: http.MethodPost, path: "/api/v1/metrics/histogram/Alloc", body: `{"delta":1}`, status: http.StatusBadRequest},
+		{name: "Нет значения", method: http.MethodPut, path: "/api/v1/metrics/gauge/Alloc", body: `{}`, status: http.StatusBadRequest},
+		{name: "Неизвестный тип", method: http.MethodGet, path: "/api/v1/metrics/histogram/Alloc", status: http.StatusBadRequest},
+		{name: "Удаление", method: http.MethodDelete, path: "/api/v1/metrics/gauge/Alloc", status: http.StatusNoContent},
+		{name: "Удалённая метрика", method: http.MethodGet, path: "/api/v1/metrics/gauge/Alloc", status: http.StatusNotFound},
+		{name: "Повторное удаление", method: http.MethodDelete, path: "/api/v1/metrics/gauge/Alloc", status: http.StatusNotFound},
+	}
+
+	for _, step := range steps {
+		req := httptest.NewRequest(step.method, step.path, strings.NewReader(step.body))
+		if step.body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != step.status {
+			t.Fatalf("%s: expected status %d, got %d: %s", step.name, step.status, rec.Code, rec.Body.String())
+		}
+		if got := strings.TrimSpace(rec.Body.String()); step.want != "" && got != step.want {
+			t.Errorf("%s: unexpected body:\n got %s\nwant %s", step.name, got, step.want)
+		}
+		if rec.Code >= http.StatusBadRequest {
+			if _, ok := problem.Parse(rec.Header().Get("Content-Type"), rec.Body.Bytes()); !ok {
+				t.Errorf("%s: expected problem+json, got %q", step.name, rec.Body.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/auth"
+	"github.com/akorablin/yandex-practicum-metrics/internal/config"
+	"github.com/akorablin/yandex-practicum-metrics/internal/handler"
+	"github.com/akorablin/yandex-practicum-metrics/internal/repository/memory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestAuthScopes(t *testing.T) {
+	tokens, err := auth.New(
+		"agent:"+auth.HashToken("agent-token")+":write,"+
+			"viewer:"+auth.HashToken("viewer-token")+":read,"+
+			"ops:"+auth.HashToken("ops-token")+":admin", "")
+	if err != nil {
+		t.Fatalf("auth.New() failed: %v", err)
+	}
+	cfg := &config.ServerConfig{}
+	router := handler.NewHandlers(cfg, memory.New(cfg), nil, zap.NewNop(), handler.WithAuth(tokens)).GetRoutes()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		token  string
+		status int
+	}{
+		{"Запись без токена", http.MethodPost, "/update/gauge/Alloc/1", "", "", http.StatusUnauthorized},
+		{"Запись токеном write", http.MethodPost, "/update/gauge/Alloc/1", "", "agent-token", http.StatusOK},
+		{"Запись токеном read", http.MethodPost, "/update/gauge/Alloc/1", "", "viewer-token", http.StatusForbidden},
+		{"Чтение токеном read", http.MethodGet, "/value/gauge/Alloc", "", "viewer-token", http.StatusOK},
+		{"Чтение токеном write", http.MethodGet, "/value/gauge/Alloc", "", "agent-token", http.StatusForbidden},
+		{"Дашборд без токена", http.MethodGet, "/", "", "", http.StatusUnauthorized},
+		{"Дашборд с токеном в адресе", http.MethodGet, "/?access_token=viewer-token", "", "", http.StatusOK},
+		{"Статика дашборда без токена", http.MethodGet, "/dashboard/dashboard.css", "", "", http.StatusOK},
+		{"Проба готовности без токена", http.MethodGet, "/readyz", "", "", http.StatusOK},
+		{"Метаданные токеном write", http.MethodPut, "/meta/Alloc", `{"unit":"bytes"}`, "agent-token", http.StatusForbidden},
+		{"Метаданные токеном admin", http.MethodPut, "/meta/Alloc", `{"unit":"bytes"}`, "ops-token", http.StatusOK},
+		{"Запись в /api/v1 токеном write", http.MethodPut, "/api/v1/metrics/gauge/Alloc", `{"value":2}`, "agent-token", http.StatusOK},
+		{"Удаление токеном write", http.MethodDelete, "/api/v1/metrics/gauge/Alloc", "", "agent-token", http.StatusForbidden},
+		{"Удаление токеном admin", http.MethodDelete, "/api/v1/metrics/gauge/Alloc", "", "ops-token", http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestDashboardTicket(t *testing.T) {
+	tokens, err := auth.New(
+		"viewer:"+auth.HashToken("viewer-token")+":read,"+
+			"ops:"+auth.HashToken("ops-token")+":admin", "")
+	if err != nil {
+		t.Fatalf("auth.New() failed: %v", err)
+	}
+	core, logs := observer.New(zap.InfoLevel)
+	cfg := &config.ServerConfig{}
+	router := handler.NewHandlers(cfg, memory.New(cfg), nil, zap.New(core), handler.WithAuth(tokens)).GetRoutes()
+
+	serve := func(method, path string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve(http.MethodGet, "/?access_token=viewer-token", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	var ticket *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == auth.TicketCookie {
+			ticket = c
+		}
+	}
+	if ticket == nil || !ticket.HttpOnly {
+		t.Fatalf("Expected HttpOnly ticket cookie, got %v", rec.Result().Cookies())
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		cookie *http.Cookie
+		status int
+	}{
+		{"Чтение по билету", http.MethodGet, "/meta", ticket, http.StatusOK},
+		{"Билет не даёт записи", http.MethodPost, "/update/gauge/Alloc/1", ticket, http.StatusUnauthorized},
+		{"Поддельный билет", http.MethodGet, "/meta", &http.Cookie{Name: auth.TicketCookie, Value: ticket.Value + "x"}, http.StatusUnauthorized},
+		{"Токен admin в адресе", http.MethodGet, "/meta?access_token=ops-token", nil, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := serve(tt.method, tt.path, tt.cookie); rec.Code != tt.status {
+				t.Errorf("Expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	if logs.Len() == 0 {
+		t.Fatal("Expected request log entries")
+	}
+	for _, entry := range logs.All() {
+		for _, field := range entry.Context {
+			if strings.Contains(field.String, "viewer-token") || strings.Contains(field.String, "ops-token") {
+				t.Errorf("Token leaked into log: %s=%s", field.Key, field.String)
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package handler_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/agent"
+	"github.com/akorablin/yandex-practicum-metrics/internal/compression"
+	"github.com/akorablin/yandex-practicum-metrics/internal/config"
+	"github.com/akorablin/yandex-practicum-metrics/internal/handler"
+	"github.com/akorablin/yandex-practicum-metrics/internal/repository/memory"
+	"go.uber.org/zap"
+)
+
+func TestCompressedRequests(t *testing.T) {
+	cfg := &config.ServerConfig{}
+	repo := memory.New(cfg)
+	server := httptest.NewServer(handler.NewHandlers(cfg, repo, nil, zap.NewNop()).GetRoutes())
+	defer server.Close()
+
+	for _, encoding := range compression.Preferred {
+		t.Run(encoding, func(t *testing.T) {
+			sender := agent.NewSender(server.URL, agent.WithCompression(encoding))
+			if err := sender.SendAllMetricsJSON(context.Background(), map[string]float64{"Alloc_" + encoding: 1}, nil); err != nil {
+				t.Fatalf("SendAllMetricsJSON: %v", err)
+			}
+			if _, err := repo.GetGauge("Alloc_" + encoding); err != nil {
+				t.Errorf("Metric was not stored: %v", err)
+			}
+		})
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/updates/", strings.NewReader(`[]`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "lz4")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected status %d for unknown encoding, got %d", http.StatusUnsupportedMediaType, resp.StatusCode)
+	}
+}
+
+func TestCompressedResponses(t *testing.T) {
+	cfg := &config.ServerConfig{CompressMinSize: 1024}
+	repo := memory.New(cfg)
+	for i := 0; i < 100; i++ {
+		repo.UpdateGauge(fmt.Sprintf("Gauge%d", i), float64(i))
+	}
+	router := handler.NewHandlers(cfg, repo, nil, zap.NewNop()).GetRoutes()
+
+	tests := []struct {
+		name     string
+		path     string
+		accept   string
+		encoding string
+	}{
+		{"Большой ответ, zstd", "/", "gzip, zstd", compression.Zstd},
+		{"Большой ответ, brotli", "/", "br", compression.Brotli},
+		{"Большой ответ, deflate", "/", "deflate", compression.Deflate},
+		{"Клиент не поддерживает сжатие", "/", "", ""},
+		{"Ответ меньше порога", "/value/gauge/Gauge1", "gzip", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept-Encoding", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", rec.Code)
+			}
+			if got := rec.Header().Get("Content-Encoding"); got != tt.encoding {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.encoding)
+			}
+			if got := rec.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("Vary = %q, want Accept-Encoding", got)
+			}
+			if tt.encoding == "" {
+				return
+			}
+
+			r, err := compression.NewReader(tt.encoding, rec.Body)
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			body, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("Failed to decompress response: %v", err)
+			}
+			if !strings.Contains(string(body), "Gauge99") {
+				t.Errorf("Decompressed body does not contain metrics: %.100s", body)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"embed"
+	"html/template"
+	"io/fs"
+	"log"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/history"
+	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
+	"github.com/akorablin/yandex-practicum-metrics/internal/problem"
+	"github.com/go-chi/chi"
+)
+
+//go:embed dashboard
+var dashboardFS embed.FS
+
+// Шаблон встроен в бинарник и разбирается один раз при старте
+var dashboardTemplate = template.Must(template.ParseFS(dashboardFS, "dashboard/index.html"))
+
+const (
+	historyDefaultWindow = 15 * time.Minute
+	historyDefaultPoints = 60
+	historyMaxPoints     = 1000
+)
+
+type dashboardMetric struct {
+	ID    string
+	Type  string
+	Value string
+	Meta  models.Metadata
+}
+
+type historySeries struct {
+	ID     string       `json:"id"`
+	Type   string       `json:"type"`
+	Points [][2]float64 `json:"points"`
+}
+
+// rootHandler отдаёт дашборд (GET /). Таблица рендерится на сервере,
+// поиск, группировка, графики и обновления по /stream работают в браузере
+func (h *Handlers) rootHandler(res http.ResponseWriter, req *http.Request) {
+	gauges, counters := h.storage.GetAllMetrics()
+
+	registered := h.storage.GetAllMetadata()
+	meta := func(id string) models.Metadata {
+		name, _ := models.ParseLabels(id)
+		return registered[name]
+	}
+
+	metrics := make([]dashboardMetric, 0, len(gauges)+len(counters))
+	for id, value := range gauges {
+		metrics = append(metrics, dashboardMetric{ID: id, Type: models.Gauge, Value: strconv.FormatFloat(value, 'f', -1, 64), Meta: meta(id)})
+	}
+	for id, value := range counters {
+		metrics = append(metrics, dashboardMetric{ID: id, Type: models.Counter, Value: strconv.FormatInt(value, 10), Meta: meta(id)})
+	}
+	sort.Slice(metrics, func(i, j int) bool {
+		if metrics[i].ID != metrics[j].ID {
+			return metrics[i].ID < metrics[j].ID
+		}
+		return metrics[i].Type < metrics[j].Type
+	})
+
+	data := struct {
+		Metrics  []dashboardMetric
+		Gauges   int
+		Counters int
+		History  bool
+		Stream   bool
+	}{
+		Metrics:  metrics,
+		Gauges:   len(gauges),
+		Counters: len(counters),
+		History:  h.history != nil,
+		Stream:   h.hub != nil,
+	}
+
+	res.Header().Set("Content-Type", "text/html; charset=utf-8")
+	res.WriteHeader(http.StatusOK)
+
+	if err := dashboardTemplate.Execute(res, data); err != nil {
+		log.Printf("Template execution error: %v", err)
+	}
+}
+
+// dashboardAssetHandler отдаёт встроенные скрипты и стили дашборда (GET /dashboard/{file})
+func (h *Handlers) dashboardAssetHandler(res http.ResponseWriter, req *http.Request) {
+	name := chi.URLParam(req, "file")
+	if path.Ext(name) == ".html" {
+		problem.Write(res, req, http.StatusNotFound, problem.CodeNotFound, "asset not found")
+		return
+	}
+	assets, _ := fs.Sub(dashboardFS, "dashboard")
+	http.ServeFileFS(res, req, assets, name)
+}
+
+// historyHandler возвращает прореженную историю метрик за окно (GET /history).
+// Параметры: window (например 15m), points — число точек на ряд, id и type — фильтр по метрике
+func (h *Handlers) historyHandler(res http.ResponseWriter, req *http.Request) {
+	if h.history == nil {
+		problem.Write(res, req, http.StatusServiceUnavailable, problem.CodeFeatureDisabled, "history is disabled")
+		return
+	}
+
+	query := req.URL.Query()
+	window := historyDefaultWindow
+	if s := query.Get("window"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			problem.Write(res, req, http.StatusBadRequest, problem.CodeInvalidRequest, "invalid window")
+			return
+		}
+		window = d
+	}
+	points := historyDefaultPoints
+	if s := query.Get("points"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > historyMaxPoints {
+			problem.Write(res, req, http.StatusBadRequest, problem.CodeInvalidRequest, "invalid points")
+			return
+		}
+		points = n
+	}
+	id, mtype := query.Get("id"), query.Get("type")
+
+	to := time.Now()
+	from := to.Add(-window)
+	bucket := window / time.Duration(points)
+
+	result := []historySeries{}
+	for _, series := range h.history.Series() {
+		if (id != "" && series.ID != id) || (mtype != "" && series.MType != mtype) {
+			continue
+		}
+		samples := downsample(h.history.Range(series, from, to), from, bucket)
+		if len(samples) == 0 {
+			continue
+		}
+		s := historySeries{ID: series.ID, Type: series.MType, Points: make([][2]float64, 0, len(samples))}
+		for _, sample := range samples {
+			s.Points = append(s.Points, [2]float64{float64(sample.Time.UnixMilli()), sample.Value})
+		}
+		result = append(result, s)
+	}
+	writeJSON(res, result)
+}
+
+// downsample оставляет последнюю точку в каждом интервале длиной bucket, отсчитывая от from
+func downsample(samples []history.Sample, from time.Time, bucket time.Duration) []history.Sample {
+	if bucket <= 0 {
+		return samples
+	}
+
+	var result []history.Sample
+	var lastBucket int64 = -1
+	for _, s := range samples {
+		b := int64(s.Time.Sub(from) / bucket)
+		if b == lastBucket {
+			result[len(result)-1] = s
+			continue
+		}
+		lastBucket = b
+		result = append(result, s)
+	}
+	return result
+}
@@ -0,0 +1,430 @@
+// Дашборд: поиск, группировка по префиксу, графики из /history и обновления из /stream
+(function () {
+    'use strict';
+
+    const body = document.body;
+    const historyEnabled = body.dataset.history === 'true';
+    const streamEnabled = body.dataset.stream === 'true';
+
+    const tbody = document.querySelector('#metrics tbody');
+    const search = document.getElementById('search');
+    const typeFilter = document.getElementById('type-filter');
+    const groupToggle = document.getElementById('group');
+    const status = document.getElementById('status');
+
+    // Токен из адреса страницы (?access_token=...) сервер обменял на cookie с билетом,
+    // который fetch и EventSource отправляют сами; токен убирается из адресной строки и истории
+    if (new URLSearchParams(location.search).has('access_token')) {
+        history.replaceState(null, '', location.pathname);
+    }
+
+    const SVG_NS = 'http://www.w3.org/2000/svg';
+    const SPARK_REFRESH_MS = 30000;
+
+    // Метрики по ключу type:id
+    const metrics = new Map();
+    const collapsed = new Set();
+    // Зарегистрированные метаданные по имени метрики (GET /meta)
+    const metadata = new Map();
+
+    function key(type, id) {
+        return type + ':' + id;
+    }
+
+    // --- Единицы измерения ---
+
+    const BYTES = /(Alloc|Sys|Inuse|Idle|Released|NextGC|Memory|Bytes)$/;
+    const NANOS = /(Ns|Nanoseconds)$/;
+
+    // Единица из метаданных приоритетнее эвристики по имени
+    const UNIT_ALIASES = {
+        bytes: 'bytes', byte: 'bytes', By: 'bytes',
+        ns: 'ns', nanoseconds: 'ns',
+        us: 'us', microseconds: 'us',
+        ms: 'ms', milliseconds: 'ms',
+        s: 's', seconds: 's',
+        percent: '%', '%': '%',
+    };
+
+    function unitOf(id, registered) {
+        if (registered) {
+            return UNIT_ALIASES[registered] || registered;
+        }
+        const name = id.split(';')[0];
+        if (name === 'LastGC') {
+            return 'timestamp_ns';
+        }
+        if (NANOS.test(name)) {
+            return 'ns';
+        }
+        if (BYTES.test(name) && !/Objects/.test(name)) {
+            return 'bytes';
+        }
+        return '';
+    }
+
+    function formatBytes(v) {
+        const units = ['B', 'KiB', 'MiB', 'GiB', 'TiB'];
+        let i = 0;
+        while (Math.abs(v) >= 1024 && i < units.length - 1) {
+            v /= 1024;
+            i++;
+        }
+        return (i === 0 ? v : v.toFixed(2)) + ' ' + units[i];
+    }
+
+    function formatNanos(v) {
+        const units = [['ns', 1], ['µs', 1e3], ['ms', 1e6], ['s', 1e9]];
+        let unit = units[0];
+        for (const u of units) {
+            if (Math.abs(v) >= u[1]) {
+                unit = u;
+            }
+        }
+        return +(v / unit[1]).toFixed(2) + ' ' + unit[0];
+    }
+
+    function formatNumber(v) {
+        if (Number.isInteger(v)) {
+            return v.toLocaleString();
+        }
+        return +v.toPrecision(6) + '';
+    }
+
+    function formatValue(m, value) {
+        const unit = unitOf(m.id, m.unit);
+        switch (unit) {
+        case '':
+            return formatNumber(value);
+        case 'bytes':
+            return formatBytes(value);
+        case 'ns':
+            return formatNanos(value);
+        case 'us':
+            return formatNanos(value * 1e3);
+        case 'ms':
+            return formatNanos(value * 1e6);
+        case 's':
+            return formatNanos(value * 1e9);
+        case '%':
+            return formatNumber(value) + ' %';
+        case 'timestamp_ns':
+            return value > 0 ? new Date(value / 1e6).toLocaleString() : '—';
+        default:
+            return formatNumber(value) + ' ' + unit;
+        }
+    }
+
+    // --- Таблица ---
+
+    // prefixOf выделяет группу: часть до первого разделителя или первое слово в CamelCase
+    function prefixOf(id) {
+        const name = id.split(';')[0];
+        const sep = name.search(/[._:]/);
+        if (sep > 0) {
+            return name.slice(0, sep);
+        }
+        const word = name.match(/^[A-Z]+(?=[A-Z][a-z])|^[A-Z]?[a-z0-9]+|^[A-Z]+/);
+        return word ? word[0] : name;
+    }
+
+    function register(tr, id, type, value, unit) {
+        const text = tr.querySelector('.name').textContent;
+        const m = {tr: tr, id: id, type: type, value: value, unit: unit, search: (text + ' ' + type).toLowerCase()};
+        tr.querySelector('.value').textContent = formatValue(m, value);
+        tr.querySelector('.value').title = String(value);
+        tr.addEventListener('click', function () {
+            openChart(m);
+        });
+        metrics.set(key(type, id), m);
+        return m;
+    }
+
+    function createRow(id, type) {
+        const tr = document.createElement('tr');
+        tr.dataset.id = id;
+        tr.dataset.type = type;
+        for (const cls of ['name', 'type', 'value', 'spark-cell']) {
+            const td = document.createElement('td');
+            td.className = cls;
+            tr.appendChild(td);
+        }
+        tr.querySelector('.name').textContent = id;
+        tr.querySelector('.type').textContent = type;
+        return tr;
+    }
+
+    function matches(m, terms, type) {
+        if (type && m.type !== type) {
+            return false;
+        }
+        return terms.every(function (t) {
+            return m.search.includes(t);
+        });
+    }
+
+    // render перестраивает порядок строк с учётом фильтра и группировки
+    function render() {
+        const terms = search.value.toLowerCase().split(/\s+/).filter(Boolean);
+        const type = typeFilter.value;
+        const sorted = Array.from(metrics.values()).sort(function (a, b) {
+            return a.id < b.id ? -1 : a.id > b.id ? 1 : a.type < b.type ? -1 : 1;
+        });
+
+        const groups = new Map();
+        for (const m of sorted) {
+            const visible = matches(m, terms, type);
+            const prefix = groupToggle.checked ? prefixOf(m.id) : '';
+            if (!groups.has(prefix)) {
+                groups.set(prefix, []);
+            }
+            if (visible) {
+                groups.get(prefix).push(m);
+            }
+            m.tr.hidden = true;
+        }
+
+        const fragment = document.createDocumentFragment();
+        let shown = 0;
+        for (const [prefix, members] of groups) {
+            if (members.length === 0) {
+                continue;
+            }
+            const isCollapsed = collapsed.has(prefix);
+            if (prefix !== '' && members.length > 1) {
+                fragment.appendChild(groupRow(prefix, members.length, isCollapsed));
+            }
+            for (const m of members) {
+                m.tr.hidden = isCollapsed && members.length > 1;
+                fragment.appendChild(m.tr);
+                shown++;
+            }
+        }
+        if (shown === 0) {
+            const tr = document.createElement('tr');
+            tr.className = 'empty';
+            tr.innerHTML = '<td colspan="4">No metrics match</td>';
+            fragment.appendChild(tr);
+        }
+
+        tbody.replaceChildren(fragment);
+    }
+
+    function groupRow(prefix, count, isCollapsed) {
+        const tr = document.createElement('tr');
+        tr.className = 'group';
+        const td = document.createElement('td');
+        td.colSpan = 4;
+        td.textContent = (isCollapsed ? '▸ ' : '▾ ') + prefix + ' ';
+        const span = document.createElement('span');
+        span.className = 'count';
+        span.textContent = '(' + count + ')';
+        td.appendChild(span);
+        tr.appendChild(td);
+        tr.addEventListener('click', function () {
+            if (collapsed.has(prefix)) {
+                collapsed.delete(prefix);
+            } else {
+                collapsed.add(prefix);
+            }
+            render();
+        });
+        return tr;
+    }
+
+    // Повторный рендер при частых обновлениях откладываем до следующего кадра
+    let renderPending = false;
+    function scheduleRender() {
+        if (!renderPending) {
+            renderPending = true;
+            requestAnimationFrame(function () {
+                renderPending = false;
+                render();
+            });
+        }
+    }
+
+    // --- Графики ---
+
+    function polyline(points, width, height) {
+        const values = points.map(function (p) {
+            return p[1];
+        });
+        const min = Math.min.apply(null, values);
+        const max = Math.max.apply(null, values);
+        const t0 = points[0][0];
+        const span = points[points.length - 1][0] - t0 || 1;
+        const line = document.createElementNS(SVG_NS, 'polyline');
+        line.setAttribute('points', points.map(function (p) {
+            const x = (p[0] - t0) / span * width;
+            const y = max === min ? height / 2 : height - (p[1] - min) / (max - min) * height;
+            return x.toFixed(1) + ',' + y.toFixed(1);
+        }).join(' '));
+        return {line: line, min: min, max: max};
+    }
+
+    function sparkline(points) {
+        const svg = document.createElementNS(SVG_NS, 'svg');
+        svg.setAttribute('class', 'spark');
+        svg.setAttribute('viewBox', '0 0 120 24');
+        svg.setAttribute('preserveAspectRatio', 'none');
+        if (points.length > 1) {
+            svg.appendChild(polyline(points, 120, 22).line);
+        }
+        return svg;
+    }
+
+    function refreshSparklines() {
+        fetch('/history?window=15m&points=40')
+            .then(function (res) {
+                return res.ok ? res.json() : [];
+            })
+            .then(function (series) {
+                for (const s of series) {
+                    const m = metrics.get(key(s.type, s.id));
+                    if (m) {
+                        m.tr.querySelector('.spark-cell').replaceChildren(sparkline(s.points));
+                    }
+                }
+            })
+            .catch(function () {});
+    }
+
+    const chart = document.getElementById('chart');
+    const chartSvg = document.getElementById('chart-svg');
+    let chartMetric = null;
+    let chartWindow = '15m';
+
+    function openChart(m) {
+        if (!historyEnabled) {
+            return;
+        }
+        chartMetric = m;
+        document.getElementById('chart-title').textContent = m.id + ' (' + m.type + ')';
+        chart.hidden = false;
+        drawChart();
+    }
+
+    function drawChart() {
+        const m = chartMetric;
+        const url = '/history?points=300&window=' + chartWindow +
+            '&type=' + encodeURIComponent(m.type) + '&id=' + encodeURIComponent(m.id);
+        fetch(url)
+            .then(function (res) {
+                return res.ok ? res.json() : [];
+            })
+            .then(function (series) {
+                chartSvg.replaceChildren();
+                const points = series.length ? series[0].points : [];
+                if (points.length < 2) {
+                    document.getElementById('chart-range').textContent = 'Not enough history yet';
+                    return;
+                }
+                const p = polyline(points, 800, 220);
+                p.line.setAttribute('transform', 'translate(0,10)');
+                chartSvg.appendChild(p.line);
+                document.getElementById('chart-range').textContent =
+                    'min ' + formatValue(m, p.min) + ' · max ' + formatValue(m, p.max);
+                document.getElementById('chart-from').textContent = new Date(points[0][0]).toLocaleTimeString();
+                document.getElementById('chart-to').textContent = new Date(points[points.length - 1][0]).toLocaleTimeString();
+            })
+            .catch(function () {});
+    }
+
+    document.querySelectorAll('.ranges button').forEach(function (button) {
+        button.addEventListener('click', function () {
+            document.querySelectorAll('.ranges button').forEach(function (b) {
+                b.classList.toggle('active', b === button);
+            });
+            chartWindow = button.dataset.window;
+            drawChart();
+        });
+    });
+    document.getElementById('chart-close').addEventListener('click', function () {
+        chart.hidden = true;
+        chartMetric = null;
+    });
+
+    // --- Обновления в реальном времени ---
+
+    function update(event) {
+        const data = JSON.parse(event.data);
+        const value = data.type === 'counter' ? data.delta : data.value;
+        const k = key(data.type, data.id);
+        let m = metrics.get(k);
+        if (!m) {
+            const meta = metadata.get(data.id.split(';')[0]) || {};
+            m = register(createRow(data.id, data.type), data.id, data.type, value, meta.unit || '');
+            scheduleRender();
+        }
+        m.value = value;
+        const cell = m.tr.querySelector('.value');
+        cell.textContent = formatValue(m, value);
+        cell.title = String(value);
+        m.tr.classList.remove('flash');
+        void m.tr.offsetWidth;
+        m.tr.classList.add('flash');
+    }
+
+    function remove(event) {
+        const data = JSON.parse(event.data);
+        const k = key(data.type, data.id);
+        if (metrics.delete(k)) {
+            scheduleRender();
+        }
+    }
+
+    function connect() {
+        const source = new EventSource('/stream');
+        source.addEventListener('open', function () {
+            status.textContent = 'live';
+            status.classList.add('live');
+        });
+        source.addEventListener('metric', update);
+        source.addEventListener('deleted', remove);
+        source.addEventListener('lagged', function () {
+            // Сервер отключил отстающего подписчика: часть обновлений потеряна
+            source.close();
+            location.reload();
+        });
+        source.addEventListener('error', function () {
+            status.textContent = 'reconnecting…';
+            status.classList.remove('live');
+        });
+    }
+
+    // --- Инициализация ---
+
+    tbody.querySelectorAll('tr[data-id]').forEach(function (tr) {
+        register(tr, tr.dataset.id, tr.dataset.type, Number(tr.dataset.value), tr.dataset.unit);
+    });
+    render();
+
+    fetch('/meta')
+        .then(function (res) {
+            return res.ok ? res.json() : [];
+        })
+        .then(function (list) {
+            for (const meta of list) {
+                metadata.set(meta.name, meta);
+            }
+        })
+        .catch(function () {});
+
+    search.addEventListener('input', scheduleRender);
+    typeFilter.addEventListener('change', render);
+    groupToggle.addEventListener('change', render);
+
+    if (historyEnabled) {
+        refreshSparklines();
+        setInterval(function () {
+            refreshSparklines();
+            if (chartMetric) {
+                drawChart();
+            }
+        }, SPARK_REFRESH_MS);
+    }
+    if (streamEnabled) {
+        connect();
+    }
+})();
@@ -0,0 +1,88 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/config"
+	"github.com/akorablin/yandex-practicum-metrics/internal/handler"
+	"github.com/akorablin/yandex-practicum-metrics/internal/history"
+	"github.com/akorablin/yandex-practicum-metrics/internal/repository/memory"
+	"go.uber.org/zap"
+)
+
+func TestDashboard(t *testing.T) {
+	cfg := &config.ServerConfig{HistoryInterval: 10, HistoryRetention: 3600}
+	repo := memory.New(cfg)
+	store := history.New(cfg, repo)
+	repo.UpdateGauge("HeapAlloc", 1024)
+	repo.UpdateCounter("PollCount", 5)
+	store.Snapshot(time.Now().Add(-time.Minute))
+	repo.UpdateGauge("HeapAlloc", 2048)
+	store.Snapshot(time.Now())
+
+	router := handler.NewHandlers(cfg, repo, nil, zap.NewNop(), handler.WithHistory(store)).GetRoutes()
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	// Страница рендерится на сервере и содержит строки всех метрик
+	rec := get("/")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET / returned %d", rec.Code)
+	}
+	for _, row := range []string{
+		`data-id="HeapAlloc" data-type="gauge" data-value="2048"`,
+		`data-id="PollCount" data-type="counter" data-value="5"`,
+	} {
+		if !strings.Contains(rec.Body.String(), row) {
+			t.Errorf("Dashboard does not contain %s", row)
+		}
+	}
+
+	// Статика отдаётся из встроенных файлов, шаблон страницы — нет
+	if rec := get("/dashboard/dashboard.js"); rec.Code != http.StatusOK || !strings.Contains(rec.Header().Get("Content-Type"), "javascript") {
+		t.Errorf("GET /dashboard/dashboard.js returned %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if rec := get("/dashboard/index.html"); rec.Code != http.StatusNotFound {
+		t.Errorf("GET /dashboard/index.html returned %d, want 404", rec.Code)
+	}
+
+	rec = get("/history?window=5m&type=gauge")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /history returned %d: %s", rec.Code, rec.Body.String())
+	}
+	var series []struct {
+		ID     string       `json:"id"`
+		Type   string       `json:"type"`
+		Points [][2]float64 `json:"points"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &series); err != nil {
+		t.Fatalf("Invalid history response: %v", err)
+	}
+	if len(series) != 1 || series[0].ID != "HeapAlloc" || len(series[0].Points) != 2 || series[0].Points[1][1] != 2048 {
+		t.Errorf("Unexpected history: %+v", series)
+	}
+
+	for _, path := range []string{"/history?window=-1m", "/history?points=0", "/history?type=histogram"} {
+		if rec := get(path); rec.Code != http.StatusBadRequest {
+			t.Errorf("GET %s returned %d, want 400", path, rec.Code)
+		}
+	}
+	if rec := get("/history"); rec.Code != http.StatusOK {
+		t.Errorf("GET /history returned %d", rec.Code)
+	}
+
+	// Без истории эндпоинт недоступен
+	rec = httptest.NewRecorder()
+	newRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/history", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("GET /history without history returned %d, want 503", rec.Code)
+	}
+}
@@ -0,0 +1,269 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/history"
+	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
+	"github.com/akorablin/yandex-practicum-metrics/internal/problem"
+)
+
+// Обработчики реализуют контракт источника данных Grafana JSON (SimpleJSON):
+// POST /search, POST /query и POST /annotations
+
+type grafanaRange struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+type grafanaTarget struct {
+	Target string `json:"target"`
+	RefID  string `json:"refId"`
+	Type   string `json:"type"`
+}
+
+type grafanaQueryRequest struct {
+	Range         grafanaRange    `json:"range"`
+	IntervalMs    int64           `json:"intervalMs"`
+	MaxDataPoints int             `json:"maxDataPoints"`
+	Targets       []grafanaTarget `json:"targets"`
+}
+
+type grafanaTimeserie struct {
+	Target     string       `json:"target"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+type grafanaColumn struct {
+	Text string `json:"text"`
+	Type string `json:"type"`
+}
+
+type grafanaTable struct {
+	Type    string          `json:"type"`
+	Columns []grafanaColumn `json:"columns"`
+	Rows    [][]any         `json:"rows"`
+}
+
+type grafanaAnnotationRequest struct {
+	Range      grafanaRange    `json:"range"`
+	Annotation json.RawMessage `json:"annotation"`
+}
+
+type grafanaAnnotation struct {
+	Annotation json.RawMessage `json:"annotation"`
+	Time       int64           `json:"time"`
+	Title      string          `json:"title"`
+	Text       string          `json:"text"`
+	Tags       []string        `json:"tags"`
+}
+
+// grafanaSearchHandler возвращает ID метрик, содержащие строку target (POST /search)
+func (h *Handlers) grafanaSearchHandler(res http.ResponseWriter, req *http.Request) {
+	var body struct {
+		Target string `json:"target"`
+	}
+	if err := decodeOptionalJSON(req, &body); err != nil {
+		if bodyTooLarge(res, req, err) {
+			return
+		}
+		problem.Write(res, req, http.StatusBadRequest, problem.CodeInvalidJSON, "invalid json")
+		return
+	}
+
+	gauges, counters := h.storage.GetAllMetrics()
+	seen := make(map[string]bool, len(gauges)+len(counters))
+	names := []string{}
+	for _, ids := range [][]string{keys(gauges), keys(counters)} {
+		for _, id := range ids {
+			if !seen[id] && strings.Contains(id, body.Target) {
+				seen[id] = true
+				names = append(names, id)
+			}
+		}
+	}
+	sort.Strings(names)
+	writeJSON(res, names)
+}
+
+// grafanaQueryHandler возвращает ряды из истории (type=timeserie) или текущие значения (type=table) (POST /query)
+func (h *Handlers) grafanaQueryHandler(res http.ResponseWriter, req *http.Request) {
+	var body grafanaQueryRequest
+	if err := decodeOptionalJSON(req, &body); err != nil {
+		if bodyTooLarge(res, req, err) {
+			return
+		}
+		problem.Write(res, req, http.StatusBadRequest, problem.CodeInvalidJSON, "invalid json")
+		return
+	}
+	if body.Range.To.Before(body.Range.From) {
+		problem.Write(res, req, http.StatusBadRequest, problem.CodeInvalidRequest, "range.to must not be before range.from")
+		return
+	}
+
+	gauges, counters := h.storage.GetAllMetrics()
+	result := []any{}
+	for _, target := range body.Targets {
+		if target.Target == "" {
+			continue
+		}
+		matched := matchMetrics(target.Target, gauges, counters)
+
+		if target.Type == "table" {
+			table := grafanaTable{
+				Type: "table",
+				Columns: []grafanaColumn{
+					{Text: "Metric", Type: "string"},
+					{Text: "Type", Type: "string"},
+					{Text: "Value", Type: "number"},
+				},
+				Rows: [][]any{},
+			}
+			for _, m := range matched {
+				table.Rows = append(table.Rows, []any{m.ID, m.MType, currentValue(m, gauges, counters)})
+			}
+			result = append(result, table)
+			continue
+		}
+
+		for _, m := range matched {
+			result = append(result, grafanaTimeserie{
+				Target:     m.ID,
+				Datapoints: h.datapoints(m, body, currentValue(m, gauges, counters)),
+			})
+		}
+	}
+	writeJSON(res, result)
+}
+
+// grafanaAnnotationsHandler отдаёт срабатывания и разрешения алертов за период (POST /annotations).
+// Строка запроса аннотации — шаблон имени алерта
+func (h *Handlers) grafanaAnnotationsHandler(res http.ResponseWriter, req *http.Request) {
+	var body grafanaAnnotationRequest
+	if err := decodeOptionalJSON(req, &body); err != nil {
+		if bodyTooLarge(res, req, err) {
+			return
+		}
+		problem.Write(res, req, http.StatusBadRequest, problem.CodeInvalidJSON, "invalid json")
+		return
+	}
+	var query struct {
+		Query string `json:"query"`
+	}
+	if len(body.Annotation) > 0 {
+		json.Unmarshal(body.Annotation, &query)
+	}
+
+	annotations := []grafanaAnnotation{}
+	if h.alerts == nil {
+		writeJSON(res, annotations)
+		return
+	}
+
+	inRange := func(t *time.Time) bool {
+		return t != nil && !t.Before(body.Range.From) && !t.After(body.Range.To)
+	}
+	for _, a := range h.alerts.Alerts() {
+		if query.Query != "" {
+			if ok, _ := path.Match(query.Query, a.Name); !ok {
+				continue
+			}
+		}
+		if inRange(a.FiredAt) {
+			annotations = append(annotations, grafanaAnnotation{
+				Annotation: body.Annotation, Time: a.FiredAt.UnixMilli(),
+				Title: a.Name + " firing", Text: a.Expr, Tags: []string{"alert", "firing"},
+			})
+		}
+		if inRange(a.ResolvedAt) {
+			annotations = append(annotations, grafanaAnnotation{
+				Annotation: body.Annotation, Time: a.ResolvedAt.UnixMilli(),
+				Title: a.Name + " resolved", Text: a.Expr, Tags: []string{"alert", "resolved"},
+			})
+		}
+	}
+	sort.Slice(annotations, func(i, j int) bool { return annotations[i].Time < annotations[j].Time })
+	writeJSON(res, annotations)
+}
+
+// datapoints строит ряд [значение, время в мс] из истории, оставляя последнюю точку
+// в каждом интервале. Без истории возвращается только текущее значение
+func (h *Handlers) datapoints(m history.Series, q grafanaQueryRequest, current float64) [][2]float64 {
+	if h.history == nil {
+		return [][2]float64{{current, float64(time.Now().UnixMilli())}}
+	}
+
+	bucket := time.Duration(q.IntervalMs) * time.Millisecond
+	if q.MaxDataPoints > 0 {
+		if minBucket := q.Range.To.Sub(q.Range.From) / time.Duration(q.MaxDataPoints); minBucket > bucket {
+			bucket = minBucket
+		}
+	}
+
+	points := [][2]float64{}
+	for _, s := range downsample(h.history.Range(m, q.Range.From, q.Range.To), q.Range.From, bucket) {
+		points = append(points, [2]float64{s.Value, float64(s.Time.UnixMilli())})
+	}
+	return points
+}
+
+// matchMetrics ищет метрики по точному ID или по шаблону с * и ?
+func matchMetrics(target string, gauges map[string]float64, counters map[string]int64) []history.Series {
+	var matched []history.Series
+	match := func(id string) bool {
+		if !strings.ContainsAny(target, "*?[") {
+			return id == target
+		}
+		ok, _ := path.Match(target, id)
+		return ok
+	}
+	for _, id := range keys(gauges) {
+		if match(id) {
+			matched = append(matched, history.Series{ID: id, MType: models.Gauge})
+		}
+	}
+	for _, id := range keys(counters) {
+		if match(id) {
+			matched = append(matched, history.Series{ID: id, MType: models.Counter})
+		}
+	}
+	return matched
+}
+
+func currentValue(m history.Series, gauges map[string]float64, counters map[string]int64) float64 {
+	if m.MType == models.Gauge {
+		return gauges[m.ID]
+	}
+	return float64(counters[m.ID])
+}
+
+func keys[V any](m map[string]V) []string {
+	ids := make([]string, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// decodeOptionalJSON разбирает тело запроса, допуская пустое тело
+func decodeOptionalJSON(req *http.Request, v any) error {
+	err := json.NewDecoder(req.Body).Decode(v)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+func writeJSON(res http.ResponseWriter, v any) {
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(http.StatusOK)
+	json.NewEncoder(res).Encode(v)
+}
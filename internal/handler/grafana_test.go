@@ -0,0 +1,105 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/config"
+	"github.com/akorablin/yandex-practicum-metrics/internal/handler"
+	"github.com/akorablin/yandex-practicum-metrics/internal/history"
+	"github.com/akorablin/yandex-practicum-metrics/internal/repository/memory"
+	"go.uber.org/zap"
+)
+
+func TestGrafanaDatasource(t *testing.T) {
+	cfg := &config.ServerConfig{HistoryInterval: 10, HistoryRetention: 3600}
+	repo := memory.New(cfg)
+	store := history.New(cfg, repo)
+
+	// Три снимка HeapAlloc с шагом 10 секунд начиная с 2023-11-14T22:13:20Z
+	start := time.Unix(1700000000, 0)
+	repo.UpdateCounter("PollCount", 3)
+	for i := 0; i < 3; i++ {
+		repo.UpdateGauge("HeapAlloc", float64(100*(i+1)))
+		store.Snapshot(start.Add(time.Duration(i) * 10 * time.Second))
+	}
+	repo.UpdateGauge("HeapInuse", 7)
+
+	router := handler.NewHandlers(cfg, repo, nil, zap.NewNop(), handler.WithHistory(store)).GetRoutes()
+
+	const rng = `"range":{"from":"2023-11-14T22:13:20Z","to":"2023-11-14T22:14:20Z"}`
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+		want   string
+	}{
+		{
+			name:   "Поиск по подстроке",
+			path:   "/search",
+			body:   `{"target":"Heap"}`,
+			status: http.StatusOK,
+			want:   `["HeapAlloc","HeapInuse"]`,
+		},
+		{
+			name:   "Поиск без тела",
+			path:   "/search",
+			status: http.StatusOK,
+			want:   `["HeapAlloc","HeapInuse","PollCount"]`,
+		},
+		{
+			name:   "Ряд из истории",
+			path:   "/query",
+			body:   `{` + rng + `,"targets":[{"target":"HeapAlloc","refId":"A","type":"timeserie"}]}`,
+			status: http.StatusOK,
+			want:   `[{"target":"HeapAlloc","datapoints":[[100,1700000000000],[200,1700000010000],[300,1700000020000]]}]`,
+		},
+		{
+			name:   "Прореживание до maxDataPoints",
+			path:   "/query",
+			body:   `{` + rng + `,"maxDataPoints":3,"targets":[{"target":"HeapAlloc"}]}`,
+			status: http.StatusOK,
+			want:   `[{"target":"HeapAlloc","datapoints":[[200,1700000010000],[300,1700000020000]]}]`,
+		},
+		{
+			name:   "Таблица по шаблону",
+			path:   "/query",
+			body:   `{` + rng + `,"targets":[{"target":"*Count","type":"table"}]}`,
+			status: http.StatusOK,
+			want:   `[{"type":"table","columns":[{"text":"Metric","type":"string"},{"text":"Type","type":"string"},{"text":"Value","type":"number"}],"rows":[["PollCount","counter",3]]}]`,
+		},
+		{
+			name:   "Запрос без диапазона",
+			path:   "/query",
+			body:   `{"targets":[]}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Аннотации без алертинга",
+			path:   "/annotations",
+			body:   `{` + rng + `,"annotation":{"query":"*"}}`,
+			status: http.StatusOK,
+			want:   `[]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+			if got := strings.TrimSpace(rec.Body.String()); tt.want != "" && got != tt.want {
+				t.Errorf("Unexpected body:\n got %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
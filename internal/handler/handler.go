@@ -2,49 +2,190 @@ package handler
 
 import (
 	"context"
+	"crypto/rsa"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"html/template"
-	"io"
 	"log"
+	"mime"
 	"net/http"
 	"strconv"
 	"strings"
 
+	"github.com/akorablin/yandex-practicum-metrics/api"
+	"github.com/akorablin/yandex-practicum-metrics/internal/alert"
+	"github.com/akorablin/yandex-practicum-metrics/internal/auth"
+	"github.com/akorablin/yandex-practicum-metrics/internal/config"
+	"github.com/akorablin/yandex-practicum-metrics/internal/health"
+	"github.com/akorablin/yandex-practicum-metrics/internal/history"
 	"github.com/akorablin/yandex-practicum-metrics/internal/middleware"
 	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
+	"github.com/akorablin/yandex-practicum-metrics/internal/otlp"
+	"github.com/akorablin/yandex-practicum-metrics/internal/problem"
+	"github.com/akorablin/yandex-practicum-metrics/internal/promql"
+	"github.com/akorablin/yandex-practicum-metrics/internal/ratelimit"
+	"github.com/akorablin/yandex-practicum-metrics/internal/selfmetrics"
+	"github.com/akorablin/yandex-practicum-metrics/internal/service"
+	"github.com/akorablin/yandex-practicum-metrics/internal/sign"
 	"github.com/akorablin/yandex-practicum-metrics/internal/storage"
+	"github.com/akorablin/yandex-practicum-metrics/internal/stream"
+	"github.com/akorablin/yandex-practicum-metrics/internal/trusted"
 	"github.com/go-chi/chi"
 	"go.uber.org/zap"
 )
 
 type Handlers struct {
-	storage storage.Storage
-	db      *sql.DB
-	logger  *zap.Logger
+	cfg      *config.ServerConfig
+	storage  storage.Storage
+	metrics  *service.Metrics
+	db       *sql.DB
+	logger   *zap.Logger
+	otlp     *otlp.Converter
+	hub      *stream.Hub
+	alerts   *alert.Manager
+	history  *history.Store
+	promql   *promql.Engine
+	privKey  *rsa.PrivateKey
+	auth     *auth.Store
+	trusted  *trusted.Checker
+	limiter  *ratelimit.Limiter
+	quota    *ratelimit.Quota
+	health   *health.Registry
+	recorder *selfmetrics.Recorder
 }
 
-func NewHandlers(repo storage.Storage, db *sql.DB, logger *zap.Logger) *Handlers {
-	return &Handlers{
+// Option подключает к обработчикам необязательные компоненты сервера
+type Option func(*Handlers)
+
+// WithStream включает поток обновлений GET /stream
+func WithStream(hub *stream.Hub) Option {
+	return func(h *Handlers) {
+		h.hub = hub
+	}
+}
+
+// WithAlerts включает просмотр состояния алертов GET /alerts
+func WithAlerts(manager *alert.Manager) Option {
+	return func(h *Handlers) {
+		h.alerts = manager
+	}
+}
+
+// WithHistory включает запросы PromQL к истории значений (/api/v1/query и др.)
+func WithHistory(store *history.Store) Option {
+	return func(h *Handlers) {
+		h.history = store
+		h.promql = promql.NewEngine(store)
+	}
+}
+
+// WithAuth требует bearer-токены с правами read, write или admin
+func WithAuth(store *auth.Store) Option {
+	return func(h *Handlers) {
+		h.auth = store
+	}
+}
+
+// WithTrustedSubnet принимает метрики только от адресов доверенной подсети
+func WithTrustedSubnet(checker *trusted.Checker) Option {
+	return func(h *Handlers) {
+		h.trusted = checker
+	}
+}
+
+// WithDecryption включает расшифровку тел запросов, зашифрованных открытым ключом сервера
+func WithDecryption(priv *rsa.PrivateKey) Option {
+	return func(h *Handlers) {
+		h.privKey = priv
+	}
+}
+
+func NewHandlers(cfg *config.ServerConfig, repo storage.Storage, db *sql.DB, logger *zap.Logger, opts ...Option) *Handlers {
+	h := &Handlers{
+		cfg:     cfg,
 		storage: repo,
+		metrics: service.New(repo),
 		db:      db,
 		logger:  logger,
+		otlp:    otlp.NewConverter(otlp.SeriesTTL),
+	}
+	for _, opt := range opts {
+		opt(h)
 	}
+	if h.health == nil {
+		h.health = health.New()
+	}
+	h.health.Register("storage", h.checkStorage)
+	return h
 }
 
 func (h *Handlers) GetRoutes() http.Handler {
+	// Спецификация встроена в бинарник, поэтому ошибка здесь возможна только при разработке.
+	// Пакет проверяет decodeBatch: он останавливается на MaxBatchSize, не дочитывая тело
+	validator, err := middleware.OpenAPIValidator(api.OpenAPISpec, "/updates/")
+	if err != nil {
+		panic(err)
+	}
+
 	r := chi.NewRouter()
-	r.Use(middleware.GzipMiddleware)
-	r.Use(middleware.Logging(*h.logger))
+	// chi оборачивает эти обработчики в уже подключённые middleware, поэтому они задаются
+	// до r.Use — иначе middleware выполнились бы для неизвестного маршрута дважды
+	r.NotFound(func(res http.ResponseWriter, req *http.Request) {
+		problem.Write(res, req, http.StatusNotFound, problem.CodeNotFound, "route not found")
+	})
+	r.MethodNotAllowed(func(res http.ResponseWriter, req *http.Request) {
+		problem.Write(res, req, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, req.Method+" is not allowed for this route")
+	})
+	// Лог и метрики запросов подключаются первыми, чтобы учитывать и отказы
+	// в доступе, лимитах и разборе тела (401, 403, 413, 415, 429)
+	r.Use(middleware.Logging(*h.logger, h.observeRequest))
+	// Адрес и токен проверяются до дорогой расшифровки тела
+	r.Use(middleware.TrustedSubnet(h.trusted))
+	r.Use(middleware.ClientCert(h.cfg.TLSCertFile != "" && h.cfg.TLSClientCAFile != ""))
+	r.Use(middleware.Auth(h.auth))
+	r.Use(middleware.RateLimit(h.limiter, h.clientID, h.throttled))
+	// Тело ограничивается дважды: как получено (до расшифровки и распаковки) и после распаковки
+	r.Use(middleware.BodyLimit(int64(h.cfg.MaxBodySize)))
+	r.Use(middleware.Decrypt(h.privKey))
+	r.Use(middleware.Compression(h.cfg.CompressMinSize))
+	r.Use(middleware.BodyLimit(int64(h.cfg.MaxDecompressedSize)))
+	r.Use(middleware.Hash(h.cfg.Key, splitList(h.cfg.UnsignedRoutes)...))
+	r.Use(validator)
 
 	r.Post("/update/{type}/{name}/{value}", h.updateHandler)
 	r.Get("/value/{type}/{name}", h.valueHandler)
 	r.Post("/update/", h.updateMetricJSONHandler)
 	r.Post("/updates/", h.UpdateMetricsBatch)
 	r.Post("/value/", h.valueMetricJSONHandler)
+	r.Post("/write", h.influxWriteHandler)
+	r.Post("/v1/metrics", h.otlpMetricsHandler)
 	r.Get("/ping", h.pingHandler)
+	r.Get("/healthz", h.healthzHandler)
+	r.Get("/readyz", h.readyzHandler)
+	r.Get("/openapi.json", h.openAPIHandler)
+	r.Get("/stream", h.streamHandler)
+	r.Get("/alerts", h.alertsHandler)
+	r.Get("/api/v1/query", h.promQueryHandler)
+	r.Post("/api/v1/query", h.promQueryHandler)
+	r.Get("/api/v1/query_range", h.promQueryRangeHandler)
+	r.Post("/api/v1/query_range", h.promQueryRangeHandler)
+	r.Get("/api/v1/labels", h.promLabelsHandler)
+	r.Get("/api/v1/label/{name}/values", h.promLabelValuesHandler)
+	r.Post("/search", h.grafanaSearchHandler)
+	r.Post("/query", h.grafanaQueryHandler)
+	r.Post("/annotations", h.grafanaAnnotationsHandler)
+	r.Get("/meta", h.listMetaHandler)
+	r.Get("/meta/{name}", h.getMetaHandler)
+	r.Put("/meta/{name}", h.putMetaHandler)
+	r.Get("/api/v1/metadata", h.promMetadataHandler)
+	r.Get("/history", h.historyHandler)
+	r.Get("/dashboard/{file}", h.dashboardAssetHandler)
+	r.Get("/api/v1/metrics", h.apiListMetrics)
+	r.Get("/api/v1/metrics/{type}/{name}", h.apiGetMetric)
+	r.Put("/api/v1/metrics/{type}/{name}", h.apiPutMetric)
+	r.Post("/api/v1/metrics/{type}/{name}", h.apiPostMetric)
+	r.Delete("/api/v1/metrics/{type}/{name}", h.apiDeleteMetric)
 	r.Get("/", h.rootHandler)
 
 	return r
@@ -53,7 +194,7 @@ func (h *Handlers) GetRoutes() http.Handler {
 func (h *Handlers) updateHandler(res http.ResponseWriter, req *http.Request) {
 	// Проверяем HTTP-метод
 	if req.Method != http.MethodPost {
-		http.Error(res, "Only POST requests are allowed!", http.StatusMethodNotAllowed)
+		problem.Write(res, req, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "only POST requests are allowed")
 		return
 	}
 
@@ -64,9 +205,9 @@ func (h *Handlers) updateHandler(res http.ResponseWriter, req *http.Request) {
 	// Проверяем URL
 	if len(parts) != 3 {
 		if parts[0] == "gauge" || parts[0] == "counter" {
-			http.Error(res, "Invalid URL format for type", http.StatusNotFound)
+			problem.Write(res, req, http.StatusNotFound, problem.CodeNotFound, "metric name is required")
 		} else {
-			http.Error(res, "Invalid URL format. Expected: /update/{type}/{name}/{value}", http.StatusBadRequest)
+			problem.Write(res, req, http.StatusBadRequest, problem.CodeInvalidRequest, "expected /update/{type}/{name}/{value}")
 		}
 		return
 	}
@@ -79,23 +220,39 @@ func (h *Handlers) updateHandler(res http.ResponseWriter, req *http.Request) {
 	case "gauge":
 		value, err := strconv.ParseFloat(value, 64)
 		if err != nil {
-			http.Error(res, "Invalid gauge value", http.StatusBadRequest)
+			problem.Write(res, req, http.StatusBadRequest, problem.CodeInvalidValue, "invalid gauge value")
 			return
 		}
-		h.storage.UpdateGauge(metricName, value)
+		metric := []models.Metrics{{ID: metricName, MType: models.Gauge}}
+		if !h.withinLimits(res, req, metric) || !h.admit(res, req, metric) {
+			return
+		}
+		if _, err := h.metrics.SetGauge(metricName, value); err != nil {
+			serviceError(res, req, err)
+			return
+		}
+		h.recordSeries(req, metric)
 		log.Printf("Updated gauge %s = %.6f", metricName, value)
 
 	case "counter":
 		value, err := strconv.ParseInt(value, 10, 64)
 		if err != nil {
-			http.Error(res, "Invalid counter value", http.StatusBadRequest)
+			problem.Write(res, req, http.StatusBadRequest, problem.CodeInvalidValue, "invalid counter value")
+			return
+		}
+		metric := []models.Metrics{{ID: metricName, MType: models.Counter}}
+		if !h.withinLimits(res, req, metric) || !h.admit(res, req, metric) {
+			return
+		}
+		if _, err := h.metrics.AddCounter(metricName, value); err != nil {
+			serviceError(res, req, err)
 			return
 		}
-		h.storage.UpdateCounter(metricName, value)
+		h.recordSeries(req, metric)
 		log.Printf("Updated counter %s (added %d)", metricName, value)
 
 	default:
-		http.Error(res, "Unknown metric type. Use 'gauge' or 'counter'", http.StatusBadRequest)
+		problem.Write(res, req, http.StatusBadRequest, problem.CodeUnknownMetricType, "unknown metric type, use gauge or counter")
 		return
 	}
 
@@ -107,185 +264,53 @@ func (h *Handlers) updateHandler(res http.ResponseWriter, req *http.Request) {
 }
 
 func (h *Handlers) valueHandler(res http.ResponseWriter, req *http.Request) {
-	metricType := chi.URLParam(req, "type")
-	metricName := chi.URLParam(req, "name")
-
-	res.Header().Set("Content-Type", "text/plain; charset=utf-8")
-
-	switch metricType {
-	case "gauge":
-		value, err := h.storage.GetGauge(metricName)
-		if errors.Is(err, storage.ErrMetricNotFound) {
-			http.Error(res, "Gauge metric not found", http.StatusNotFound)
-			return
-		}
-
-		res.WriteHeader(http.StatusOK)
-		fmt.Fprintf(res, "%g", value)
-
-	case "counter":
-		value, err := h.storage.GetCounter(metricName)
-		if errors.Is(err, storage.ErrMetricNotFound) {
-			http.Error(res, "Counter metric not found", http.StatusNotFound)
-			return
-		}
-		res.WriteHeader(http.StatusOK)
-		fmt.Fprintf(res, "%d", value)
-
-	default:
-		http.Error(res, "Unknown metric type. Use 'gauge' or 'counter'", http.StatusBadRequest)
-	}
-}
-
-func (h *Handlers) rootHandler(res http.ResponseWriter, req *http.Request) {
-	var gaugesCopy, countersCopy = h.storage.GetAllMetrics()
-
-	tmpl := `<!DOCTYPE html>
-<html>
-<head>
-    <title>Metrics Server</title>
-    <style>
-        body { 
-            font-family: Arial, sans-serif; 
-            margin: 40px; 
-            background-color: #f5f5f5; 
-        }
-        .container {
-            background-color: white;
-            padding: 20px;
-            border-radius: 8px;
-            box-shadow: 0 2px 4px rgba(0,0,0,0.1);
-        }
-        table { 
-            border-collapse: collapse; 
-            width: 100%; 
-            margin-bottom: 20px; 
-        }
-        th, td { 
-            border: 1px solid #ddd; 
-            padding: 12px; 
-            text-align: left; 
-        }
-        th { 
-            background-color: #4CAF50; 
-            color: white;
-        }
-        tr:nth-child(even) {
-            background-color: #f2f2f2;
-        }
-        h1 { 
-            color: #333; 
-            text-align: center;
-        }
-        h2 { 
-            color: #4CAF50; 
-            border-bottom: 2px solid #4CAF50;
-            padding-bottom: 10px;
-        }
-        .count {
-            color: #666;
-            font-size: 0.9em;
-        }
-    </style>
-</head>
-<body>
-    <div class="container">
-        <h1>Metrics Server Dashboard</h1>
-        
-        <h2>Gauges <span class="count">({{len .Gauges}})</span></h2>
-        <table>
-            <tr><th>Name</th><th>Value</th></tr>
-            {{range $name, $value := .Gauges}}
-            <tr><td><strong>{{$name}}</strong></td><td>{{printf "%.6f" $value}}</td></tr>
-            {{else}}
-            <tr><td colspan="2" style="text-align: center; color: #666;">No gauges available</td></tr>
-            {{end}}
-        </table>
-        
-        <h2>Counters <span class="count">({{len .Counters}})</span></h2>
-        <table>
-            <tr><th>Name</th><th>Value</th></tr>
-            {{range $name, $value := .Counters}}
-            <tr><td><strong>{{$name}}</strong></td><td>{{$value}}</td></tr>
-            {{else}}
-            <tr><td colspan="2" style="text-align: center; color: #666;">No counters available</td></tr>
-            {{end}}
-        </table>
-        
-        <div style="margin-top: 30px; padding: 15px; background-color: #e7f3ff; border-left: 4px solid #2196F3;">
-            <h3>API Endpoints:</h3>
-            <ul>
-                <li><code>POST /update/{type}/{name}/{value}- Update metric</code> </li>
-                <li><code>GET /value/{type}/{name} - Get metric value</code></li>
-				<li><code>POST /update - Update metric (JSON)</code></li>
-                <li><code>GET /value - Get metric value (JSON)</code></li>
-				<li><code>GET /ping - Ping DB</code></li>
-				<li><code>GET / - This dashboard</code></li>
-            </ul>
-        </div>
-    </div>
-</body>
-</html>`
-
-	t, err := template.New("metrics").Parse(tmpl)
+	m, err := h.metrics.Get(chi.URLParam(req, "type"), chi.URLParam(req, "name"))
 	if err != nil {
-		http.Error(res, "Template error", http.StatusInternalServerError)
-		log.Printf("Template parse error: %v", err)
+		serviceError(res, req, err)
 		return
 	}
 
-	data := struct {
-		Gauges   map[string]float64
-		Counters map[string]int64
-	}{
-		Gauges:   gaugesCopy,
-		Counters: countersCopy,
-	}
-
-	res.Header().Set("Content-Type", "text/html; charset=utf-8")
+	res.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	res.WriteHeader(http.StatusOK)
-
-	if err := t.Execute(res, data); err != nil {
-		log.Printf("Template execution error: %v", err)
+	if m.MType == models.Gauge {
+		fmt.Fprintf(res, "%g", *m.Value)
+	} else {
+		fmt.Fprintf(res, "%d", *m.Delta)
 	}
 }
 
 func (h *Handlers) updateMetricJSONHandler(res http.ResponseWriter, req *http.Request) {
-	if req.Header.Get("Content-Type") != "application/json" {
-		http.Error(res, "Content-Type must be application/json", http.StatusBadRequest)
-		return
-	}
-	body, err := io.ReadAll(req.Body)
-	if err != nil {
-		http.Error(res, "cannot read body", http.StatusInternalServerError)
+	if !isJSONRequest(req) {
+		problem.Write(res, req, http.StatusBadRequest, problem.CodeUnsupportedMediaType, "Content-Type must be application/json")
 		return
 	}
 	defer req.Body.Close()
 
 	var m models.Metrics
-	if err := json.Unmarshal(body, &m); err != nil {
-		http.Error(res, "invalid json", http.StatusBadRequest)
+	if err := json.NewDecoder(req.Body).Decode(&m); err != nil {
+		if bodyTooLarge(res, req, err) {
+			return
+		}
+		problem.Write(res, req, http.StatusBadRequest, problem.CodeInvalidJSON, "invalid json")
+		return
+	}
+	if errs := validateMetric(m, true); len(errs) > 0 {
+		problem.Write(res, req, http.StatusBadRequest, problem.CodeValidationFailed, "validation failed", errs...)
 		return
 	}
-	if m.ID == "" || (m.MType != "gauge" && m.MType != "counter") {
-		http.Error(res, "invalid metric id or type", http.StatusBadRequest)
+	if !h.validHash(m) {
+		problem.Write(res, req, http.StatusBadRequest, problem.CodeInvalidSignature, "invalid hash")
+		return
+	}
+	if !h.withinLimits(res, req, []models.Metrics{m}) || !h.admit(res, req, []models.Metrics{m}) {
 		return
 	}
 
-	switch m.MType {
-	case "gauge":
-		if m.Value == nil {
-			http.Error(res, "missing value for gauge", http.StatusBadRequest)
-			return
-		}
-		h.storage.UpdateGauge(m.ID, *m.Value)
-	case "counter":
-		if m.Delta == nil {
-			http.Error(res, "missing delta for counter", http.StatusBadRequest)
-			return
-		}
-		h.storage.UpdateCounter(m.ID, *m.Delta)
+	if _, err := h.metrics.Update(m); err != nil {
+		serviceError(res, req, err)
+		return
 	}
+	h.recordSeries(req, []models.Metrics{m})
 
 	res.Header().Set("Content-Type", "application/json")
 	res.WriteHeader(http.StatusOK)
@@ -293,89 +318,58 @@ func (h *Handlers) updateMetricJSONHandler(res http.ResponseWriter, req *http.Re
 }
 
 func (h *Handlers) UpdateMetricsBatch(res http.ResponseWriter, req *http.Request) {
-	if req.Header.Get("Content-Type") != "application/json" {
-		http.Error(res, "Content-Type must be application/json", http.StatusBadRequest)
+	if !isJSONRequest(req) {
+		problem.Write(res, req, http.StatusBadRequest, problem.CodeUnsupportedMediaType, "Content-Type must be application/json")
 		return
 	}
+	defer req.Body.Close()
 
-	// Получаем метрики из тела запроса
-	var metrics []models.Metrics
-	if err := json.NewDecoder(req.Body).Decode(&metrics); err != nil {
-		res.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(res).Encode(map[string]string{"error": "Invalid JSON format"})
+	// Получаем метрики из тела запроса, не разбирая пакет дальше лимита
+	metrics, err := decodeBatch(req.Body, h.cfg.MaxBatchSize)
+	if errors.Is(err, errBatchTooLarge) {
+		problem.Write(res, req, http.StatusRequestEntityTooLarge, problem.CodeLimitExceeded, fmt.Sprintf("batch exceeds %d metrics", h.cfg.MaxBatchSize))
+		return
+	}
+	if bodyTooLarge(res, req, err) {
+		return
+	}
+	if err != nil {
+		problem.Write(res, req, http.StatusBadRequest, problem.CodeInvalidJSON, "invalid json")
 		return
 	}
 
 	// Проверяем количество метрик на пустоту
 	if len(metrics) == 0 {
-		res.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(res).Encode(map[string]string{"error": "Empty batch"})
+		problem.Write(res, req, http.StatusBadRequest, problem.CodeEmptyBatch, "empty batch")
 		return
 	}
 
 	// Валидация метрик
 	var validationErrors []string
 	for i, metric := range metrics {
-		if metric.ID == "" {
-			validationErrors = append(validationErrors, fmt.Sprintf("metric[%d]: ID is required", i))
-			continue
+		for _, e := range validateMetric(metric, true) {
+			validationErrors = append(validationErrors, fmt.Sprintf("metric[%d]: %s", i, e))
 		}
-
-		switch metric.MType {
-		case models.Gauge:
-			if metric.Value == nil {
-				validationErrors = append(validationErrors, fmt.Sprintf("metric[%d]: gauge value is required", i))
-			}
-		case models.Counter:
-			if metric.Delta == nil {
-				validationErrors = append(validationErrors, fmt.Sprintf("metric[%d]: counter delta is required", i))
-			}
-		default:
-			validationErrors = append(validationErrors, fmt.Sprintf("metric[%d]: unknown metric type: %s", i, metric.MType))
+		if !h.validHash(metric) {
+			validationErrors = append(validationErrors, fmt.Sprintf("metric[%d]: invalid hash", i))
 		}
 	}
 	if len(validationErrors) > 0 {
-		res.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(res).Encode(map[string]any{
-			"error":   "validation failed",
-			"details": validationErrors,
-		})
+		problem.Write(res, req, http.StatusBadRequest, problem.CodeValidationFailed, "validation failed", validationErrors...)
 		return
 	}
-
-	// Удаляем дубликаты метрик и сразу суммируем counters
-	uniqueMetrics := []models.Metrics{}
-	uniqueKeys := make(map[string]int)
-	uniqueGaugeValues := make(map[string]float64)
-	uniqueCounterValues := make(map[string]int64)
-	for i, metric := range metrics {
-		uniqueKeys[metric.ID] = i
-		switch metric.MType {
-		case models.Gauge:
-			uniqueGaugeValues[metric.ID] = *metric.Value
-		case models.Counter:
-			uniqueCounterValues[metric.ID] += *metric.Delta
-		}
-	}
-	for i, metric := range metrics {
-		ui := uniqueKeys[metric.ID]
-		if ui == i {
-			switch metric.MType {
-			case models.Gauge:
-				*metric.Value = uniqueGaugeValues[metric.ID]
-			case models.Counter:
-				*metric.Delta = uniqueCounterValues[metric.ID]
-			}
-			uniqueMetrics = append(uniqueMetrics, metric)
-		}
+	if !h.withinLimits(res, req, metrics) || !h.admit(res, req, metrics) {
+		return
 	}
 
-	// Сохранение метрик
+	// Сохранение метрик. Хранилище само накапливает counters, в том числе повторяющиеся внутри пакета
 	ctx := context.Background()
-	err := h.storage.UpdateMetricsBatch(ctx, uniqueMetrics)
-	if err != nil {
+	if err = h.metrics.UpdateBatch(ctx, metrics); err != nil {
 		log.Printf("Failed to update mectrics after retries: %v", err)
+		serviceError(res, req, err)
+		return
 	}
+	h.recordSeries(req, metrics)
 
 	// Ответ
 	res.Header().Set("Content-Type", "application/json")
@@ -384,48 +378,47 @@ func (h *Handlers) UpdateMetricsBatch(res http.ResponseWriter, req *http.Request
 }
 
 func (h *Handlers) valueMetricJSONHandler(res http.ResponseWriter, req *http.Request) {
-	if req.Header.Get("Content-Type") != "application/json" {
-		http.Error(res, "Content-Type must be application/json", http.StatusBadRequest)
-		return
-	}
-	body, err := io.ReadAll(req.Body)
-	if err != nil {
-		http.Error(res, "cannot read body", http.StatusInternalServerError)
+	if !isJSONRequest(req) {
+		problem.Write(res, req, http.StatusBadRequest, problem.CodeUnsupportedMediaType, "Content-Type must be application/json")
 		return
 	}
 	defer req.Body.Close()
 
 	var m models.Metrics
-	if err := json.Unmarshal(body, &m); err != nil {
-		http.Error(res, "invalid json", http.StatusBadRequest)
+	if err := json.NewDecoder(req.Body).Decode(&m); err != nil {
+		if bodyTooLarge(res, req, err) {
+			return
+		}
+		problem.Write(res, req, http.StatusBadRequest, problem.CodeInvalidJSON, "invalid json")
 		return
 	}
-
-	if m.ID == "" || (m.MType != "gauge" && m.MType != "counter") {
-		http.Error(res, "invalid metric id or type", http.StatusBadRequest)
+	if errs := validateMetric(m, false); len(errs) > 0 {
+		problem.Write(res, req, http.StatusBadRequest, problem.CodeValidationFailed, "validation failed", errs...)
 		return
 	}
 
-	resp := models.Metrics{ID: m.ID, MType: m.MType}
-
-	switch m.MType {
-	case "gauge":
-		value, err := h.storage.GetGauge(m.ID)
-		if errors.Is(err, storage.ErrMetricNotFound) {
-			value = 0
-		}
-		resp.Value = &value
-	case "counter":
-		value, err := h.storage.GetCounter(m.ID)
-		if errors.Is(err, storage.ErrMetricNotFound) {
-			value = 0
+	// Прежний контракт: отсутствующая метрика возвращается с нулевым значением
+	resp, err := h.metrics.Get(m.MType, m.ID)
+	if errors.Is(err, storage.ErrMetricNotFound) {
+		resp = models.Metrics{ID: m.ID, MType: m.MType}
+		var value float64
+		var delta int64
+		if m.MType == models.Gauge {
+			resp.Value = &value
+		} else {
+			resp.Delta = &delta
 		}
-		resp.Delta = &value
+	} else if err != nil {
+		serviceError(res, req, err)
+		return
+	}
+	if h.cfg.Key != "" {
+		resp.Hash = sign.MetricHash(resp, h.cfg.Key)
 	}
 
 	jsonResp, err := json.Marshal(resp)
 	if err != nil {
-		http.Error(res, "failed to marshal response", http.StatusInternalServerError)
+		problem.Write(res, req, http.StatusInternalServerError, problem.CodeInternal, "failed to marshal response")
 		return
 	}
 
@@ -435,17 +428,70 @@ func (h *Handlers) valueMetricJSONHandler(res http.ResponseWriter, req *http.Req
 }
 
 func (h *Handlers) pingHandler(res http.ResponseWriter, req *http.Request) {
-	res.Header().Set("Content-Type", "text/html")
-
 	if h.db == nil {
-		http.Error(res, "БД недоступна!", http.StatusInternalServerError)
+		problem.Write(res, req, http.StatusInternalServerError, problem.CodeStorageUnavailable, "database is not configured")
 		return
 	}
 
 	if err := h.db.Ping(); err != nil {
-		http.Error(res, err.Error(), http.StatusInternalServerError)
+		problem.Write(res, req, http.StatusInternalServerError, problem.CodeStorageUnavailable, err.Error())
 		return
 	}
 
+	res.Header().Set("Content-Type", "text/html")
+	res.WriteHeader(http.StatusOK)
+}
+
+func (h *Handlers) openAPIHandler(res http.ResponseWriter, req *http.Request) {
+	res.Header().Set("Content-Type", "application/json")
 	res.WriteHeader(http.StatusOK)
+	res.Write(api.OpenAPISpec)
+}
+
+// validateMetric — общие проверки метрики для JSON-обработчиков;
+// withValue требует наличия значения, соответствующего типу
+func validateMetric(m models.Metrics, withValue bool) []string {
+	var errs []string
+	if m.ID == "" {
+		errs = append(errs, "id is required")
+	}
+
+	switch m.MType {
+	case models.Gauge:
+		if withValue && m.Value == nil {
+			errs = append(errs, "value is required for gauge")
+		}
+	case models.Counter:
+		if withValue && m.Delta == nil {
+			errs = append(errs, "delta is required for counter")
+		}
+	default:
+		errs = append(errs, fmt.Sprintf("unknown metric type: %q", m.MType))
+	}
+
+	return errs
+}
+
+// validHash проверяет подпись метрики, если она передана и на сервере задан ключ
+func (h *Handlers) validHash(m models.Metrics) bool {
+	if h.cfg.Key == "" || m.Hash == "" {
+		return true
+	}
+	return sign.ValidMetric(m, h.cfg.Key)
+}
+
+func isJSONRequest(req *http.Request) bool {
+	contentType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	return contentType == "application/json"
+}
+
+// splitList разбирает список конфигурации через запятую, пропуская пустые элементы
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
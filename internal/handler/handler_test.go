@@ -0,0 +1,89 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/akorablin/yandex-practicum-metrics/api"
+	"github.com/akorablin/yandex-practicum-metrics/internal/config"
+	"github.com/akorablin/yandex-practicum-metrics/internal/handler"
+	"github.com/akorablin/yandex-practicum-metrics/internal/problem"
+	"github.com/akorablin/yandex-practicum-metrics/internal/repository/memory"
+	"github.com/go-chi/chi"
+	"go.uber.org/zap"
+)
+
+func newRouter() http.Handler {
+	cfg := &config.ServerConfig{InfluxIntegerType: "counter", InfluxFloatType: "gauge"}
+	return handler.NewHandlers(cfg, memory.New(cfg), nil, zap.NewNop()).GetRoutes()
+}
+
+func TestOpenAPICoversAllRoutes(t *testing.T) {
+	var spec struct {
+		Paths map[string]map[string]any `json:"paths"`
+	}
+	if err := json.Unmarshal(api.OpenAPISpec, &spec); err != nil {
+		t.Fatalf("Failed to parse spec: %v", err)
+	}
+
+	routes, ok := newRouter().(chi.Routes)
+	if !ok {
+		t.Fatal("GetRoutes() did not return chi.Routes")
+	}
+
+	err := chi.Walk(routes, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if _, ok := spec.Paths[route][strings.ToLower(method)]; !ok {
+			t.Errorf("Route %s %s is missing in openapi.json", method, route)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("chi.Walk() failed: %v", err)
+	}
+}
+
+func TestRequestValidation(t *testing.T) {
+	router := newRouter()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{"valid gauge", http.MethodPost, "/update/", `{"id":"Alloc","type":"gauge","value":1.5}`, http.StatusOK},
+		{"gauge without value", http.MethodPost, "/update/", `{"id":"Alloc","type":"gauge","delta":1}`, http.StatusBadRequest},
+		{"unknown type", http.MethodPost, "/update/", `{"id":"Alloc","type":"histogram","value":1}`, http.StatusBadRequest},
+		{"empty batch", http.MethodPost, "/updates/", `[]`, http.StatusBadRequest},
+		{"valid batch", http.MethodPost, "/updates/", `[{"id":"PollCount","type":"counter","delta":1}]`, http.StatusOK},
+		{"value without id", http.MethodPost, "/value/", `{"type":"counter"}`, http.StatusBadRequest},
+		{"unknown type in path", http.MethodPost, "/update/histogram/Alloc/1", ``, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+
+			// Ошибки валидации возвращаются в едином формате problem+json
+			if rec.Code == http.StatusBadRequest {
+				p, ok := problem.Parse(rec.Header().Get("Content-Type"), rec.Body.Bytes())
+				if !ok || p.Status != rec.Code || p.Title == "" {
+					t.Errorf("Expected problem+json error, got %q", rec.Body.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/health"
+)
+
+// readyTimeout ограничивает время всех проверок /readyz
+const readyTimeout = 2 * time.Second
+
+// WithHealth подключает проверки зависимостей и фоновых задач к /readyz
+func WithHealth(registry *health.Registry) Option {
+	return func(h *Handlers) {
+		h.health = registry
+	}
+}
+
+// healthzHandler сообщает, что процесс жив (GET /healthz)
+func (h *Handlers) healthzHandler(res http.ResponseWriter, req *http.Request) {
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(http.StatusOK)
+	json.NewEncoder(res).Encode(map[string]string{"status": health.StatusUp})
+}
+
+// readyzHandler проверяет хранилище, снимок и фоновые задачи (GET /readyz)
+func (h *Handlers) readyzHandler(res http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithTimeout(req.Context(), readyTimeout)
+	defer cancel()
+
+	report := h.health.Check(ctx)
+	status := http.StatusOK
+	if report.Status != health.StatusUp {
+		status = http.StatusServiceUnavailable
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(status)
+	json.NewEncoder(res).Encode(report)
+}
+
+// checkStorage проверяет активное хранилище: Postgres пингуется, память всегда готова
+func (h *Handlers) checkStorage(ctx context.Context) error {
+	if h.db == nil {
+		return nil
+	}
+	return h.db.PingContext(ctx)
+}
@@ -0,0 +1,63 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/config"
+	"github.com/akorablin/yandex-practicum-metrics/internal/handler"
+	"github.com/akorablin/yandex-practicum-metrics/internal/health"
+	"github.com/akorablin/yandex-practicum-metrics/internal/repository/memory"
+	"go.uber.org/zap"
+)
+
+func TestHealthProbes(t *testing.T) {
+	cfg := &config.ServerConfig{}
+	checks := health.New()
+	router := handler.NewHandlers(cfg, memory.New(cfg), nil, zap.NewNop(), handler.WithHealth(checks)).GetRoutes()
+
+	get := func(path string) (int, health.Report) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Unexpected Content-Type %q", ct)
+		}
+		var report health.Report
+		if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+			t.Fatalf("Invalid JSON %q: %v", rec.Body.String(), err)
+		}
+		return rec.Code, report
+	}
+
+	t.Run("Процесс жив", func(t *testing.T) {
+		code, report := get("/healthz")
+		if code != http.StatusOK || report.Status != health.StatusUp {
+			t.Errorf("Unexpected /healthz: %d %+v", code, report)
+		}
+	})
+
+	t.Run("Хранилище в памяти готово", func(t *testing.T) {
+		code, report := get("/readyz")
+		if code != http.StatusOK || report.Status != health.StatusUp {
+			t.Errorf("Unexpected /readyz: %d %+v", code, report)
+		}
+		if c, ok := report.Components["storage"]; !ok || c.Status != health.StatusUp {
+			t.Errorf("Unexpected storage component: %+v", report.Components)
+		}
+	})
+
+	t.Run("Отказ компонента", func(t *testing.T) {
+		checks.Register("file", func(context.Context) error { return errors.New("permission denied") })
+		code, report := get("/readyz")
+		if code != http.StatusServiceUnavailable || report.Status != health.StatusDown {
+			t.Errorf("Unexpected /readyz: %d %+v", code, report)
+		}
+		if c := report.Components["file"]; c.Status != health.StatusDown || c.Error != "permission denied" {
+			t.Errorf("Unexpected file component: %+v", c)
+		}
+	})
+}
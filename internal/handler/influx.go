@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/influx"
+	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
+	"github.com/akorablin/yandex-practicum-metrics/internal/problem"
+	"github.com/akorablin/yandex-practicum-metrics/internal/storage"
+)
+
+// influxWriteHandler принимает метрики в формате InfluxDB line protocol (POST /write)
+func (h *Handlers) influxWriteHandler(res http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if bodyTooLarge(res, req, err) {
+		return
+	}
+	if err != nil {
+		problem.Write(res, req, http.StatusBadRequest, problem.CodeInvalidRequest, "cannot read body")
+		return
+	}
+	defer req.Body.Close()
+
+	points, err := influx.Parse(body)
+	if err != nil {
+		problem.Write(res, req, http.StatusBadRequest, problem.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	metrics := influx.ToMetrics(points, h.cfg.InfluxIntegerType, h.cfg.InfluxFloatType)
+	if !h.withinLimits(res, req, metrics) || !h.admit(res, req, metrics) {
+		return
+	}
+	if err := h.storeMetrics(req.Context(), metrics); err != nil {
+		if errors.Is(err, storage.ErrTypeMismatch) {
+			problem.Write(res, req, http.StatusConflict, problem.CodeTypeMismatch, err.Error())
+			return
+		}
+		problem.Write(res, req, http.StatusServiceUnavailable, problem.CodeStorageUnavailable, "failed to store metrics")
+		return
+	}
+	h.recordSeries(req, metrics)
+
+	res.WriteHeader(http.StatusNoContent)
+}
+
+// storeMetrics сохраняет метрики одним атомарным пакетом: при ошибке не сохраняется ничего,
+// поэтому повтор клиента не учтёт counters дважды
+func (h *Handlers) storeMetrics(ctx context.Context, metrics []models.Metrics) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+	if err := h.metrics.UpdateBatch(ctx, metrics); err != nil {
+		log.Printf("Failed to store %d metrics: %v", len(metrics), err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package handler_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/config"
+	"github.com/akorablin/yandex-practicum-metrics/internal/handler"
+	"github.com/akorablin/yandex-practicum-metrics/internal/repository/memory"
+	"github.com/akorablin/yandex-practicum-metrics/internal/storage"
+	"go.uber.org/zap"
+)
+
+func TestInfluxWrite(t *testing.T) {
+	cfg := &config.ServerConfig{InfluxIntegerType: "gauge", InfluxFloatType: "gauge", TypeConflictPolicy: config.TypeConflictReject}
+	repo := memory.New(cfg)
+	router := handler.NewHandlers(cfg, repo, nil, zap.NewNop()).GetRoutes()
+	repo.UpdateCounter("net_errors;host=a", 1)
+
+	write := func(body string) int {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/write", strings.NewReader(body)))
+		return rec.Code
+	}
+
+	// Накопленный итог Telegraf не суммируется между записями
+	for range 2 {
+		if code := write("net,host=a bytes_recv=100i"); code != http.StatusNoContent {
+			t.Fatalf("Expected 204, got %d", code)
+		}
+	}
+	if value, err := repo.GetGauge("net_bytes_recv;host=a"); err != nil || value != 100 {
+		t.Errorf("Expected bytes_recv = 100, got %v (%v)", value, err)
+	}
+
+	// Конфликт типов во втором поле отклоняет запись целиком
+	if code := write("net,host=a bytes_sent=5i,errors=1i"); code != http.StatusConflict {
+		t.Fatalf("Expected 409, got %d", code)
+	}
+	if _, err := repo.GetGauge("net_bytes_sent;host=a"); !errors.Is(err, storage.ErrMetricNotFound) {
+		t.Errorf("Expected no partial write, got %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/middleware"
+	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
+	"github.com/akorablin/yandex-practicum-metrics/internal/problem"
+)
+
+var errBatchTooLarge = errors.New("batch too large")
+
+// bodyTooLarge отвечает 413, если чтение тела прервано лимитом размера
+func bodyTooLarge(res http.ResponseWriter, req *http.Request, err error) bool {
+	if !middleware.IsBodyTooLarge(err) {
+		return false
+	}
+	problem.Write(res, req, http.StatusRequestEntityTooLarge, problem.CodeBodyTooLarge, "request body too large")
+	return true
+}
+
+// decodeBatch читает JSON-массив метрик поэлементно и прекращает разбор,
+// как только метрик становится больше max (0 — без ограничения)
+func decodeBatch(r io.Reader, max int) ([]models.Metrics, error) {
+	dec := json.NewDecoder(r)
+	if tok, err := dec.Token(); err != nil {
+		return nil, err
+	} else if tok != json.Delim('[') {
+		return nil, fmt.Errorf("expected json array")
+	}
+
+	var metrics []models.Metrics
+	for dec.More() {
+		if max > 0 && len(metrics) == max {
+			return nil, errBatchTooLarge
+		}
+		var m models.Metrics
+		if err := dec.Decode(&m); err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return metrics, nil
+}
+
+// withinLimits проверяет число метрик и длину ID и отвечает 413 при превышении
+func (h *Handlers) withinLimits(res http.ResponseWriter, req *http.Request, metrics []models.Metrics) bool {
+	var details []string
+	if h.cfg.MaxBatchSize > 0 && len(metrics) > h.cfg.MaxBatchSize {
+		details = append(details, fmt.Sprintf("batch exceeds %d metrics", h.cfg.MaxBatchSize))
+	}
+	if h.cfg.MaxIDLength > 0 {
+		for i, m := range metrics {
+			if len(m.ID) > h.cfg.MaxIDLength {
+				details = append(details, fmt.Sprintf("metric[%d]: id exceeds %d bytes", i, h.cfg.MaxIDLength))
+			}
+		}
+	}
+	if len(details) == 0 {
+		return true
+	}
+
+	problem.Write(res, req, http.StatusRequestEntityTooLarge, problem.CodeLimitExceeded, details[0], details...)
+	return false
+}
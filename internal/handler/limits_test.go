@@ -0,0 +1,81 @@
+package handler_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/config"
+	"github.com/akorablin/yandex-practicum-metrics/internal/handler"
+	"github.com/akorablin/yandex-practicum-metrics/internal/repository/memory"
+	"go.uber.org/zap"
+)
+
+func gzipBody(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestBodyLimits(t *testing.T) {
+	cfg := &config.ServerConfig{
+		MaxBodySize:         1024,
+		MaxDecompressedSize: 4096,
+		MaxBatchSize:        2,
+		MaxIDLength:         16,
+	}
+	router := handler.NewHandlers(cfg, memory.New(cfg), nil, zap.NewNop()).GetRoutes()
+
+	// Пробелы сжимаются почти в ноль, но после распаковки превышают лимит
+	bomb := gzipBody(t, []byte(`[{"id":"A","type":"gauge","value":1}`+strings.Repeat(" ", 1<<20)+`]`))
+
+	tests := []struct {
+		name   string
+		path   string
+		body   []byte
+		gzip   bool
+		status int
+	}{
+		{"Обычный запрос", "/update/", []byte(`{"id":"Alloc","type":"gauge","value":1}`), false, http.StatusOK},
+		{"Сжатый запрос в пределах лимита", "/updates/", gzipBody(t, []byte(`[{"id":"Alloc","type":"gauge","value":1}]`)), true, http.StatusOK},
+		{"Сжатое тело больше лимита", "/update/", bytes.Repeat([]byte(" "), 2048), false, http.StatusRequestEntityTooLarge},
+		{"Распакованное тело больше лимита", "/updates/", bomb, true, http.StatusRequestEntityTooLarge},
+		{"Пакет больше лимита", "/updates/", []byte(`[{"id":"A","type":"gauge","value":1},{"id":"B","type":"gauge","value":1},{"id":"C","type":"gauge","value":1}]`), false, http.StatusRequestEntityTooLarge},
+		{"Пакет больше лимита не дочитывается до конца", "/updates/", []byte(`[{"id":"A","type":"gauge","value":1},{"id":"B","type":"gauge","value":1},{"id":"C","type":"gauge","value":1},` + strings.Repeat("x", 512)), false, http.StatusRequestEntityTooLarge},
+		{"Слишком длинный ID", "/update/", []byte(`{"id":"` + strings.Repeat("a", 17) + `","type":"gauge","value":1}`), false, http.StatusRequestEntityTooLarge},
+		{"Слишком длинный ID в URL", "/update/gauge/" + strings.Repeat("a", 17) + "/1", nil, false, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body io.Reader = http.NoBody
+			if tt.body != nil {
+				body = bytes.NewReader(tt.body)
+			}
+			req := httptest.NewRequest(http.MethodPost, tt.path, body)
+			if tt.body != nil {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			if tt.gzip {
+				req.Header.Set("Content-Encoding", "gzip")
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+
+	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
+	"github.com/akorablin/yandex-practicum-metrics/internal/problem"
+	"github.com/akorablin/yandex-practicum-metrics/internal/storage"
+	"github.com/go-chi/chi"
+)
+
+type promMetadata struct {
+	Type string `json:"type"`
+	Help string `json:"help"`
+	Unit string `json:"unit"`
+}
+
+// putMetaHandler регистрирует метаданные метрики (PUT /meta/{name})
+func (h *Handlers) putMetaHandler(res http.ResponseWriter, req *http.Request) {
+	if !isJSONRequest(req) {
+		problem.Write(res, req, http.StatusBadRequest, problem.CodeUnsupportedMediaType, "Content-Type must be application/json")
+		return
+	}
+	defer req.Body.Close()
+
+	name := chi.URLParam(req, "name")
+	var meta models.Metadata
+	if err := json.NewDecoder(req.Body).Decode(&meta); err != nil {
+		if bodyTooLarge(res, req, err) {
+			return
+		}
+		problem.Write(res, req, http.StatusBadRequest, problem.CodeInvalidJSON, "invalid json")
+		return
+	}
+	if errs := validateMetadata(name, meta); len(errs) > 0 {
+		problem.Write(res, req, http.StatusBadRequest, problem.CodeValidationFailed, "validation failed", errs...)
+		return
+	}
+	meta.Name = name
+
+	if err := h.storage.SetMetadata(meta); err != nil {
+		problem.Write(res, req, http.StatusInternalServerError, problem.CodeStorageUnavailable, "failed to store metadata")
+		return
+	}
+	writeJSON(res, meta)
+}
+
+// getMetaHandler возвращает метаданные метрики (GET /meta/{name})
+func (h *Handlers) getMetaHandler(res http.ResponseWriter, req *http.Request) {
+	meta, err := h.storage.GetMetadata(chi.URLParam(req, "name"))
+	if errors.Is(err, storage.ErrMetadataNotFound) {
+		problem.Write(res, req, http.StatusNotFound, problem.CodeNotFound, "metadata not found")
+		return
+	}
+	if err != nil {
+		problem.Write(res, req, http.StatusInternalServerError, problem.CodeStorageUnavailable, "failed to get metadata")
+		return
+	}
+	writeJSON(res, meta)
+}
+
+// listMetaHandler возвращает все зарегистрированные метаданные (GET /meta)
+func (h *Handlers) listMetaHandler(res http.ResponseWriter, req *http.Request) {
+	all := h.storage.GetAllMetadata()
+	list := make([]models.Metadata, 0, len(all))
+	for _, meta := range all {
+		list = append(list, meta)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	writeJSON(res, list)
+}
+
+// promMetadataHandler отдаёт метаданные в формате Prometheus (GET /api/v1/metadata).
+// Тип берётся из хранилища, описание и единица — из зарегистрированных метаданных
+func (h *Handlers) promMetadataHandler(res http.ResponseWriter, req *http.Request) {
+	filter := req.URL.Query().Get("metric")
+	registered := h.storage.GetAllMetadata()
+	gauges, counters := h.storage.GetAllMetrics()
+
+	data := make(map[string][]promMetadata)
+	add := func(name, mtype string) {
+		if filter != "" && name != filter {
+			return
+		}
+		for _, m := range data[name] {
+			if m.Type == mtype {
+				return
+			}
+		}
+		meta := registered[name]
+		data[name] = append(data[name], promMetadata{Type: mtype, Help: meta.Description, Unit: meta.Unit})
+	}
+	for id := range gauges {
+		name, _ := models.ParseLabels(id)
+		add(name, models.Gauge)
+	}
+	for id := range counters {
+		name, _ := models.ParseLabels(id)
+		add(name, models.Counter)
+	}
+	// Метаданные можно зарегистрировать до появления первых значений
+	for name, meta := range registered {
+		if _, ok := data[name]; !ok {
+			mtype := meta.Type
+			if mtype == "" {
+				mtype = "unknown"
+			}
+			add(name, mtype)
+		}
+	}
+	writePromData(res, data)
+}
+
+func validateMetadata(name string, meta models.Metadata) []string {
+	var errs []string
+	if strings.Contains(name, ";") {
+		errs = append(errs, "name: metadata is registered for a metric name without labels")
+	}
+	if meta.Name != "" && meta.Name != name {
+		errs = append(errs, "name: must match the name in the path")
+	}
+	if meta.Type != "" && meta.Type != models.Gauge && meta.Type != models.Counter {
+		errs = append(errs, "type: must be gauge or counter")
+	}
+	return errs
+}
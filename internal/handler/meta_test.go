@@ -0,0 +1,117 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/config"
+	"github.com/akorablin/yandex-practicum-metrics/internal/handler"
+	"github.com/akorablin/yandex-practicum-metrics/internal/repository/memory"
+	"github.com/akorablin/yandex-practicum-metrics/internal/storage"
+	"go.uber.org/zap"
+)
+
+func TestMetadata(t *testing.T) {
+	cfg := &config.ServerConfig{}
+	repo := storage.NewTypeCheckingStorage(memory.New(cfg))
+	router := handler.NewHandlers(cfg, repo, nil, zap.NewNop()).GetRoutes()
+
+	// Шаги выполняются по порядку: регистрация метаданных влияет на последующие обновления
+	steps := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        string
+		status      int
+		want        string
+	}{
+		{
+			name:   "Метаданные ещё не зарегистрированы",
+			method: http.MethodGet, path: "/meta/HeapAlloc",
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "Регистрация",
+			method: http.MethodPut, path: "/meta/HeapAlloc", contentType: "application/json",
+			body:   `{"description":"Bytes of allocated heap objects","unit":"bytes","owner":"runtime","type":"gauge"}`,
+			status: http.StatusOK,
+			want:   `{"name":"HeapAlloc","description":"Bytes of allocated heap objects","unit":"bytes","owner":"runtime","type":"gauge"}`,
+		},
+		{
+			name:   "Чтение",
+			method: http.MethodGet, path: "/meta/HeapAlloc",
+			status: http.StatusOK,
+			want:   `{"name":"HeapAlloc","description":"Bytes of allocated heap objects","unit":"bytes","owner":"runtime","type":"gauge"}`,
+		},
+		{
+			name:   "Неизвестный тип",
+			method: http.MethodPut, path: "/meta/PollCount", contentType: "application/json",
+			body:   `{"type":"histogram"}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Имя с метками",
+			method: http.MethodPut, path: "/meta/requests;code=200", contentType: "application/json",
+			body:   `{"unit":"requests"}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Обновление с верным типом",
+			method: http.MethodPost, path: "/update/gauge/HeapAlloc/1024",
+			status: http.StatusOK,
+		},
+		{
+			name:   "Обновление с противоречащим типом",
+			method: http.MethodPost, path: "/update/counter/HeapAlloc/1",
+			status: http.StatusConflict,
+		},
+		{
+			name:   "Противоречащий тип у метрики с метками",
+			method: http.MethodPost, path: "/update/", contentType: "application/json",
+			body:   `{"id":"HeapAlloc;pool=a","type":"counter","delta":1}`,
+			status: http.StatusConflict,
+		},
+		{
+			name:   "Пакет отклоняется целиком",
+			method: http.MethodPost, path: "/updates/", contentType: "application/json",
+			body:   `[{"id":"PollCount","type":"counter","delta":1},{"id":"HeapAlloc","type":"counter","delta":1}]`,
+			status: http.StatusConflict,
+		},
+		{
+			name:   "Метаданные в формате Prometheus",
+			method: http.MethodGet, path: "/api/v1/metadata",
+			status: http.StatusOK,
+			want:   `{"status":"success","data":{"HeapAlloc":[{"type":"gauge","help":"Bytes of allocated heap objects","unit":"bytes"}]}}`,
+		},
+	}
+
+	for _, step := range steps {
+		var req *http.Request
+		if step.body != "" {
+			req = httptest.NewRequest(step.method, step.path, strings.NewReader(step.body))
+		} else {
+			req = httptest.NewRequest(step.method, step.path, nil)
+		}
+		if step.contentType != "" {
+			req.Header.Set("Content-Type", step.contentType)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != step.status {
+			t.Fatalf("%s: expected status %d, got %d: %s", step.name, step.status, rec.Code, rec.Body.String())
+		}
+		if got := strings.TrimSpace(rec.Body.String()); step.want != "" && got != step.want {
+			t.Errorf("%s: unexpected body:\n got %s\nwant %s", step.name, got, step.want)
+		}
+	}
+
+	// Отклонённые обновления не должны попасть в хранилище
+	gauges, counters := repo.GetAllMetrics()
+	if len(gauges) != 1 || len(counters) != 0 {
+		t.Errorf("Unexpected metrics after rejected updates: %v %v", gauges, counters)
+	}
+}
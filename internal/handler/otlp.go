@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/problem"
+	"github.com/akorablin/yandex-practicum-metrics/internal/storage"
+
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	contentTypeProtobuf = "application/x-protobuf"
+	contentTypeJSON     = "application/json"
+)
+
+// otlpMetricsHandler принимает метрики по протоколу OTLP/HTTP (POST /v1/metrics)
+// в кодировке protobuf или JSON; ответ возвращается в той же кодировке
+func (h *Handlers) otlpMetricsHandler(res http.ResponseWriter, req *http.Request) {
+	contentType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if contentType != contentTypeProtobuf && contentType != contentTypeJSON {
+		problem.Write(res, req, http.StatusUnsupportedMediaType, problem.CodeUnsupportedMediaType, "Content-Type must be application/x-protobuf or application/json")
+		return
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if bodyTooLarge(res, req, err) {
+		return
+	}
+	if err != nil {
+		problem.Write(res, req, http.StatusBadRequest, problem.CodeInvalidRequest, "cannot read body")
+		return
+	}
+	defer req.Body.Close()
+
+	var request colmetricspb.ExportMetricsServiceRequest
+	if contentType == contentTypeProtobuf {
+		err = proto.Unmarshal(body, &request)
+	} else {
+		err = protojson.Unmarshal(body, &request)
+	}
+	if err != nil {
+		problem.Write(res, req, http.StatusBadRequest, problem.CodeInvalidRequest, "invalid otlp payload")
+		return
+	}
+
+	metrics, pending := h.otlp.Convert(&request)
+	if !h.withinLimits(res, req, metrics) || !h.admit(res, req, metrics) {
+		return
+	}
+	if err := h.storeMetrics(req.Context(), metrics); err != nil {
+		if errors.Is(err, storage.ErrTypeMismatch) {
+			problem.Write(res, req, http.StatusConflict, problem.CodeTypeMismatch, err.Error())
+			return
+		}
+		problem.Write(res, req, http.StatusServiceUnavailable, problem.CodeStorageUnavailable, "failed to store metrics")
+		return
+	}
+	h.recordSeries(req, metrics)
+	h.otlp.Commit(pending)
+
+	// Формируем ответ
+	var response []byte
+	if contentType == contentTypeProtobuf {
+		response, err = proto.Marshal(&colmetricspb.ExportMetricsServiceResponse{})
+	} else {
+		response, err = protojson.Marshal(&colmetricspb.ExportMetricsServiceResponse{})
+	}
+	if err != nil {
+		problem.Write(res, req, http.StatusInternalServerError, problem.CodeInternal, "failed to marshal response")
+		return
+	}
+
+	res.Header().Set("Content-Type", contentType)
+	res.WriteHeader(http.StatusOK)
+	res.Write(response)
+}
@@ -0,0 +1,60 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/config"
+	"github.com/akorablin/yandex-practicum-metrics/internal/handler"
+	"github.com/akorablin/yandex-practicum-metrics/internal/problem"
+	"github.com/akorablin/yandex-practicum-metrics/internal/repository/memory"
+	"go.uber.org/zap"
+)
+
+func TestProblemResponses(t *testing.T) {
+	cfg := &config.ServerConfig{Key: "secret", MaxBodySize: 64}
+	router := handler.NewHandlers(cfg, memory.New(cfg), nil, zap.NewNop()).GetRoutes()
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		body    string
+		headers map[string]string
+		status  int
+		code    string
+	}{
+		{"Неизвестный маршрут", http.MethodGet, "/unknown", "", nil, http.StatusNotFound, problem.CodeNotFound},
+		{"Неподдерживаемый метод", http.MethodDelete, "/ping", "", nil, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed},
+		{"Метрика не найдена", http.MethodGet, "/value/gauge/Missing", "", nil, http.StatusNotFound, problem.CodeNotFound},
+		{"Нет подписи", http.MethodPost, "/update/gauge/Alloc/1", "", nil, http.StatusBadRequest, problem.CodeMissingSignature},
+		{"Неизвестное сжатие", http.MethodPost, "/updates/", "[]", map[string]string{"Content-Type": "application/json", "Content-Encoding": "lz4"}, http.StatusUnsupportedMediaType, problem.CodeUnsupportedEncoding},
+		{"Слишком большое тело", http.MethodPost, "/updates/", strings.Repeat(" ", 100), map[string]string{"Content-Type": "application/json"}, http.StatusRequestEntityTooLarge, problem.CodeBodyTooLarge},
+		{"Поток отключён", http.MethodGet, "/stream", "", nil, http.StatusServiceUnavailable, problem.CodeFeatureDisabled},
+		{"Нет базы данных", http.MethodGet, "/ping", "", nil, http.StatusInternalServerError, problem.CodeStorageUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+			p, ok := problem.Parse(rec.Header().Get("Content-Type"), rec.Body.Bytes())
+			if !ok {
+				t.Fatalf("Expected problem+json, got %q (%s)", rec.Body.String(), rec.Header().Get("Content-Type"))
+			}
+			if p.Code != tt.code || p.Status != tt.status || p.Type != problem.TypePrefix+tt.code || p.Instance != tt.path {
+				t.Errorf("Unexpected problem: %+v", p)
+			}
+		})
+	}
+}
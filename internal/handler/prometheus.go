@@ -0,0 +1,236 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
+	"github.com/akorablin/yandex-practicum-metrics/internal/promql"
+	"github.com/go-chi/chi"
+)
+
+// Ответы повторяют формат HTTP API Prometheus, чтобы сервер можно было подключить
+// к Grafana как источник данных Prometheus
+type promResponse struct {
+	Status    string `json:"status"`
+	Data      any    `json:"data,omitempty"`
+	ErrorType string `json:"errorType,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+type promQueryData struct {
+	ResultType promql.ValueType `json:"resultType"`
+	Result     any              `json:"result"`
+}
+
+type promSample struct {
+	Metric promql.Labels `json:"metric"`
+	Value  [2]any        `json:"value"`
+}
+
+type promSeries struct {
+	Metric promql.Labels `json:"metric"`
+	Values [][2]any      `json:"values"`
+}
+
+// promQueryHandler вычисляет выражение в один момент времени (GET|POST /api/v1/query)
+func (h *Handlers) promQueryHandler(res http.ResponseWriter, req *http.Request) {
+	if h.promql == nil {
+		writePromError(res, http.StatusServiceUnavailable, "unavailable", "history is disabled")
+		return
+	}
+
+	query := req.FormValue("query")
+	if query == "" {
+		writePromError(res, http.StatusBadRequest, "bad_data", "query parameter is required")
+		return
+	}
+	ts := time.Now()
+	if s := req.FormValue("time"); s != "" {
+		t, err := parsePromTime(s)
+		if err != nil {
+			writePromError(res, http.StatusBadRequest, "bad_data", "invalid time: "+err.Error())
+			return
+		}
+		ts = t
+	}
+
+	value, err := h.promql.Instant(query, ts)
+	if err != nil {
+		writePromQueryError(res, err)
+		return
+	}
+	writePromData(res, promQueryData{ResultType: value.Type(), Result: promResult(value)})
+}
+
+// promQueryRangeHandler вычисляет выражение на интервале с шагом (GET|POST /api/v1/query_range)
+func (h *Handlers) promQueryRangeHandler(res http.ResponseWriter, req *http.Request) {
+	if h.promql == nil {
+		writePromError(res, http.StatusServiceUnavailable, "unavailable", "history is disabled")
+		return
+	}
+
+	query := req.FormValue("query")
+	if query == "" {
+		writePromError(res, http.StatusBadRequest, "bad_data", "query parameter is required")
+		return
+	}
+	start, err := parsePromTime(req.FormValue("start"))
+	if err != nil {
+		writePromError(res, http.StatusBadRequest, "bad_data", "invalid start: "+err.Error())
+		return
+	}
+	end, err := parsePromTime(req.FormValue("end"))
+	if err != nil {
+		writePromError(res, http.StatusBadRequest, "bad_data", "invalid end: "+err.Error())
+		return
+	}
+	step, err := parsePromDuration(req.FormValue("step"))
+	if err != nil {
+		writePromError(res, http.StatusBadRequest, "bad_data", "invalid step: "+err.Error())
+		return
+	}
+
+	matrix, err := h.promql.Range(query, start, end, step)
+	if err != nil {
+		writePromQueryError(res, err)
+		return
+	}
+	writePromData(res, promQueryData{ResultType: matrix.Type(), Result: promResult(matrix)})
+}
+
+// promLabelsHandler возвращает имена всех меток (GET /api/v1/labels)
+func (h *Handlers) promLabelsHandler(res http.ResponseWriter, req *http.Request) {
+	h.writeLabelValues(res, func(name string, labels map[string]string, add func(string)) {
+		add("__name__")
+		for k := range labels {
+			add(k)
+		}
+	})
+}
+
+// promLabelValuesHandler возвращает значения метки (GET /api/v1/label/{name}/values)
+func (h *Handlers) promLabelValuesHandler(res http.ResponseWriter, req *http.Request) {
+	label := chi.URLParam(req, "name")
+	h.writeLabelValues(res, func(name string, labels map[string]string, add func(string)) {
+		if label == "__name__" {
+			add(name)
+		} else if v, ok := labels[label]; ok {
+			add(v)
+		}
+	})
+}
+
+func (h *Handlers) writeLabelValues(res http.ResponseWriter, collect func(name string, labels map[string]string, add func(string))) {
+	if h.history == nil {
+		writePromError(res, http.StatusServiceUnavailable, "unavailable", "history is disabled")
+		return
+	}
+
+	seen := make(map[string]bool)
+	values := []string{}
+	add := func(v string) {
+		if !seen[v] {
+			seen[v] = true
+			values = append(values, v)
+		}
+	}
+	for _, series := range h.history.Series() {
+		name, labels := models.ParseLabels(series.ID)
+		labels[promql.TypeLabel] = series.MType
+		collect(name, labels, add)
+	}
+	sort.Strings(values)
+	writePromData(res, values)
+}
+
+func promResult(value promql.Value) any {
+	switch v := value.(type) {
+	case promql.Scalar:
+		return promPoint(promql.Point(v))
+	case promql.Vector:
+		result := make([]promSample, 0, len(v))
+		for _, s := range v {
+			result = append(result, promSample{Metric: s.Metric, Value: promPoint(s.Point)})
+		}
+		return result
+	case promql.Matrix:
+		result := make([]promSeries, 0, len(v))
+		for _, s := range v {
+			values := make([][2]any, 0, len(s.Points))
+			for _, p := range s.Points {
+				values = append(values, promPoint(p))
+			}
+			result = append(result, promSeries{Metric: s.Metric, Values: values})
+		}
+		return result
+	}
+	return nil
+}
+
+// promPoint кодирует точку как [unix-время в секундах, "значение"]
+func promPoint(p promql.Point) [2]any {
+	var value string
+	switch {
+	case math.IsInf(p.V, 1):
+		value = "+Inf"
+	case math.IsInf(p.V, -1):
+		value = "-Inf"
+	case math.IsNaN(p.V):
+		value = "NaN"
+	default:
+		value = strconv.FormatFloat(p.V, 'f', -1, 64)
+	}
+	return [2]any{float64(p.T.UnixMilli()) / 1000, value}
+}
+
+// parsePromTime принимает unix-время в секундах (возможно дробное) или RFC 3339
+func parsePromTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, errors.New("value is required")
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		sec, frac := math.Modf(f)
+		return time.Unix(int64(sec), int64(frac*1e9)), nil
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+// parsePromDuration принимает число секунд или длительность вида 15s, 1m
+func parsePromDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, errors.New("value is required")
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		if f <= 0 {
+			return 0, errors.New("must be positive")
+		}
+		return time.Duration(f * float64(time.Second)), nil
+	}
+	return promql.ParseDuration(s)
+}
+
+func writePromQueryError(res http.ResponseWriter, err error) {
+	errorType := "execution"
+	if errors.Is(err, promql.ErrParse) || errors.Is(err, promql.ErrEval) {
+		errorType = "bad_data"
+	}
+	writePromError(res, http.StatusBadRequest, errorType, err.Error())
+}
+
+func writePromData(res http.ResponseWriter, data any) {
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(http.StatusOK)
+	json.NewEncoder(res).Encode(promResponse{Status: "success", Data: data})
+}
+
+func writePromError(res http.ResponseWriter, status int, errorType, message string) {
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(status)
+	json.NewEncoder(res).Encode(promResponse{Status: "error", ErrorType: errorType, Error: message})
+}
@@ -0,0 +1,119 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/config"
+	"github.com/akorablin/yandex-practicum-metrics/internal/handler"
+	"github.com/akorablin/yandex-practicum-metrics/internal/history"
+	"github.com/akorablin/yandex-practicum-metrics/internal/repository/memory"
+	"go.uber.org/zap"
+)
+
+type promResponse struct {
+	Status    string          `json:"status"`
+	Data      json.RawMessage `json:"data"`
+	ErrorType string          `json:"errorType"`
+}
+
+func TestPromQuery(t *testing.T) {
+	cfg := &config.ServerConfig{HistoryInterval: 10, HistoryRetention: 3600}
+	repo := memory.New(cfg)
+	store := history.New(cfg, repo)
+
+	// Два снимка с разницей в 10 секунд: счётчик вырос на 20
+	now := time.Unix(1700000000, 0)
+	repo.UpdateCounter("hits;code=200", 10)
+	store.Snapshot(now.Add(-10 * time.Second))
+	repo.UpdateCounter("hits;code=200", 20)
+	store.Snapshot(now)
+
+	router := handler.NewHandlers(cfg, repo, nil, zap.NewNop(), handler.WithHistory(store)).GetRoutes()
+
+	tests := []struct {
+		name      string
+		method    string
+		params    url.Values
+		status    int
+		errorType string
+		data      string
+	}{
+		{
+			name:   "Мгновенный запрос",
+			method: http.MethodGet,
+			params: url.Values{"query": {"rate(hits[1m])"}, "time": {"1700000000"}},
+			status: http.StatusOK,
+			data:   `{"resultType":"vector","result":[{"metric":{"code":"200","type":"counter"},"value":[1700000000,"2"]}]}`,
+		},
+		{
+			name:   "Запрос за период формой",
+			method: http.MethodPost,
+			params: url.Values{"query": {`hits{code="200"}`}, "start": {"1699999990"}, "end": {"2023-11-14T22:13:20Z"}, "step": {"10s"}},
+			status: http.StatusOK,
+			data:   `{"resultType":"matrix","result":[{"metric":{"__name__":"hits","code":"200","type":"counter"},"values":[[1699999990,"10"],[1700000000,"30"]]}]}`,
+		},
+		{
+			name:      "Ошибка разбора",
+			method:    http.MethodGet,
+			params:    url.Values{"query": {"hits{"}},
+			status:    http.StatusBadRequest,
+			errorType: "bad_data",
+		},
+		{
+			name:      "Некорректный шаг",
+			method:    http.MethodGet,
+			params:    url.Values{"query": {"hits"}, "start": {"1"}, "end": {"2"}, "step": {"-1"}},
+			status:    http.StatusBadRequest,
+			errorType: "bad_data",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := "/api/v1/query"
+			if tt.params.Has("step") {
+				path = "/api/v1/query_range"
+			}
+
+			var req *http.Request
+			if tt.method == http.MethodPost {
+				req = httptest.NewRequest(tt.method, path, strings.NewReader(tt.params.Encode()))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			} else {
+				req = httptest.NewRequest(tt.method, path+"?"+tt.params.Encode(), nil)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+			var resp promResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Invalid response: %v", err)
+			}
+			if resp.ErrorType != tt.errorType {
+				t.Errorf("Expected errorType %q, got %q", tt.errorType, resp.ErrorType)
+			}
+			if tt.data != "" && string(resp.Data) != tt.data {
+				t.Errorf("Unexpected data:\n got %s\nwant %s", resp.Data, tt.data)
+			}
+		})
+	}
+}
+
+func TestPromQueryWithoutHistory(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/query?query=up", nil)
+	rec := httptest.NewRecorder()
+	newRouter().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/auth"
+	"github.com/akorablin/yandex-practicum-metrics/internal/middleware"
+	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
+	"github.com/akorablin/yandex-practicum-metrics/internal/problem"
+	"github.com/akorablin/yandex-practicum-metrics/internal/ratelimit"
+	"github.com/akorablin/yandex-practicum-metrics/internal/trusted"
+)
+
+// WithRateLimit ограничивает частоту запросов на приём метрик для каждого клиента
+func WithRateLimit(limiter *ratelimit.Limiter) Option {
+	return func(h *Handlers) {
+		h.limiter = limiter
+	}
+}
+
+// WithQuota включает квоты клиента на размер запроса и число рядов
+func WithQuota(quota *ratelimit.Quota) Option {
+	return func(h *Handlers) {
+		h.quota = quota
+	}
+}
+
+// clientID определяет клиента для ограничений способом из RateLimitBy
+func (h *Handlers) clientID(req *http.Request) string {
+	var token string
+	if t, ok := auth.FromContext(req.Context()); ok {
+		token = t.Name
+	}
+	return ratelimit.ClientKey(h.cfg.RateLimitBy, h.clientIP(req), token, req.Header.Get(ratelimit.AgentIDHeader))
+}
+
+// clientIP — адрес клиента с учётом доверенных прокси, если задана доверенная подсеть
+func (h *Handlers) clientIP(req *http.Request) string {
+	if h.trusted != nil {
+		return h.trusted.ClientIP(req.RemoteAddr, req.Header.Get(trusted.Header), req.Header.Get("X-Forwarded-For")).String()
+	}
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	return host
+}
+
+// admit проверяет квоты клиента перед сохранением метрик и отвечает 429 при превышении
+func (h *Handlers) admit(res http.ResponseWriter, req *http.Request, metrics []models.Metrics) bool {
+	if h.quota == nil {
+		return true
+	}
+
+	err := h.quota.Admit(h.clientID(req), metrics)
+	var quotaErr *ratelimit.QuotaError
+	if !errors.As(err, &quotaErr) {
+		return true
+	}
+
+	h.throttled(quotaErr.Reason)
+	if quotaErr.RetryAfter > 0 {
+		middleware.SetRetryAfter(res, quotaErr.RetryAfter)
+	}
+	problem.Write(res, req, http.StatusTooManyRequests, problem.CodeQuotaExceeded, quotaErr.Error())
+	return false
+}
+
+// recordSeries учитывает ряды успешно сохранённых метрик в квоте клиента
+func (h *Handlers) recordSeries(req *http.Request, metrics []models.Metrics) {
+	if h.quota != nil {
+		h.quota.Record(h.clientID(req), metrics)
+	}
+}
+
+// throttled учитывает отклонённый запрос в счётчике ThrottledRequests;reason=... в памяти:
+// счётчик записывается вместе с собственными метриками, а не отдельной записью на каждый отказ
+func (h *Handlers) throttled(reason string) {
+	h.recorder.Add(ratelimit.ThrottledMetric, map[string]string{"reason": reason}, 1)
+}
@@ -0,0 +1,70 @@
+package handler_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/config"
+	"github.com/akorablin/yandex-practicum-metrics/internal/handler"
+	"github.com/akorablin/yandex-practicum-metrics/internal/ratelimit"
+	"github.com/akorablin/yandex-practicum-metrics/internal/repository/memory"
+	"github.com/akorablin/yandex-practicum-metrics/internal/selfmetrics"
+	"go.uber.org/zap"
+)
+
+func TestRateLimit(t *testing.T) {
+	cfg := &config.ServerConfig{RateLimitBy: config.RateLimitByAgent}
+	repo := memory.New(cfg)
+	recorder := selfmetrics.New(cfg)
+	router := handler.NewHandlers(cfg, repo, nil, zap.NewNop(),
+		handler.WithSelfMetrics(recorder),
+		handler.WithRateLimit(ratelimit.NewLimiter(0.001, 2)),
+		handler.WithQuota(ratelimit.NewQuota(2, 0)),
+	).GetRoutes()
+
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		agent      string
+		status     int
+		retryAfter bool
+	}{
+		{"Первый запрос", "/update/gauge/Alloc/1", "", "agent-1", http.StatusOK, false},
+		{"Пакет больше квоты", "/updates/", `[{"id":"A","type":"gauge","value":1},{"id":"B","type":"gauge","value":1},{"id":"C","type":"gauge","value":1}]`, "agent-1", http.StatusTooManyRequests, false},
+		{"Превышение частоты", "/update/gauge/Alloc/1", "", "agent-1", http.StatusTooManyRequests, true},
+		{"Другой агент", "/update/gauge/Alloc/1", "", "agent-2", http.StatusOK, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			req.Header.Set(ratelimit.AgentIDHeader, tt.agent)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+			if got := rec.Header().Get("Retry-After") != ""; got != tt.retryAfter {
+				t.Errorf("Retry-After present = %v, want %v", got, tt.retryAfter)
+			}
+		})
+	}
+
+	// Отказы учитываются в собственных метриках сервера и пишутся вместе с ними
+	if err := recorder.Flush(context.Background(), repo); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	for reason, want := range map[string]int64{ratelimit.ReasonRate: 1, ratelimit.ReasonBatch: 1} {
+		if got, err := repo.GetCounter("ThrottledRequests;reason=" + reason); err != nil || got != want {
+			t.Errorf("ThrottledRequests reason=%s = %d (%v), want %d", reason, got, err, want)
+		}
+	}
+}
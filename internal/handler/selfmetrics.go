@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/selfmetrics"
+	"github.com/go-chi/chi"
+)
+
+// httpDurationMetric — гистограмма длительности HTTP-запросов по маршруту, методу и статусу
+const httpDurationMetric = "HTTPRequestDuration"
+
+// WithSelfMetrics включает измерение HTTP-запросов сервера
+func WithSelfMetrics(recorder *selfmetrics.Recorder) Option {
+	return func(h *Handlers) {
+		h.recorder = recorder
+	}
+}
+
+// observeRequest учитывает запрос, измеренный middleware.Logging. Метка route — шаблон
+// маршрута chi, а не путь, чтобы число рядов не зависело от имён метрик. Запросы,
+// отклонённые middleware до выбора маршрута, попадают в route=unmatched
+func (h *Handlers) observeRequest(req *http.Request, status int, duration time.Duration, _ int) {
+	if h.recorder == nil {
+		return
+	}
+	route := "unmatched"
+	if rctx := chi.RouteContext(req.Context()); rctx != nil && rctx.RoutePattern() != "" {
+		route = rctx.RoutePattern()
+	}
+	if status == 0 {
+		status = http.StatusOK
+	}
+	h.recorder.Observe(httpDurationMetric, map[string]string{
+		"method": req.Method,
+		"route":  route,
+		"status": strconv.Itoa(status),
+	}, duration)
+}
@@ -0,0 +1,50 @@
+package handler_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/config"
+	"github.com/akorablin/yandex-practicum-metrics/internal/handler"
+	"github.com/akorablin/yandex-practicum-metrics/internal/repository/memory"
+	"github.com/akorablin/yandex-practicum-metrics/internal/selfmetrics"
+	"go.uber.org/zap"
+)
+
+func TestRequestSelfMetrics(t *testing.T) {
+	cfg := &config.ServerConfig{MaxBodySize: 16}
+	repo := memory.New(cfg)
+	recorder := selfmetrics.New(cfg)
+	router := handler.NewHandlers(cfg, repo, nil, zap.NewNop(), handler.WithSelfMetrics(recorder)).GetRoutes()
+
+	for _, path := range []string{"/value/gauge/Missing", "/value/gauge/Other", "/unknown"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	// Отказ в middleware до обработчика тоже учитывается
+	tooLarge := httptest.NewRequest(http.MethodPost, "/update/", strings.NewReader(`{"id":"Alloc","type":"gauge","value":1}`))
+	tooLarge.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(httptest.NewRecorder(), tooLarge)
+	if err := recorder.Flush(context.Background(), repo); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		id   string
+		want int64
+	}{
+		{"Маршрут с шаблоном", "HTTPRequestDuration_count;method=GET;route=/value/{type}/{name};status=404", 2},
+		{"Неизвестный маршрут", "HTTPRequestDuration_count;method=GET;route=unmatched;status=404", 1},
+		{"Отказ по размеру тела", "HTTPRequestDuration_count;method=POST;route=unmatched;status=413", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := repo.GetCounter(tt.id); err != nil || got != tt.want {
+				t.Errorf("Expected %d, got %d (%v)", tt.want, got, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/config"
+	"github.com/akorablin/yandex-practicum-metrics/internal/handler"
+	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
+	"github.com/akorablin/yandex-practicum-metrics/internal/repository/memory"
+	"github.com/akorablin/yandex-practicum-metrics/internal/sign"
+	"go.uber.org/zap"
+)
+
+func TestSignedRequests(t *testing.T) {
+	const key = "secret"
+	cfg := &config.ServerConfig{Key: key}
+	router := handler.NewHandlers(cfg, memory.New(cfg), nil, zap.NewNop()).GetRoutes()
+
+	delta := int64(5)
+	metricHash := sign.MetricHash(models.Metrics{ID: "PollCount", MType: models.Counter, Delta: &delta}, key)
+	batch := `[{"id":"PollCount","type":"counter","delta":5,"hash":"` + metricHash + `"}]`
+	badMetric := `[{"id":"PollCount","type":"counter","delta":6,"hash":"` + metricHash + `"}]`
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		hash   string
+		status int
+	}{
+		{"Подписанный пакет", http.MethodPost, "/updates/", batch, sign.Sum([]byte(batch), key), http.StatusOK},
+		{"Пакет без подписи", http.MethodPost, "/updates/", batch, "", http.StatusBadRequest},
+		{"Подпись другим ключом", http.MethodPost, "/updates/", batch, sign.Sum([]byte(batch), "other"), http.StatusBadRequest},
+		{"Неверный hash метрики", http.MethodPost, "/updates/", badMetric, sign.Sum([]byte(badMetric), key), http.StatusBadRequest},
+		{"Подписанный путь", http.MethodPost, "/update/gauge/Alloc/1.5", "", sign.Sum([]byte("/update/gauge/Alloc/1.5"), key), http.StatusOK},
+		{"Подпись чужого пути", http.MethodPost, "/update/gauge/Alloc/100", "", sign.Sum([]byte("/update/gauge/Alloc/1.5"), key), http.StatusBadRequest},
+		{"Чтение без подписи", http.MethodGet, "/value/counter/PollCount", "", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			if tt.hash != "" {
+				req.Header.Set(sign.Header, tt.hash)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+			if tt.hash != "" && rec.Code == http.StatusOK && !sign.Valid(rec.Body.Bytes(), key, rec.Header().Get(sign.Header)) {
+				t.Errorf("Response signature %q does not match body", rec.Header().Get(sign.Header))
+			}
+		})
+	}
+}
+
+func TestUnsignedRoutes(t *testing.T) {
+	cfg := &config.ServerConfig{Key: "secret", UnsignedRoutes: "/write"}
+	router := handler.NewHandlers(cfg, memory.New(cfg), nil, zap.NewNop()).GetRoutes()
+
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		hash   string
+		status int
+	}{
+		{"Telegraf без подписи", "/write", "cpu usage=1", "", http.StatusNoContent},
+		{"Неверная подпись проверяется", "/write", "cpu usage=1", "bad", http.StatusBadRequest},
+		{"OTel по-прежнему требует подписи", "/v1/metrics", "{}", "", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			if tt.hash != "" {
+				req.Header.Set(sign.Header, tt.hash)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("Expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/problem"
+	"github.com/akorablin/yandex-practicum-metrics/internal/stream"
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+)
+
+// Интервал отправки keep-alive комментариев в SSE
+const sseKeepAliveInterval = 15 * time.Second
+
+// streamHandler отдаёт обновления метрик по мере их применения (GET /stream?match=Heap*).
+// По умолчанию используется Server-Sent Events, при запросе Upgrade — WebSocket
+func (h *Handlers) streamHandler(res http.ResponseWriter, req *http.Request) {
+	if h.hub == nil {
+		problem.Write(res, req, http.StatusServiceUnavailable, problem.CodeFeatureDisabled, "stream is disabled")
+		return
+	}
+
+	pattern := req.URL.Query().Get("match")
+	if !stream.ValidPattern(pattern) {
+		problem.Write(res, req, http.StatusBadRequest, problem.CodeInvalidRequest, "invalid match pattern")
+		return
+	}
+
+	if strings.EqualFold(req.Header.Get("Upgrade"), "websocket") {
+		h.streamWebSocket(res, req, pattern)
+		return
+	}
+	h.streamSSE(res, req, pattern)
+}
+
+func (h *Handlers) streamSSE(res http.ResponseWriter, req *http.Request, pattern string) {
+	rc := http.NewResponseController(res)
+
+	sub := h.hub.Subscribe(pattern)
+	defer h.hub.Unsubscribe(sub)
+
+	res.Header().Set("Content-Type", "text/event-stream")
+	res.Header().Set("Cache-Control", "no-cache")
+	res.Header().Set("Connection", "keep-alive")
+	res.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		log.Printf("SSE flush is not supported: %v", err)
+		return
+	}
+
+	keepAlive := time.NewTicker(sseKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+
+		case <-keepAlive.C:
+			fmt.Fprint(res, ": keep-alive\n\n")
+
+		case e, ok := <-sub.C:
+			if !ok {
+				if sub.Lagged() {
+					fmt.Fprint(res, "event: lagged\ndata: {}\n\n")
+					rc.Flush()
+				}
+				return
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				continue
+			}
+			event := "metric"
+			if e.Deleted {
+				event = "deleted"
+			}
+			fmt.Fprintf(res, "event: %s\ndata: %s\n\n", event, data)
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (h *Handlers) streamWebSocket(res http.ResponseWriter, req *http.Request, pattern string) {
+	conn, err := websocket.Accept(res, req, nil)
+	if err != nil {
+		log.Printf("WebSocket accept failed: %v", err)
+		return
+	}
+	defer conn.CloseNow()
+
+	sub := h.hub.Subscribe(pattern)
+	defer h.hub.Unsubscribe(sub)
+
+	// Клиент ничего не отправляет; CloseRead обрабатывает управляющие кадры и закрытие
+	ctx := conn.CloseRead(req.Context())
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case e, ok := <-sub.C:
+			if !ok {
+				if sub.Lagged() {
+					conn.Close(websocket.StatusTryAgainLater, "subscriber lagged behind")
+				} else {
+					conn.Close(websocket.StatusGoingAway, "server is shutting down")
+				}
+				return
+			}
+			if err := wsjson.Write(ctx, conn, e); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package handler_test
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/config"
+	"github.com/akorablin/yandex-practicum-metrics/internal/handler"
+	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
+	"github.com/akorablin/yandex-practicum-metrics/internal/repository/memory"
+	"github.com/akorablin/yandex-practicum-metrics/internal/storage"
+	"github.com/akorablin/yandex-practicum-metrics/internal/stream"
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"go.uber.org/zap"
+)
+
+func newStreamServer(t *testing.T) (*httptest.Server, *stream.Hub) {
+	t.Helper()
+
+	cfg := &config.ServerConfig{}
+	hub := stream.NewHub(16)
+	repo := stream.NewPublishingStorage(memory.New(cfg), hub)
+	server := httptest.NewServer(handler.NewHandlers(cfg, repo, nil, zap.NewNop(), handler.WithStream(hub)).GetRoutes())
+	t.Cleanup(func() {
+		hub.Close()
+		server.Close()
+	})
+	return server, hub
+}
+
+func update(t *testing.T, server *httptest.Server, path string) {
+	t.Helper()
+
+	resp, err := http.Post(server.URL+path, "text/plain", nil)
+	if err != nil {
+		t.Fatalf("POST %s failed: %v", path, err)
+	}
+	resp.Body.Close()
+}
+
+// waitSubscribed ждёт, пока в hub появится подписчик на метрику id
+func waitSubscribed(t *testing.T, hub *stream.Hub, id string) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !hub.Interested(id) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Subscription for %s was not registered", id)
+		}
+		runtime.Gosched()
+	}
+}
+
+func TestStreamSSE(t *testing.T) {
+	server, _ := newStreamServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/stream?match=Heap*", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /stream failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected Content-Type 'text/event-stream', got '%s'", ct)
+	}
+
+	// Метрика, не подходящая под шаблон, не должна попасть в поток
+	update(t, server, "/update/gauge/Alloc/1")
+	update(t, server, "/update/gauge/HeapAlloc/42")
+
+	reader := bufio.NewReader(resp.Body)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read event: %v", err)
+		}
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			if !strings.Contains(data, `"id":"HeapAlloc"`) || !strings.Contains(data, `"value":42`) {
+				t.Errorf("Unexpected event data: %s", data)
+			}
+			return
+		}
+	}
+}
+
+func TestStreamWebSocket(t *testing.T) {
+	server, hub := newStreamServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, server.URL+"/stream?match=PollCount", nil)
+	if err != nil {
+		t.Fatalf("websocket.Dial() failed: %v", err)
+	}
+	defer conn.CloseNow()
+
+	// Ждём регистрации подписки, прежде чем публиковать обновления
+	waitSubscribed(t, hub, "PollCount")
+	update(t, server, "/update/counter/PollCount/2")
+	update(t, server, "/update/counter/PollCount/3")
+
+	// В поток попадает итоговое значение счётчика
+	var e stream.Event
+	for _, want := range []int64{2, 5} {
+		if err := wsjson.Read(ctx, conn, &e); err != nil {
+			t.Fatalf("wsjson.Read() failed: %v", err)
+		}
+		if e.ID != "PollCount" || e.Deleted || e.Delta == nil || *e.Delta != want {
+			t.Errorf("Expected PollCount = %d, got %+v", want, e)
+		}
+	}
+
+	// Удаление тоже публикуется
+	req, _ := http.NewRequest(http.MethodDelete, server.URL+"/api/v1/metrics/counter/PollCount", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("DELETE failed: %v", err)
+	}
+	resp.Body.Close()
+	e = stream.Event{}
+	if err := wsjson.Read(ctx, conn, &e); err != nil {
+		t.Fatalf("wsjson.Read() failed: %v", err)
+	}
+	if e.ID != "PollCount" || e.MType != models.Counter || !e.Deleted {
+		t.Errorf("Expected PollCount deletion, got %+v", e)
+	}
+}
+
+func TestStreamLaggedSubscriber(t *testing.T) {
+	hub := stream.NewHub(1)
+	sub := hub.Subscribe("")
+
+	value := 1.0
+	hub.Publish(models.Metrics{ID: "a", MType: models.Gauge, Value: &value})
+	hub.Publish(models.Metrics{ID: "b", MType: models.Gauge, Value: &value})
+
+	<-sub.C
+	if _, ok := <-sub.C; ok {
+		t.Fatal("Expected lagged subscription to be closed")
+	}
+	if !sub.Lagged() {
+		t.Error("Expected subscription to be marked as lagged")
+	}
+}
+
+// counterReads считает чтения counter из хранилища
+type counterReads struct {
+	storage.Storage
+	reads int
+}
+
+func (s *counterReads) GetCounter(name string) (int64, error) {
+	s.reads++
+	return s.Storage.GetCounter(name)
+}
+
+func TestStreamCounterReads(t *testing.T) {
+	hub := stream.NewHub(16)
+	inner := &counterReads{Storage: memory.New(&config.ServerConfig{})}
+	repo := stream.NewPublishingStorage(inner, hub)
+
+	// Без подходящих подписчиков итог счётчика не читается
+	sub := hub.Subscribe("Heap*")
+	repo.UpdateCounter("PollCount", 1)
+	if inner.reads != 0 {
+		t.Errorf("Expected no counter reads without subscribers, got %d", inner.reads)
+	}
+
+	hub.Unsubscribe(sub)
+	sub = hub.Subscribe("Poll*")
+	defer hub.Unsubscribe(sub)
+	repo.UpdateCounter("PollCount", 2)
+	if e := <-sub.C; inner.reads != 1 || e.Delta == nil || *e.Delta != 3 {
+		t.Errorf("Expected PollCount = 3 after 1 read, got %+v after %d reads", e, inner.reads)
+	}
+}
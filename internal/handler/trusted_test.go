@@ -0,0 +1,52 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/config"
+	"github.com/akorablin/yandex-practicum-metrics/internal/handler"
+	"github.com/akorablin/yandex-practicum-metrics/internal/repository/memory"
+	"github.com/akorablin/yandex-practicum-metrics/internal/trusted"
+	"go.uber.org/zap"
+)
+
+func TestTrustedSubnet(t *testing.T) {
+	checker, err := trusted.New("10.0.0.0/24", "127.0.0.0/8")
+	if err != nil {
+		t.Fatalf("trusted.New() failed: %v", err)
+	}
+	cfg := &config.ServerConfig{}
+	router := handler.NewHandlers(cfg, memory.New(cfg), nil, zap.NewNop(), handler.WithTrustedSubnet(checker)).GetRoutes()
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		remoteAddr string
+		realIP     string
+		status     int
+	}{
+		{"Агент из подсети через локальный прокси", http.MethodPost, "/update/gauge/Alloc/1", "127.0.0.1:40000", "10.0.0.5", http.StatusOK},
+		{"Агент вне подсети", http.MethodPost, "/update/gauge/Alloc/1", "127.0.0.1:40000", "10.0.1.5", http.StatusForbidden},
+		{"Подделанный X-Real-IP", http.MethodPost, "/update/gauge/Alloc/1", "172.16.0.1:40000", "10.0.0.5", http.StatusForbidden},
+		{"Чтение не ограничено", http.MethodGet, "/value/gauge/Alloc", "172.16.0.1:40000", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.realIP != "" {
+				req.Header.Set(trusted.Header, tt.realIP)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// staleFactor — во сколько интервалов периодическая задача может не отчитываться
+const staleFactor = 3
+
+// Check проверяет один компонент сервера; nil означает, что компонент готов
+type Check func(ctx context.Context) error
+
+// Component — состояние одного компонента в отчёте /readyz
+type Component struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Report — общий статус и разбивка по компонентам
+type Report struct {
+	Status     string               `json:"status"`
+	Components map[string]Component `json:"components"`
+}
+
+// Registry хранит проверки зависимостей и состояние фоновых задач
+type Registry struct {
+	mu     sync.RWMutex
+	checks map[string]Check
+}
+
+func New() *Registry {
+	return &Registry{checks: make(map[string]Check)}
+}
+
+// Register добавляет проверку компонента; повторная регистрация заменяет проверку
+func (r *Registry) Register(name string, check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.checks[name] = check
+}
+
+// Job регистрирует фоновую задачу, работающую до отмены контекста
+func (r *Registry) Job(name string) *Job {
+	job := &Job{created: time.Now()}
+	r.Register("job:"+name, job.check)
+	return job
+}
+
+// PeriodicJob регистрирует задачу, которая отчитывается через Done каждые interval
+func (r *Registry) PeriodicJob(name string, interval time.Duration) *Job {
+	job := &Job{created: time.Now(), interval: interval}
+	r.Register("job:"+name, job.check)
+	return job
+}
+
+// Check выполняет все проверки параллельно; любой отказ делает общий статус down.
+// Проверки, не завершившиеся до отмены ctx, считаются отказавшими, даже если сами игнорируют контекст
+func (r *Registry) Check(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := make(map[string]Check, len(r.checks))
+	for name, check := range r.checks {
+		checks[name] = check
+	}
+	r.mu.RUnlock()
+
+	type result struct {
+		name      string
+		component Component
+	}
+	// Буфер на все проверки, чтобы зависшая проверка могла завершиться и после ответа
+	results := make(chan result, len(checks))
+	for name, check := range checks {
+		go func() {
+			component := Component{Status: StatusUp}
+			if err := check(ctx); err != nil {
+				component = Component{Status: StatusDown, Error: err.Error()}
+			}
+			results <- result{name: name, component: component}
+		}()
+	}
+
+	report := Report{Status: StatusUp, Components: make(map[string]Component, len(checks))}
+	for len(report.Components) < len(checks) {
+		select {
+		case res := <-results:
+			report.Components[res.name] = res.component
+		case <-ctx.Done():
+			for name := range checks {
+				if _, ok := report.Components[name]; !ok {
+					report.Components[name] = Component{Status: StatusDown, Error: ctx.Err().Error()}
+				}
+			}
+		}
+	}
+	for _, component := range report.Components {
+		if component.Status == StatusDown {
+			report.Status = StatusDown
+		}
+	}
+
+	return report
+}
+
+// Job — состояние фоновой задачи для /readyz
+type Job struct {
+	mu       sync.Mutex
+	created  time.Time
+	interval time.Duration
+	started  bool
+	running  bool
+	lastRun  time.Time
+	err      error
+}
+
+// Run выполняет долгоживущую задачу и запоминает, с какой ошибкой она завершилась
+func (j *Job) Run(fn func() error) error {
+	j.mu.Lock()
+	j.started, j.running = true, true
+	j.mu.Unlock()
+
+	err := fn()
+
+	j.mu.Lock()
+	j.running, j.err = false, err
+	j.mu.Unlock()
+	return err
+}
+
+// Done отмечает очередной запуск периодической задачи
+func (j *Job) Done(err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.err = err
+	if err == nil {
+		j.lastRun = time.Now()
+	}
+}
+
+func (j *Job) check(context.Context) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.interval > 0 {
+		if j.err != nil {
+			return j.err
+		}
+		last := j.lastRun
+		if last.IsZero() {
+			last = j.created
+		}
+		if since := time.Since(last); since > staleFactor*j.interval {
+			return fmt.Errorf("no successful run for %s", since.Round(time.Second))
+		}
+		return nil
+	}
+
+	switch {
+	case j.running:
+		return nil
+	case j.err != nil:
+		return j.err
+	case j.started:
+		return errors.New("stopped")
+	}
+	return errors.New("not started")
+}
@@ -0,0 +1,114 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/health"
+)
+
+func TestRegistryCheck(t *testing.T) {
+	registry := health.New()
+	registry.Register("storage", func(context.Context) error { return nil })
+
+	report := registry.Check(context.Background())
+	if report.Status != health.StatusUp || report.Components["storage"].Status != health.StatusUp {
+		t.Fatalf("Expected all up, got %+v", report)
+	}
+
+	registry.Register("file", func(context.Context) error { return errors.New("read-only file system") })
+	report = registry.Check(context.Background())
+	if report.Status != health.StatusDown {
+		t.Errorf("Expected down, got %+v", report)
+	}
+	if c := report.Components["file"]; c.Status != health.StatusDown || c.Error != "read-only file system" {
+		t.Errorf("Unexpected file component: %+v", c)
+	}
+	if c := report.Components["storage"]; c.Status != health.StatusUp {
+		t.Errorf("Unexpected storage component: %+v", c)
+	}
+}
+
+func TestRegistryCheckTimeout(t *testing.T) {
+	registry := health.New()
+	registry.Register("storage", func(context.Context) error { return nil })
+	// Проверка игнорирует контекст и не завершается до конца теста
+	release := make(chan struct{})
+	defer close(release)
+	registry.Register("stuck", func(context.Context) error { <-release; return nil })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	report := registry.Check(ctx)
+
+	if report.Status != health.StatusDown {
+		t.Errorf("Expected down, got %+v", report)
+	}
+	if c := report.Components["stuck"]; c.Status != health.StatusDown || c.Error != context.DeadlineExceeded.Error() {
+		t.Errorf("Unexpected stuck component: %+v", c)
+	}
+	if c := report.Components["storage"]; c.Status != health.StatusUp {
+		t.Errorf("Unexpected storage component: %+v", c)
+	}
+}
+
+func TestJob(t *testing.T) {
+	registry := health.New()
+	job := registry.Job("statsd")
+	status := func() health.Component {
+		return registry.Check(context.Background()).Components["job:statsd"]
+	}
+
+	if c := status(); c.Status != health.StatusDown || c.Error != "not started" {
+		t.Errorf("Не запущена: %+v", c)
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		job.Run(func() error {
+			<-stop
+			return errors.New("listen udp: address already in use")
+		})
+	}()
+	deadline := time.Now().Add(time.Second)
+	for status().Status != health.StatusUp && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if c := status(); c.Status != health.StatusUp {
+		t.Errorf("Работает: %+v", c)
+	}
+
+	close(stop)
+	<-done
+	if c := status(); c.Status != health.StatusDown || c.Error != "listen udp: address already in use" {
+		t.Errorf("Завершилась с ошибкой: %+v", c)
+	}
+}
+
+func TestPeriodicJob(t *testing.T) {
+	registry := health.New()
+	job := registry.PeriodicJob("snapshot", 10*time.Millisecond)
+	status := func() health.Component {
+		return registry.Check(context.Background()).Components["job:snapshot"]
+	}
+
+	job.Done(nil)
+	if c := status(); c.Status != health.StatusUp {
+		t.Errorf("Успешный запуск: %+v", c)
+	}
+
+	job.Done(errors.New("permission denied"))
+	if c := status(); c.Status != health.StatusDown || c.Error != "permission denied" {
+		t.Errorf("Ошибка запуска: %+v", c)
+	}
+
+	job.Done(nil)
+	time.Sleep(50 * time.Millisecond)
+	if c := status(); c.Status != health.StatusDown {
+		t.Errorf("Давно не запускалась: %+v", c)
+	}
+}
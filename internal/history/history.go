@@ -0,0 +1,119 @@
+package history
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/config"
+	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
+	"github.com/akorablin/yandex-practicum-metrics/internal/storage"
+)
+
+type Sample struct {
+	Time  time.Time
+	Value float64
+}
+
+// Series идентифицирует ряд значений метрики
+type Series struct {
+	ID    string
+	MType string
+}
+
+// Store периодически снимает текущие значения из хранилища и хранит их в памяти
+// в течение HistoryRetention. История нужна для запросов за период, которых нет в Storage
+type Store struct {
+	cfg     *config.ServerConfig
+	storage storage.Storage
+
+	mu     sync.RWMutex
+	series map[Series][]Sample
+}
+
+func New(cfg *config.ServerConfig, repo storage.Storage) *Store {
+	return &Store{
+		cfg:     cfg,
+		storage: repo,
+		series:  make(map[Series][]Sample),
+	}
+}
+
+// Run снимает значения каждые HistoryInterval секунд до отмены контекста
+func (s *Store) Run(ctx context.Context) {
+	log.Printf("History sampling started with interval %ds", s.cfg.HistoryInterval)
+	ticker := time.NewTicker(time.Duration(s.cfg.HistoryInterval) * time.Second)
+	defer ticker.Stop()
+
+	s.Snapshot(time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("History sampling stopped")
+			return
+		case now := <-ticker.C:
+			s.Snapshot(now)
+		}
+	}
+}
+
+// Snapshot добавляет текущие значения всех метрик и удаляет устаревшие точки
+func (s *Store) Snapshot(now time.Time) {
+	gauges, counters := s.storage.GetAllMetrics()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, value := range gauges {
+		key := Series{ID: id, MType: models.Gauge}
+		s.series[key] = append(s.series[key], Sample{Time: now, Value: value})
+	}
+	for id, value := range counters {
+		key := Series{ID: id, MType: models.Counter}
+		s.series[key] = append(s.series[key], Sample{Time: now, Value: float64(value)})
+	}
+
+	cutoff := now.Add(-time.Duration(s.cfg.HistoryRetention) * time.Second)
+	for key, samples := range s.series {
+		i := sort.Search(len(samples), func(i int) bool { return !samples[i].Time.Before(cutoff) })
+		if i == len(samples) {
+			delete(s.series, key)
+			continue
+		}
+		s.series[key] = samples[i:]
+	}
+}
+
+// Series возвращает все ряды, по которым есть точки, отсортированные по ID
+func (s *Store) Series() []Series {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]Series, 0, len(s.series))
+	for key := range s.series {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].ID != keys[j].ID {
+			return keys[i].ID < keys[j].ID
+		}
+		return keys[i].MType < keys[j].MType
+	})
+	return keys
+}
+
+// Range возвращает копию точек ряда в интервале [from, to]
+func (s *Store) Range(key Series, from, to time.Time) []Sample {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	samples := s.series[key]
+	start := sort.Search(len(samples), func(i int) bool { return !samples[i].Time.Before(from) })
+	end := sort.Search(len(samples), func(i int) bool { return samples[i].Time.After(to) })
+	if start >= end {
+		return nil
+	}
+	return append([]Sample(nil), samples[start:end]...)
+}
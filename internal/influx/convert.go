@@ -0,0 +1,35 @@
+package influx
+
+import (
+	"math"
+
+	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
+)
+
+// ToMetrics превращает каждое поле точки в отдельную метрику measurement_field,
+// теги переносятся в ID через models.FlattenLabels
+func ToMetrics(points []Point, integerType, floatType string) []models.Metrics {
+	metrics := make([]models.Metrics, 0, len(points))
+	for _, point := range points {
+		for _, field := range point.Fields {
+			id := models.FlattenLabels(point.Measurement+"_"+field.Key, point.Tags)
+
+			metricType := floatType
+			if field.Integer {
+				metricType = integerType
+			}
+
+			m := models.Metrics{ID: id, MType: metricType}
+			switch metricType {
+			case models.Counter:
+				delta := int64(math.Round(field.Value))
+				m.Delta = &delta
+			default:
+				value := field.Value
+				m.Value = &value
+			}
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics
+}
@@ -0,0 +1,162 @@
+package influx
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidLine = errors.New("invalid line protocol")
+
+type Field struct {
+	Key     string
+	Value   float64
+	Integer bool
+}
+
+type Point struct {
+	Measurement string
+	Tags        map[string]string
+	Fields      []Field
+	Timestamp   time.Time
+}
+
+// Parse разбирает тело запроса в формате InfluxDB line protocol:
+// measurement[,tag=value...] field=value[,field=value...] [timestamp]
+// Строковые и логические поля пропускаются, так как не отображаются в gauge/counter
+func Parse(data []byte) ([]Point, error) {
+	var points []Point
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		point, err := parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		points = append(points, point)
+	}
+	return points, nil
+}
+
+func parseLine(line string) (Point, error) {
+	sections := splitUnescaped(line, ' ', true)
+	if len(sections) < 2 || len(sections) > 3 {
+		return Point{}, fmt.Errorf("%w: expected measurement, fields and optional timestamp", ErrInvalidLine)
+	}
+
+	// Имя измерения и теги
+	series := splitUnescaped(sections[0], ',', false)
+	point := Point{
+		Measurement: unescape(series[0]),
+		Tags:        make(map[string]string, len(series)-1),
+		Timestamp:   time.Now(),
+	}
+	if point.Measurement == "" {
+		return Point{}, fmt.Errorf("%w: missing measurement", ErrInvalidLine)
+	}
+	for _, tag := range series[1:] {
+		kv := splitUnescaped(tag, '=', false)
+		if len(kv) != 2 || kv[0] == "" {
+			return Point{}, fmt.Errorf("%w: bad tag %q", ErrInvalidLine, tag)
+		}
+		point.Tags[unescape(kv[0])] = unescape(kv[1])
+	}
+
+	// Поля
+	for _, field := range splitUnescaped(sections[1], ',', true) {
+		kv := splitUnescaped(field, '=', true)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return Point{}, fmt.Errorf("%w: bad field %q", ErrInvalidLine, field)
+		}
+		f, ok, err := parseFieldValue(kv[1])
+		if err != nil {
+			return Point{}, fmt.Errorf("%w: field %q: %v", ErrInvalidLine, kv[0], err)
+		}
+		if !ok {
+			continue
+		}
+		f.Key = unescape(kv[0])
+		point.Fields = append(point.Fields, f)
+	}
+
+	// Метка времени в наносекундах
+	if len(sections) == 3 {
+		ns, err := strconv.ParseInt(sections[2], 10, 64)
+		if err != nil {
+			return Point{}, fmt.Errorf("%w: bad timestamp %q", ErrInvalidLine, sections[2])
+		}
+		point.Timestamp = time.Unix(0, ns)
+	}
+
+	return point, nil
+}
+
+// parseFieldValue возвращает ok=false для строковых и логических значений
+func parseFieldValue(raw string) (Field, bool, error) {
+	switch {
+	case strings.HasPrefix(raw, `"`):
+		return Field{}, false, nil
+	case raw == "t" || raw == "T" || raw == "f" || raw == "F" || strings.EqualFold(raw, "true") || strings.EqualFold(raw, "false"):
+		return Field{}, false, nil
+	case strings.HasSuffix(raw, "i"):
+		v, err := strconv.ParseInt(strings.TrimSuffix(raw, "i"), 10, 64)
+		if err != nil {
+			return Field{}, false, err
+		}
+		return Field{Value: float64(v), Integer: true}, true, nil
+	case strings.HasSuffix(raw, "u"):
+		v, err := strconv.ParseUint(strings.TrimSuffix(raw, "u"), 10, 64)
+		if err != nil {
+			return Field{}, false, err
+		}
+		return Field{Value: float64(v), Integer: true}, true, nil
+	default:
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return Field{}, false, err
+		}
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return Field{}, false, fmt.Errorf("non-finite value %q", raw)
+		}
+		return Field{Value: v}, true, nil
+	}
+}
+
+// splitUnescaped делит строку по разделителю, пропуская экранированные символы
+// и (при quoted=true) содержимое строк в двойных кавычках
+func splitUnescaped(s string, sep byte, quoted bool) []string {
+	var parts []string
+	start, inQuotes := 0, false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			i++
+		case quoted && s[i] == '"':
+			inQuotes = !inQuotes
+		case s[i] == sep && !inQuotes:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,74 @@
+package influx_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/influx"
+	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
+)
+
+func TestParse(t *testing.T) {
+	body := []byte(`cpu,host=server\ 01,region=eu usage_idle=93.5,usage_user=2i,state="ok" 1700000000000000000
+# комментарий
+
+mem used=1024u,active=true`)
+
+	points, err := influx.Parse(body)
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if len(points) != 2 {
+		t.Fatalf("Expected 2 points, got %d", len(points))
+	}
+
+	cpu := points[0]
+	if cpu.Measurement != "cpu" {
+		t.Errorf("Expected measurement 'cpu', got '%s'", cpu.Measurement)
+	}
+	if cpu.Tags["host"] != "server 01" || cpu.Tags["region"] != "eu" {
+		t.Errorf("Unexpected tags: %v", cpu.Tags)
+	}
+	// Строковое поле пропускается
+	if len(cpu.Fields) != 2 {
+		t.Fatalf("Expected 2 numeric fields, got %d", len(cpu.Fields))
+	}
+	if cpu.Fields[0].Integer || cpu.Fields[0].Value != 93.5 {
+		t.Errorf("Unexpected float field: %+v", cpu.Fields[0])
+	}
+	if !cpu.Fields[1].Integer || cpu.Fields[1].Value != 2 {
+		t.Errorf("Unexpected integer field: %+v", cpu.Fields[1])
+	}
+	if cpu.Timestamp.Unix() != 1700000000 {
+		t.Errorf("Unexpected timestamp: %v", cpu.Timestamp)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	lines := []string{"cpu", "cpu usage", "cpu usage=abc", "cpu,host usage=1", "cpu usage=1 yesterday",
+		"cpu usage=NaN", "cpu usage=Inf", "cpu usage=-Inf"}
+
+	for _, line := range lines {
+		if _, err := influx.Parse([]byte(line)); !errors.Is(err, influx.ErrInvalidLine) {
+			t.Errorf("Parse(%q): expected ErrInvalidLine, got %v", line, err)
+		}
+	}
+}
+
+func TestToMetrics(t *testing.T) {
+	points, err := influx.Parse([]byte("net,host=a bytes_recv=100i,rate=1.5"))
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+
+	metrics := influx.ToMetrics(points, models.Counter, models.Gauge)
+	if len(metrics) != 2 {
+		t.Fatalf("Expected 2 metrics, got %d", len(metrics))
+	}
+	if m := metrics[0]; m.ID != "net_bytes_recv;host=a" || m.MType != models.Counter || *m.Delta != 100 {
+		t.Errorf("Unexpected counter metric: %+v", m)
+	}
+	if m := metrics[1]; m.ID != "net_rate;host=a" || m.MType != models.Gauge || *m.Value != 1.5 {
+		t.Errorf("Unexpected gauge metric: %+v", m)
+	}
+}
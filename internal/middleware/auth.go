@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/auth"
+	"github.com/akorablin/yandex-practicum-metrics/internal/problem"
+)
+
+// Auth требует bearer-токен с правом, нужным маршруту (см. requiredScope).
+// Токен передаётся в заголовке Authorization. Для GET токен с правом read можно передать
+// в параметре access_token: в ответ выдаётся cookie с короткоживущим билетом, которым
+// дашборд и его EventSource пользуются дальше, не повторяя токен в адресах
+func Auth(store *auth.Store) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if store == nil || !store.Enabled() {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scope := requiredScope(r)
+			if scope == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			token, err := authorize(store, w, r, scope)
+			switch {
+			case errors.Is(err, auth.ErrUnauthorized):
+				w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+				problem.Write(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "missing or invalid bearer token")
+				return
+			case errors.Is(err, errAdminInQuery):
+				problem.Write(w, r, http.StatusForbidden, problem.CodeForbidden, err.Error())
+				return
+			case errors.Is(err, auth.ErrForbidden):
+				problem.Write(w, r, http.StatusForbidden, problem.CodeForbidden, scope+" scope required")
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), token)))
+		})
+	}
+}
+
+// AccessTokenParam — параметр адреса с токеном; в журнал запросов он не попадает
+const AccessTokenParam = "access_token"
+
+// Токен из адреса оседает в журналах прокси и истории браузера, поэтому admin так не принимается
+var errAdminInQuery = errors.New("admin tokens are not accepted in access_token, use the Authorization header")
+
+// authorize проверяет заголовок Authorization, затем для GET — access_token и cookie с билетом.
+// Токен из адреса и билет дают только право read
+func authorize(store *auth.Store, w http.ResponseWriter, r *http.Request, scope string) (auth.Token, error) {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return store.Authorize(strings.TrimSpace(token), scope)
+	}
+	if r.Method != http.MethodGet {
+		return auth.Token{}, auth.ErrUnauthorized
+	}
+
+	now := time.Now()
+	if raw := r.URL.Query().Get(AccessTokenParam); raw != "" {
+		token, err := store.Authorize(raw, auth.ScopeRead)
+		if err != nil {
+			return token, err
+		}
+		if slices.Contains(token.Scopes, auth.ScopeAdmin) {
+			return auth.Token{}, errAdminInQuery
+		}
+		if scope != auth.ScopeRead {
+			return token, auth.ErrForbidden
+		}
+		setTicket(w, r, store.IssueTicket(token, now))
+		return token, nil
+	}
+
+	cookie, err := r.Cookie(auth.TicketCookie)
+	if err != nil {
+		return auth.Token{}, auth.ErrUnauthorized
+	}
+	token, expiry, err := store.Ticket(cookie.Value, now)
+	if err != nil {
+		return auth.Token{}, auth.ErrUnauthorized
+	}
+	if scope != auth.ScopeRead {
+		return token, auth.ErrForbidden
+	}
+	// Билет продлевается, пока дашборд делает запросы
+	if expiry.Sub(now) < auth.TicketTTL/2 {
+		setTicket(w, r, store.IssueTicket(token, now))
+	}
+	return token, nil
+}
+
+func setTicket(w http.ResponseWriter, r *http.Request, ticket string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     auth.TicketCookie,
+		Value:    ticket,
+		Path:     "/",
+		MaxAge:   int(auth.TicketTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// requiredScope: приём метрик — write, изменение метаданных и удаление метрик — admin,
+// статика дашборда, /ping и пробы /healthz, /readyz доступны без токена, остальное — read
+func requiredScope(r *http.Request) string {
+	switch {
+	case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/meta/"), isDeleteRequest(r):
+		return auth.ScopeAdmin
+	case isStoringRequest(r):
+		return auth.ScopeWrite
+	case r.URL.Path == "/ping" || r.URL.Path == "/healthz" || r.URL.Path == "/readyz",
+		strings.HasPrefix(r.URL.Path, "/dashboard/"):
+		return ""
+	}
+	return auth.ScopeRead
+}
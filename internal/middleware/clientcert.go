@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/problem"
+)
+
+// ClientCert при включённом mTLS отклоняет запросы на приём и удаление метрик без проверенного
+// клиентского сертификата. Остальные маршруты, в том числе /healthz и /readyz, доступны без него
+func ClientCert(required bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !required {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if (isStoringRequest(r) || isDeleteRequest(r)) && (r.TLS == nil || len(r.TLS.VerifiedChains) == 0) {
+				problem.Write(w, r, http.StatusForbidden, problem.CodeClientCertRequired, "client certificate is required")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/compression"
+	"github.com/akorablin/yandex-practicum-metrics/internal/problem"
+)
+
+// compressWriter копит начало ответа, пока не наберётся minSize байт, и только тогда
+// решает, сжимать ли его: короткие ответы дешевле отправить как есть
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+
+	status  int
+	buf     []byte
+	decided bool
+	writer  io.WriteCloser
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.decided || code < http.StatusOK {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, b...)
+		if len(w.buf) < w.minSize {
+			return len(b), nil
+		}
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if w.writer != nil {
+		return w.writer.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush отправляет накопленное клиенту; потоковые ответы сжимаются независимо от размера
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		if err := w.decide(true); err != nil {
+			return
+		}
+	}
+	if f, ok := w.writer.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *compressWriter) Close() error {
+	if !w.decided {
+		if err := w.decide(false); err != nil {
+			return err
+		}
+	}
+	if w.writer != nil {
+		return w.writer.Close()
+	}
+	return nil
+}
+
+// decide отправляет заголовки и накопленные данные, сжимая их, если позволяют тип, статус и размер
+func (w *compressWriter) decide(large bool) error {
+	w.decided = true
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	header := w.Header()
+	if header.Get("Content-Type") == "" && len(w.buf) > 0 {
+		header.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	if large && header.Get("Content-Encoding") == "" && compressible(header.Get("Content-Type")) &&
+		w.status != http.StatusNoContent && w.status != http.StatusNotModified {
+		writer, err := compression.NewWriter(w.encoding, w.ResponseWriter)
+		if err == nil {
+			header.Set("Content-Encoding", w.encoding)
+			header.Del("Content-Length")
+			w.writer = writer
+		}
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+
+	if len(w.buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.writer != nil {
+		_, err = w.writer.Write(w.buf)
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf)
+	}
+	w.buf = nil
+	return err
+}
+
+// compressible отбирает текстовые форматы; уже сжатые данные и поток событий не сжимаются
+func compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case mediaType == "text/event-stream":
+		return false
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/javascript", "application/xml", "image/svg+xml":
+		return true
+	}
+	return false
+}
+
+// Compression распаковывает тело запроса по Content-Encoding (gzip, deflate, zstd, br)
+// и сжимает ответ алгоритмом, выбранным по Accept-Encoding.
+// Ответы короче minSize байт отправляются без сжатия
+func Compression(minSize int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Распаковка тела запроса, если оно сжато
+			if encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))); encoding != "" && encoding != compression.Identity {
+				reader, err := compression.NewReader(encoding, r.Body)
+				if errors.Is(err, compression.ErrUnsupported) {
+					w.Header().Set("Accept-Encoding", strings.Join(compression.Preferred, ", "))
+					problem.Write(w, r, http.StatusUnsupportedMediaType, problem.CodeUnsupportedEncoding, "unsupported content encoding")
+					return
+				}
+				if err != nil {
+					if IsBodyTooLarge(err) {
+						problem.Write(w, r, http.StatusRequestEntityTooLarge, problem.CodeBodyTooLarge, "request body too large")
+						return
+					}
+					problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidEncoding, "invalid compressed body")
+					return
+				}
+				defer reader.Close()
+				r.Body = reader
+				r.Header.Del("Content-Encoding")
+				r.ContentLength = -1
+			}
+
+			// WebSocket захватывает соединение, его ответ не оборачивается
+			if r.Header.Get("Upgrade") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Ответ зависит от Accept-Encoding, это нужно учитывать кэшам
+			w.Header().Add("Vary", "Accept-Encoding")
+			encoding := compression.Negotiate(r.Header.Get("Accept-Encoding"))
+			if encoding == "" || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{ResponseWriter: w, encoding: encoding, minSize: minSize}
+			defer cw.Close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"io"
+	"net/http"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/encryption"
+	"github.com/akorablin/yandex-practicum-metrics/internal/problem"
+)
+
+// Decrypt расшифровывает тела запросов с заголовком X-Encryption закрытым ключом сервера.
+// Стоит первым, до распаковки и разбора JSON. Без ключа зашифрованные запросы отклоняются
+func Decrypt(priv *rsa.PrivateKey) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scheme := r.Header.Get(encryption.Header)
+			if scheme == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if priv == nil || scheme != encryption.Scheme {
+				problem.Write(w, r, http.StatusBadRequest, problem.CodeUnsupportedEncoding, "unsupported encryption")
+				return
+			}
+
+			data, err := io.ReadAll(r.Body)
+			if IsBodyTooLarge(err) {
+				problem.Write(w, r, http.StatusRequestEntityTooLarge, problem.CodeBodyTooLarge, "request body too large")
+				return
+			}
+			if err != nil {
+				problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "failed to read body")
+				return
+			}
+			plaintext, err := encryption.Decrypt(priv, data)
+			if err != nil {
+				problem.Write(w, r, http.StatusBadRequest, problem.CodeDecryptFailed, err.Error())
+				return
+			}
+
+			r.Body = io.NopCloser(bytes.NewReader(plaintext))
+			r.ContentLength = int64(len(plaintext))
+			r.Header.Del(encryption.Header)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -1,57 +0,0 @@
-package middleware
-
-import (
-	"compress/gzip"
-	"io"
-	"net/http"
-	"strings"
-)
-
-type gzipResponseWriter struct {
-	http.ResponseWriter
-	writer io.Writer
-}
-
-func (w gzipResponseWriter) Write(b []byte) (int, error) {
-	return w.writer.Write(b)
-}
-
-func GzipMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Распаковка gzip тела запроса, если есть
-		if r.Header.Get("Content-Encoding") == "gzip" {
-			gzipReader, err := gzip.NewReader(r.Body)
-			if err != nil {
-				http.Error(w, "Invalid gzip body", http.StatusBadRequest)
-				return
-			}
-			defer gzipReader.Close()
-			r.Body = gzipReader
-		}
-
-		// Проверка заголовков "Accept" и "Content-Type"
-		contentType := r.Header.Get("Content-Type")
-		accept := r.Header.Get("Accept")
-		if contentType != "application/json" && contentType != "text/html" && accept != "application/json" && accept != "text/html" {
-			next.ServeHTTP(w, r)
-			return
-		}
-
-		// Сжатие ответа, если клиент поддерживает gzip
-		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
-			next.ServeHTTP(w, r)
-			return
-		}
-
-		// Подготавливаем gzip writer
-		w.Header().Set("Content-Encoding", "gzip")
-		gzipWriter := gzip.NewWriter(w)
-		defer gzipWriter.Close()
-
-		// Оборачиваем ResponseWriter
-		grw := gzipResponseWriter{ResponseWriter: w, writer: gzipWriter}
-
-		// Запускаем следующий обработчик с оберткой
-		next.ServeHTTP(grw, r)
-	})
-}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"slices"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/problem"
+	"github.com/akorablin/yandex-practicum-metrics/internal/sign"
+)
+
+type hashResponseWriter struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (w *hashResponseWriter) WriteHeader(code int) {
+	w.statusCode = code
+}
+
+func (w *hashResponseWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+// Hash проверяет подпись HMAC-SHA256 тела запроса в заголовке HashSHA256.
+// Запросы, сохраняющие метрики, без подписи отклоняются; ответ на подписанный запрос тоже подписывается.
+// Для запросов без тела (POST /update/{type}/{name}/{value}) подписывается путь.
+// На маршрутах unsignedRoutes подпись необязательна (клиенты, не умеющие подписывать)
+func Hash(key string, unsignedRoutes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if key == "" {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hash := r.Header.Get(sign.Header)
+			if hash == "" {
+				if isStoringRequest(r) && !slices.Contains(unsignedRoutes, r.URL.Path) {
+					problem.Write(w, r, http.StatusBadRequest, problem.CodeMissingSignature, "missing signature")
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if IsBodyTooLarge(err) {
+				problem.Write(w, r, http.StatusRequestEntityTooLarge, problem.CodeBodyTooLarge, "request body too large")
+				return
+			}
+			if err != nil {
+				problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "failed to read body")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			data := body
+			if len(data) == 0 {
+				data = []byte(r.URL.Path)
+			}
+			if !sign.Valid(data, key, hash) {
+				problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidSignature, "invalid signature")
+				return
+			}
+
+			// Ответ буферизуется, чтобы подпись была в заголовке
+			hw := &hashResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(hw, r)
+
+			w.Header().Set(sign.Header, sign.Sum(hw.body.Bytes(), key))
+			w.WriteHeader(hw.statusCode)
+			w.Write(hw.body.Bytes())
+		})
+	}
+}
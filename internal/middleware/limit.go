@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/problem"
+)
+
+// BodyLimit ограничивает размер тела запроса: заявленный Content-Length проверяется сразу,
+// фактическое чтение обрывается ошибкой *http.MaxBytesError. До Compression ограничивает
+// сжатое тело, после — распакованное. Нулевой лимит отключает проверку
+func BodyLimit(limit int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if limit <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > limit {
+				problem.Write(w, r, http.StatusRequestEntityTooLarge, problem.CodeBodyTooLarge, "request body too large")
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// IsBodyTooLarge сообщает, что чтение тела прервано лимитом BodyLimit
+func IsBodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
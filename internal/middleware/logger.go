@@ -32,7 +32,24 @@ func (r *loggingResponseWriter) WriteHeader(statusCode int) {
 	r.responseData.status = statusCode // захватываем код статуса
 }
 
-func Logging(logger zap.Logger) func(http.Handler) http.Handler {
+func (r *loggingResponseWriter) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// redactedURI возвращает адрес запроса без значения access_token
+func redactedURI(r *http.Request) string {
+	query := r.URL.Query()
+	if !query.Has(AccessTokenParam) {
+		return r.RequestURI
+	}
+	query.Set(AccessTokenParam, "REDACTED")
+	return r.URL.Path + "?" + query.Encode()
+}
+
+// RequestObserver получает те же данные запроса, что попадают в лог
+type RequestObserver func(r *http.Request, status int, duration time.Duration, size int)
+
+func Logging(logger zap.Logger, observers ...RequestObserver) func(http.Handler) http.Handler {
 	return func(h http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
@@ -50,12 +67,15 @@ func Logging(logger zap.Logger) func(http.Handler) http.Handler {
 			duration := time.Since(start)
 
 			logger.Info("HTTP request",
-				zap.String("uri", r.RequestURI),
+				zap.String("uri", redactedURI(r)),
 				zap.String("method", r.Method),
 				zap.Int("status", responseData.status),
 				zap.Duration("duration", duration),
 				zap.Int("size", responseData.size),
 			)
+			for _, observe := range observers {
+				observe(r, responseData.status, duration, responseData.size)
+			}
 		})
 	}
 }
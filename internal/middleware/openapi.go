@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/problem"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+)
+
+// OpenAPIValidator проверяет запросы по спецификации OpenAPI.
+// Запросы к маршрутам, которых нет в спецификации, передаются дальше без проверки.
+// Тело запросов к streamedRoutes не проверяется: обработчик разбирает его потоково
+// и прерывает чтение на лимите, а валидатор прочитал бы и разобрал тело целиком
+func OpenAPIValidator(spec []byte, streamedRoutes ...string) (func(http.Handler) http.Handler, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load openapi spec: %w", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("invalid openapi spec: %w", err)
+	}
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build openapi router: %w", err)
+	}
+
+	streamed := make(map[string]bool, len(streamedRoutes))
+	for _, route := range streamedRoutes {
+		streamed[route] = true
+	}
+
+	options := &openapi3filter.Options{
+		MultiError:         true,
+		AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route, pathParams, err := router.FindRoute(r)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Тело проверяется только для JSON: прочие форматы (line protocol, protobuf)
+			// разбираются и проверяются собственными парсерами обработчиков
+			opts := *options
+			contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			opts.ExcludeRequestBody = contentType != "application/json" || streamed[r.URL.Path]
+
+			input := &openapi3filter.RequestValidationInput{
+				Request:    r,
+				PathParams: pathParams,
+				Route:      route,
+				Options:    &opts,
+			}
+			if err := openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+				if IsBodyTooLarge(err) {
+					problem.Write(w, r, http.StatusRequestEntityTooLarge, problem.CodeBodyTooLarge, "request body too large")
+					return
+				}
+				problem.Write(w, r, http.StatusBadRequest, problem.CodeValidationFailed, "request does not match the api schema", validationDetails(err)...)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}, nil
+}
+
+// validationDetails превращает ошибки kin-openapi в список строк вида "<где>: <причина>"
+func validationDetails(err error) []string {
+	var reqErr *openapi3filter.RequestError
+	if errors.As(err, &reqErr) {
+		location := "request"
+		switch {
+		case reqErr.Parameter != nil:
+			location = fmt.Sprintf("%s parameter %q", reqErr.Parameter.In, reqErr.Parameter.Name)
+		case reqErr.RequestBody != nil:
+			location = "body"
+		}
+		if reqErr.Err == nil {
+			return []string{location + ": " + reqErr.Reason}
+		}
+		return schemaDetails(location, reqErr.Err)
+	}
+
+	var multi openapi3.MultiError
+	if errors.As(err, &multi) {
+		var details []string
+		for _, e := range multi {
+			details = append(details, validationDetails(e)...)
+		}
+		return details
+	}
+
+	var routeErr *routers.RouteError
+	if errors.As(err, &routeErr) {
+		return []string{routeErr.Reason}
+	}
+
+	return []string{err.Error()}
+}
+
+// schemaDetails раскрывает вложенные ошибки схемы (например, для oneOf) до конкретных полей
+func schemaDetails(location string, err error) []string {
+	var multi openapi3.MultiError
+	if errors.As(err, &multi) {
+		var details []string
+		for _, e := range multi {
+			details = append(details, schemaDetails(location, e)...)
+		}
+		return details
+	}
+
+	var schemaErr *openapi3.SchemaError
+	if errors.As(err, &schemaErr) {
+		if pointer := schemaErr.JSONPointer(); len(pointer) > 0 {
+			location += "." + strings.Join(pointer, ".")
+		}
+		if schemaErr.Origin != nil {
+			return schemaDetails(location, schemaErr.Origin)
+		}
+		return []string{location + ": " + schemaErr.Reason}
+	}
+
+	return []string{location + ": " + err.Error()}
+}
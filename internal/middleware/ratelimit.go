@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/problem"
+	"github.com/akorablin/yandex-practicum-metrics/internal/ratelimit"
+)
+
+// RateLimit ограничивает частоту запросов на приём метрик для каждого клиента.
+// identify определяет клиента, onThrottle вызывается на каждый отклонённый запрос
+func RateLimit(limiter *ratelimit.Limiter, identify func(*http.Request) string, onThrottle func(reason string)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if limiter == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !isStoringRequest(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if ok, wait := limiter.Allow(identify(r)); !ok {
+				onThrottle(ratelimit.ReasonRate)
+				SetRetryAfter(w, wait)
+				problem.Write(w, r, http.StatusTooManyRequests, problem.CodeRateLimited, "too many requests")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// SetRetryAfter записывает заголовок Retry-After в целых секундах с округлением вверх
+func SetRetryAfter(w http.ResponseWriter, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(max(seconds, 1)))
+}
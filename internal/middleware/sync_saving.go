@@ -18,6 +18,10 @@ func (r *responseWriter) WriteHeader(code int) {
 	r.ResponseWriter.WriteHeader(code)
 }
 
+func (r *responseWriter) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
 func SyncSaving(next http.Handler, file *file.Files) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		rw := &responseWriter{
@@ -28,10 +32,10 @@ func SyncSaving(next http.Handler, file *file.Files) http.Handler {
 		// Запускаем следующий обработчик с оберткой
 		next.ServeHTTP(rw, r)
 
-		// Сохраняем после успешного POST запроса к /update
-		if r.Method == http.MethodPost &&
-			(strings.HasPrefix(r.URL.Path, "/update/") || r.URL.Path == "/update") &&
-			rw.statusCode == http.StatusOK {
+		// Сохраняем после успешного POST запроса к /update, /updates, /write или /v1/metrics,
+		// после записи и удаления через /api/v1/metrics и после регистрации метаданных PUT /meta/{name}
+		if (isStoringRequest(r) || isDeleteRequest(r)) &&
+			rw.statusCode >= http.StatusOK && rw.statusCode < http.StatusMultipleChoices {
 			if err := file.Save(); err != nil {
 				log.Printf("Failed to save metrics: %v", err)
 			} else {
@@ -40,3 +44,21 @@ func SyncSaving(next http.Handler, file *file.Files) http.Handler {
 		}
 	})
 }
+
+func isStoringRequest(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodPost:
+		return strings.HasPrefix(r.URL.Path, "/update") || r.URL.Path == "/write" || r.URL.Path == "/v1/metrics" ||
+			strings.HasPrefix(r.URL.Path, apiMetricsPrefix)
+	case http.MethodPut:
+		return strings.HasPrefix(r.URL.Path, "/meta/") || strings.HasPrefix(r.URL.Path, apiMetricsPrefix)
+	}
+	return false
+}
+
+const apiMetricsPrefix = "/api/v1/metrics/"
+
+// isDeleteRequest — удаление метрики DELETE /api/v1/metrics/{type}/{name}
+func isDeleteRequest(r *http.Request) bool {
+	return r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, apiMetricsPrefix)
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/problem"
+	"github.com/akorablin/yandex-practicum-metrics/internal/trusted"
+)
+
+// TrustedSubnet отклоняет запросы на приём и удаление метрик от адресов вне доверенной подсети
+func TrustedSubnet(checker *trusted.Checker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if checker == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isStoringRequest(r) || isDeleteRequest(r) {
+				ip := checker.ClientIP(r.RemoteAddr, r.Header.Get(trusted.Header), r.Header.Get("X-Forwarded-For"))
+				if !checker.Allowed(ip) {
+					problem.Write(w, r, http.StatusForbidden, problem.CodeUntrustedAddress, "address is outside trusted subnet")
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
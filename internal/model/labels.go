@@ -0,0 +1,44 @@
+package models
+
+import (
+	"sort"
+	"strings"
+)
+
+// Пока у метрик нет отдельного поля для меток, они «сплющиваются» в ID
+// в формате тегов Graphite: name;key1=value1;key2=value2 (ключи отсортированы)
+const labelSeparator = ";"
+
+func FlattenLabels(name string, labels map[string]string) string {
+	if len(labels) == 0 {
+		return name
+	}
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(name)
+	for _, k := range keys {
+		b.WriteString(labelSeparator)
+		b.WriteString(k)
+		b.WriteString("=")
+		b.WriteString(labels[k])
+	}
+	return b.String()
+}
+
+// ParseLabels выполняет обратное преобразование ID в имя и метки
+func ParseLabels(id string) (string, map[string]string) {
+	parts := strings.Split(id, labelSeparator)
+	labels := make(map[string]string, len(parts)-1)
+	for _, part := range parts[1:] {
+		if k, v, ok := strings.Cut(part, "="); ok && k != "" {
+			labels[k] = v
+		}
+	}
+	return parts[0], labels
+}
@@ -0,0 +1,11 @@
+package models
+
+// Metadata описывает семейство метрик по имени без меток: для requests;code=200
+// используются метаданные requests. Type — ожидаемый тип, пустой если не задан
+type Metadata struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Unit        string `json:"unit,omitempty"`
+	Owner       string `json:"owner,omitempty"`
+	Type        string `json:"type,omitempty"`
+}
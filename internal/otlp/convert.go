@@ -0,0 +1,179 @@
+package otlp
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+)
+
+// SeriesTTL — через сколько забывается кумулятивный ряд, от которого не приходило точек.
+// Экспортёры OTLP присылают точки раз в десятки секунд, поэтому час с запасом покрывает паузы
+const SeriesTTL = time.Hour
+
+type cumulativeState struct {
+	start uint64
+	value int64
+	seen  time.Time
+}
+
+// Pending — новые значения кумулятивных рядов из одного запроса.
+// Они применяются через Converter.Commit только после успешной записи, чтобы повтор отклонённого запроса
+// снова дал те же приращения
+type Pending map[string]cumulativeState
+
+// Converter отображает метрики OTLP на gauge/counter.
+// Для монотонных кумулятивных Sum хранится последнее значение ряда, чтобы вычислять приращение;
+// ряды без точек дольше ttl удаляются, чтобы состояние не росло с каждым новым набором атрибутов
+type Converter struct {
+	mu        sync.Mutex
+	last      map[string]cumulativeState
+	ttl       time.Duration
+	lastSweep time.Time
+}
+
+func NewConverter(ttl time.Duration) *Converter {
+	return &Converter{
+		last:      make(map[string]cumulativeState),
+		ttl:       ttl,
+		lastSweep: time.Now(),
+	}
+}
+
+// Convert поддерживает Gauge и Sum; гистограммы и summary пропускаются.
+// Атрибуты ресурса и точки данных переносятся в метки через models.FlattenLabels.
+// Состояние кумулятивных рядов не меняется: его нужно зафиксировать через Commit после записи метрик
+func (c *Converter) Convert(req *colmetricspb.ExportMetricsServiceRequest) ([]models.Metrics, Pending) {
+	var metrics []models.Metrics
+	pending := make(Pending)
+	for _, rm := range req.GetResourceMetrics() {
+		resourceLabels := attributesToLabels(nil, rm.GetResource().GetAttributes())
+
+		for _, sm := range rm.GetScopeMetrics() {
+			for _, metric := range sm.GetMetrics() {
+				switch data := metric.GetData().(type) {
+				case *metricspb.Metric_Gauge:
+					for _, dp := range data.Gauge.GetDataPoints() {
+						id := seriesID(metric.GetName(), resourceLabels, dp)
+						metrics = append(metrics, gauge(id, numberValue(dp)))
+					}
+
+				case *metricspb.Metric_Sum:
+					sum := data.Sum
+					for _, dp := range sum.GetDataPoints() {
+						id := seriesID(metric.GetName(), resourceLabels, dp)
+						switch {
+						case !sum.GetIsMonotonic():
+							metrics = append(metrics, gauge(id, numberValue(dp)))
+						case sum.GetAggregationTemporality() == metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA:
+							metrics = append(metrics, counter(id, int64(math.Round(numberValue(dp)))))
+						default:
+							metrics = append(metrics, counter(id, c.delta(pending, id, dp)))
+						}
+					}
+				}
+			}
+		}
+	}
+	return metrics, pending
+}
+
+// Commit запоминает значения рядов из успешно записанного запроса.
+// Более старая точка того же ряда из параллельного запроса не откатывает уже сохранённое значение
+func (c *Converter) Commit(pending Pending) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for id, state := range pending {
+		if prev, ok := c.last[id]; ok && prev.start == state.start && prev.value > state.value {
+			continue
+		}
+		c.last[id] = state
+	}
+}
+
+// delta переводит кумулятивное значение в приращение относительно предыдущей точки.
+// Первая точка ряда и сброс счётчика (смена StartTime или уменьшение значения) дают всё значение целиком
+func (c *Converter) delta(pending Pending, id string, dp *metricspb.NumberDataPoint) int64 {
+	current := int64(math.Round(numberValue(dp)))
+	now := time.Now()
+
+	// Повтор ряда в том же запросе считается от предыдущей точки запроса
+	prev, ok := pending[id]
+	if !ok {
+		c.mu.Lock()
+		c.sweep(now)
+		prev, ok = c.last[id]
+		c.mu.Unlock()
+	}
+	pending[id] = cumulativeState{start: dp.GetStartTimeUnixNano(), value: current, seen: now}
+	if !ok || prev.start != dp.GetStartTimeUnixNano() || current < prev.value {
+		return current
+	}
+	return current - prev.value
+}
+
+// sweep не чаще раза в ttl удаляет ряды, от которых не было точек дольше ttl
+func (c *Converter) sweep(now time.Time) {
+	if now.Sub(c.lastSweep) < c.ttl {
+		return
+	}
+	c.lastSweep = now
+	for id, state := range c.last {
+		if now.Sub(state.seen) > c.ttl {
+			delete(c.last, id)
+		}
+	}
+}
+
+func seriesID(name string, resourceLabels map[string]string, dp *metricspb.NumberDataPoint) string {
+	return models.FlattenLabels(name, attributesToLabels(resourceLabels, dp.GetAttributes()))
+}
+
+func attributesToLabels(base map[string]string, attrs []*commonpb.KeyValue) map[string]string {
+	labels := make(map[string]string, len(base)+len(attrs))
+	for k, v := range base {
+		labels[k] = v
+	}
+	for _, kv := range attrs {
+		if value, ok := anyValueString(kv.GetValue()); ok {
+			labels[kv.GetKey()] = value
+		}
+	}
+	return labels
+}
+
+// anyValueString поддерживает только скалярные значения атрибутов
+func anyValueString(v *commonpb.AnyValue) (string, bool) {
+	switch value := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return value.StringValue, true
+	case *commonpb.AnyValue_BoolValue:
+		return strconv.FormatBool(value.BoolValue), true
+	case *commonpb.AnyValue_IntValue:
+		return strconv.FormatInt(value.IntValue, 10), true
+	case *commonpb.AnyValue_DoubleValue:
+		return strconv.FormatFloat(value.DoubleValue, 'f', -1, 64), true
+	}
+	return "", false
+}
+
+func numberValue(dp *metricspb.NumberDataPoint) float64 {
+	if v, ok := dp.GetValue().(*metricspb.NumberDataPoint_AsInt); ok {
+		return float64(v.AsInt)
+	}
+	return dp.GetAsDouble()
+}
+
+func gauge(id string, value float64) models.Metrics {
+	return models.Metrics{ID: id, MType: models.Gauge, Value: &value}
+}
+
+func counter(id string, delta int64) models.Metrics {
+	return models.Metrics{ID: id, MType: models.Counter, Delta: &delta}
+}
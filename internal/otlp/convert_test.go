@@ -0,0 +1,137 @@
+package otlp_test
+
+import (
+	"testing"
+	"time"
+
+	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
+	"github.com/akorablin/yandex-practicum-metrics/internal/otlp"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+func request(metrics ...*metricspb.Metric) *colmetricspb.ExportMetricsServiceRequest {
+	return &colmetricspb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{{
+			Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{{
+				Key:   "service.name",
+				Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "checkout"}},
+			}}},
+			ScopeMetrics: []*metricspb.ScopeMetrics{{Metrics: metrics}},
+		}},
+	}
+}
+
+func cumulativeSum(name string, start uint64, value int64) *metricspb.Metric {
+	return &metricspb.Metric{
+		Name: name,
+		Data: &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+			IsMonotonic:            true,
+			AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+			DataPoints: []*metricspb.NumberDataPoint{{
+				StartTimeUnixNano: start,
+				Value:             &metricspb.NumberDataPoint_AsInt{AsInt: value},
+			}},
+		}},
+	}
+}
+
+func TestConvertGauge(t *testing.T) {
+	converter := otlp.NewConverter(otlp.SeriesTTL)
+
+	metrics, _ := converter.Convert(request(&metricspb.Metric{
+		Name: "queue.size",
+		Data: &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{
+			DataPoints: []*metricspb.NumberDataPoint{{Value: &metricspb.NumberDataPoint_AsDouble{AsDouble: 4.5}}},
+		}},
+	}))
+
+	if len(metrics) != 1 {
+		t.Fatalf("Expected 1 metric, got %d", len(metrics))
+	}
+	if m := metrics[0]; m.ID != "queue.size;service.name=checkout" || m.MType != models.Gauge || *m.Value != 4.5 {
+		t.Errorf("Unexpected metric: %+v", m)
+	}
+}
+
+func TestConvertCumulativeSum(t *testing.T) {
+	converter := otlp.NewConverter(otlp.SeriesTTL)
+
+	// Последовательность кумулятивных значений и ожидаемые приращения
+	steps := []struct {
+		start uint64
+		value int64
+		delta int64
+	}{
+		{1, 10, 10},
+		{1, 15, 5},
+		{1, 15, 0},
+		{2, 3, 3}, // перезапуск источника
+	}
+
+	for i, step := range steps {
+		metrics, pending := converter.Convert(request(cumulativeSum("requests", step.start, step.value)))
+		converter.Commit(pending)
+		if len(metrics) != 1 || metrics[0].MType != models.Counter {
+			t.Fatalf("step %d: expected 1 counter, got %+v", i, metrics)
+		}
+		if *metrics[0].Delta != step.delta {
+			t.Errorf("step %d: expected delta %d, got %d", i, step.delta, *metrics[0].Delta)
+		}
+	}
+}
+
+func TestConvertRetryAfterReject(t *testing.T) {
+	converter := otlp.NewConverter(otlp.SeriesTTL)
+
+	convert := func(value int64) (int64, otlp.Pending) {
+		t.Helper()
+		metrics, pending := converter.Convert(request(cumulativeSum("requests", 1, value)))
+		if len(metrics) != 1 {
+			t.Fatalf("Expected 1 metric, got %d", len(metrics))
+		}
+		return *metrics[0].Delta, pending
+	}
+
+	_, pending := convert(10)
+	converter.Commit(pending)
+
+	// Запрос отклонён, состояние не зафиксировано — повтор того же значения даёт то же приращение
+	if delta, _ := convert(15); delta != 5 {
+		t.Fatalf("Expected delta 5, got %d", delta)
+	}
+	delta, pending := convert(15)
+	if delta != 5 {
+		t.Errorf("Expected retry to keep delta 5, got %d", delta)
+	}
+	converter.Commit(pending)
+
+	if delta, _ := convert(15); delta != 0 {
+		t.Errorf("Expected delta 0 after commit, got %d", delta)
+	}
+}
+
+func TestConvertForgetsIdleSeries(t *testing.T) {
+	converter := otlp.NewConverter(20 * time.Millisecond)
+
+	convert := func(name string, value int64) int64 {
+		t.Helper()
+		metrics, pending := converter.Convert(request(cumulativeSum(name, 1, value)))
+		converter.Commit(pending)
+		if len(metrics) != 1 {
+			t.Fatalf("Expected 1 metric, got %d", len(metrics))
+		}
+		return *metrics[0].Delta
+	}
+
+	convert("idle", 10)
+	time.Sleep(50 * time.Millisecond)
+	convert("active", 1) // очередная точка запускает очистку
+
+	// Состояние ряда удалено, поэтому его следующая точка считается первой
+	if delta := convert("idle", 15); delta != 15 {
+		t.Errorf("Expected idle series to be forgotten, got delta %d", delta)
+	}
+}
@@ -0,0 +1,108 @@
+package problem
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+)
+
+// ContentType ответа с ошибкой (RFC 7807)
+const ContentType = "application/problem+json"
+
+// TypePrefix образует URI типа ошибки из её кода
+const TypePrefix = "urn:metrics:problem:"
+
+// Машиночитаемые коды ошибок
+const (
+	CodeInvalidRequest       = "invalid_request"
+	CodeInvalidJSON          = "invalid_json"
+	CodeValidationFailed     = "validation_failed"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeUnknownMetricType    = "unknown_metric_type"
+	CodeInvalidValue         = "invalid_value"
+	CodeNotFound             = "not_found"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodeTypeMismatch         = "type_mismatch"
+	CodeEmptyBatch           = "empty_batch"
+	CodeBodyTooLarge         = "body_too_large"
+	CodeLimitExceeded        = "limit_exceeded"
+	CodeRateLimited          = "rate_limited"
+	CodeQuotaExceeded        = "quota_exceeded"
+	CodeUnauthorized         = "unauthorized"
+	CodeForbidden            = "forbidden"
+	CodeUntrustedAddress     = "untrusted_address"
+	CodeClientCertRequired   = "client_cert_required"
+	CodeMissingSignature     = "missing_signature"
+	CodeInvalidSignature     = "invalid_signature"
+	CodeUnsupportedEncoding  = "unsupported_encoding"
+	CodeInvalidEncoding      = "invalid_encoding"
+	CodeDecryptFailed        = "decrypt_failed"
+	CodeFeatureDisabled      = "feature_disabled"
+	CodeStorageUnavailable   = "storage_unavailable"
+	CodeInternal             = "internal_error"
+)
+
+// Problem описывает ошибку в формате RFC 7807 с расширениями code и errors
+type Problem struct {
+	Type     string   `json:"type"`
+	Title    string   `json:"title"`
+	Status   int      `json:"status"`
+	Detail   string   `json:"detail,omitempty"`
+	Instance string   `json:"instance,omitempty"`
+	Code     string   `json:"code"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+func New(status int, code, detail string, errs ...string) *Problem {
+	return &Problem{
+		Type:   TypePrefix + code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+		Errors: errs,
+	}
+}
+
+func (p *Problem) Error() string {
+	if p.Detail == "" {
+		return fmt.Sprintf("%s (%d)", p.Code, p.Status)
+	}
+	return fmt.Sprintf("%s (%d): %s", p.Code, p.Status, p.Detail)
+}
+
+// Retryable сообщает, имеет ли смысл повторить запрос позже без изменений
+func (p *Problem) Retryable() bool {
+	switch p.Code {
+	case CodeRateLimited, CodeStorageUnavailable, CodeInternal:
+		return true
+	}
+	return false
+}
+
+// Write отправляет ошибку клиенту; instance — путь запроса
+func Write(w http.ResponseWriter, r *http.Request, status int, code, detail string, errs ...string) {
+	p := New(status, code, detail, errs...)
+	if r != nil {
+		p.Instance = r.URL.Path
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// Parse разбирает тело ответа, если оно в формате problem+json
+func Parse(contentType string, body []byte) (*Problem, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != ContentType {
+		return nil, false
+	}
+	var p Problem
+	if err := json.Unmarshal(body, &p); err != nil || p.Code == "" {
+		return nil, false
+	}
+	return &p, true
+}
@@ -0,0 +1,59 @@
+package problem_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/problem"
+)
+
+func TestWriteAndParse(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/updates/", nil)
+	rec := httptest.NewRecorder()
+	problem.Write(rec, req, http.StatusBadRequest, problem.CodeValidationFailed, "validation failed", "metric[0]: id is required")
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", rec.Code)
+	}
+	p, ok := problem.Parse(rec.Header().Get("Content-Type"), rec.Body.Bytes())
+	if !ok {
+		t.Fatalf("Parse failed for %q", rec.Body.String())
+	}
+	want := problem.Problem{
+		Type:     "urn:metrics:problem:validation_failed",
+		Title:    "Bad Request",
+		Status:   http.StatusBadRequest,
+		Detail:   "validation failed",
+		Instance: "/updates/",
+		Code:     problem.CodeValidationFailed,
+	}
+	if p.Type != want.Type || p.Title != want.Title || p.Status != want.Status || p.Detail != want.Detail ||
+		p.Instance != want.Instance || p.Code != want.Code || len(p.Errors) != 1 {
+		t.Errorf("Parse() = %+v, want %+v", p, want)
+	}
+
+	if _, ok := problem.Parse("application/json", rec.Body.Bytes()); ok {
+		t.Error("Parse() accepted a body without problem+json content type")
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{problem.CodeRateLimited, true},
+		{problem.CodeStorageUnavailable, true},
+		{problem.CodeInternal, true},
+		{problem.CodeValidationFailed, false},
+		{problem.CodeQuotaExceeded, false},
+		{problem.CodeUnauthorized, false},
+	}
+
+	for _, tt := range tests {
+		if got := problem.New(http.StatusBadRequest, tt.code, "").Retryable(); got != tt.want {
+			t.Errorf("Retryable(%s) = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
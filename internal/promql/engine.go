@@ -0,0 +1,495 @@
+package promql
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/history"
+	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
+)
+
+// LookbackDelta — насколько старую точку ещё можно считать текущим значением ряда
+const LookbackDelta = 5 * time.Minute
+
+// MaxPoints ограничивает число шагов range-запроса, как в Prometheus
+const MaxPoints = 11000
+
+const metricNameLabel = "__name__"
+
+// TypeLabel — метка с типом ряда (gauge или counter): gauge и counter с одним ID — разные ряды
+const TypeLabel = "type"
+
+var ErrEval = errors.New("evaluation error")
+
+type ValueType string
+
+const (
+	ValueScalar ValueType = "scalar"
+	ValueVector ValueType = "vector"
+	ValueMatrix ValueType = "matrix"
+)
+
+type Labels map[string]string
+
+// signature строит ключ набора меток для группировки и сопоставления рядов
+func (l Labels) signature(keep func(name string) bool) string {
+	names := make([]string, 0, len(l))
+	for name := range l {
+		if keep(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name)
+		b.WriteByte('\xff')
+		b.WriteString(l[name])
+		b.WriteByte('\xff')
+	}
+	return b.String()
+}
+
+func (l Labels) withoutName() Labels {
+	out := make(Labels, len(l))
+	for k, v := range l {
+		if k != metricNameLabel {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+type Point struct {
+	T time.Time
+	V float64
+}
+
+type Sample struct {
+	Metric Labels
+	Point
+}
+
+type Series struct {
+	Metric Labels
+	Points []Point
+}
+
+type Value interface {
+	Type() ValueType
+}
+
+type Scalar Point
+
+type Vector []Sample
+
+type Matrix []Series
+
+func (Scalar) Type() ValueType { return ValueScalar }
+func (Vector) Type() ValueType { return ValueVector }
+func (Matrix) Type() ValueType { return ValueMatrix }
+
+// Source — источник исторических рядов, по умолчанию history.Store
+type Source interface {
+	Series() []history.Series
+	Range(key history.Series, from, to time.Time) []history.Sample
+}
+
+type Engine struct {
+	source Source
+}
+
+func NewEngine(source Source) *Engine {
+	return &Engine{source: source}
+}
+
+// Instant вычисляет выражение в момент ts
+func (e *Engine) Instant(query string, ts time.Time) (Value, error) {
+	expr, err := Parse(query)
+	if err != nil {
+		return nil, err
+	}
+	return e.eval(expr, ts)
+}
+
+// Range вычисляет выражение на каждом шаге от start до end и собирает результат в матрицу
+func (e *Engine) Range(query string, start, end time.Time, step time.Duration) (Matrix, error) {
+	if step <= 0 {
+		return nil, fmt.Errorf("%w: step must be positive", ErrEval)
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("%w: end must not be before start", ErrEval)
+	}
+	if end.Sub(start)/step >= MaxPoints {
+		return nil, fmt.Errorf("%w: exceeded maximum resolution of %d points per timeseries", ErrEval, MaxPoints)
+	}
+
+	expr, err := Parse(query)
+	if err != nil {
+		return nil, err
+	}
+	if sel, ok := expr.(*VectorSelector); ok && sel.Range > 0 {
+		return nil, fmt.Errorf("%w: range query expression must be a scalar or instant vector", ErrEval)
+	}
+
+	series := make(map[string]*Series)
+	var order []string
+	add := func(metric Labels, p Point) {
+		key := metric.signature(func(string) bool { return true })
+		s, ok := series[key]
+		if !ok {
+			s = &Series{Metric: metric}
+			series[key] = s
+			order = append(order, key)
+		}
+		s.Points = append(s.Points, p)
+	}
+
+	for ts := start; !ts.After(end); ts = ts.Add(step) {
+		v, err := e.eval(expr, ts)
+		if err != nil {
+			return nil, err
+		}
+		switch v := v.(type) {
+		case Scalar:
+			add(Labels{}, Point(v))
+		case Vector:
+			for _, s := range v {
+				add(s.Metric, s.Point)
+			}
+		}
+	}
+
+	sort.Strings(order)
+	result := make(Matrix, 0, len(order))
+	for _, key := range order {
+		result = append(result, *series[key])
+	}
+	return result, nil
+}
+
+func (e *Engine) eval(expr Expr, ts time.Time) (Value, error) {
+	switch expr := expr.(type) {
+	case *NumberLiteral:
+		return Scalar{T: ts, V: expr.Val}, nil
+	case *VectorSelector:
+		return e.evalSelector(expr, ts), nil
+	case *Call:
+		arg, err := e.eval(expr.Arg, ts)
+		if err != nil {
+			return nil, err
+		}
+		return evalCall(expr.Func, arg.(Matrix), ts), nil
+	case *Aggregate:
+		v, err := e.eval(expr.Expr, ts)
+		if err != nil {
+			return nil, err
+		}
+		vec, ok := v.(Vector)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s expects an instant vector, got %s", ErrEval, expr.Op, v.Type())
+		}
+		return evalAggregate(expr, vec, ts), nil
+	case *Binary:
+		lhs, err := e.eval(expr.LHS, ts)
+		if err != nil {
+			return nil, err
+		}
+		rhs, err := e.eval(expr.RHS, ts)
+		if err != nil {
+			return nil, err
+		}
+		return evalBinary(expr.Op, lhs, rhs, ts)
+	default:
+		return nil, fmt.Errorf("%w: unsupported expression %T", ErrEval, expr)
+	}
+}
+
+// evalSelector возвращает последнюю точку каждого подходящего ряда либо,
+// для range vector, все точки за окно
+func (e *Engine) evalSelector(sel *VectorSelector, ts time.Time) Value {
+	window := LookbackDelta
+	if sel.Range > 0 {
+		window = sel.Range
+	}
+
+	var (
+		vector Vector
+		matrix Matrix
+	)
+	for _, key := range e.source.Series() {
+		name, labels := models.ParseLabels(key.ID)
+		metric := Labels(labels)
+		metric[metricNameLabel] = name
+		metric[TypeLabel] = key.MType
+		if !selects(sel, metric) {
+			continue
+		}
+
+		samples := e.source.Range(key, ts.Add(-window), ts)
+		if len(samples) == 0 {
+			continue
+		}
+		if sel.Range == 0 {
+			last := samples[len(samples)-1]
+			vector = append(vector, Sample{Metric: metric, Point: Point{T: ts, V: last.Value}})
+			continue
+		}
+		points := make([]Point, len(samples))
+		for i, s := range samples {
+			points[i] = Point{T: s.Time, V: s.Value}
+		}
+		matrix = append(matrix, Series{Metric: metric, Points: points})
+	}
+
+	if sel.Range > 0 {
+		return matrix
+	}
+	return vector
+}
+
+func selects(sel *VectorSelector, metric Labels) bool {
+	if sel.Name != "" && metric[metricNameLabel] != sel.Name {
+		return false
+	}
+	for _, m := range sel.Matchers {
+		if !m.Matches(metric[m.Name]) {
+			return false
+		}
+	}
+	return true
+}
+
+func evalCall(fn string, matrix Matrix, ts time.Time) Vector {
+	var result Vector
+	for _, s := range matrix {
+		var (
+			v  float64
+			ok = true
+		)
+		switch fn {
+		case "rate", "increase":
+			// Для rate и increase нужны минимум две точки; сбросы счётчика учитываются
+			if len(s.Points) < 2 {
+				ok = false
+				break
+			}
+			v = counterIncrease(s.Points)
+			if fn == "rate" {
+				v /= s.Points[len(s.Points)-1].T.Sub(s.Points[0].T).Seconds()
+			}
+		case "avg_over_time":
+			for _, p := range s.Points {
+				v += p.V
+			}
+			v /= float64(len(s.Points))
+		case "sum_over_time":
+			for _, p := range s.Points {
+				v += p.V
+			}
+		case "min_over_time":
+			v = math.Inf(1)
+			for _, p := range s.Points {
+				v = math.Min(v, p.V)
+			}
+		case "max_over_time":
+			v = math.Inf(-1)
+			for _, p := range s.Points {
+				v = math.Max(v, p.V)
+			}
+		case "count_over_time":
+			v = float64(len(s.Points))
+		}
+		if ok {
+			result = append(result, Sample{Metric: s.Metric.withoutName(), Point: Point{T: ts, V: v}})
+		}
+	}
+	return result
+}
+
+func counterIncrease(points []Point) float64 {
+	var inc float64
+	for i := 1; i < len(points); i++ {
+		if points[i].V < points[i-1].V {
+			inc += points[i].V
+		} else {
+			inc += points[i].V - points[i-1].V
+		}
+	}
+	return inc
+}
+
+func evalAggregate(agg *Aggregate, vec Vector, ts time.Time) Vector {
+	grouping := make(map[string]bool, len(agg.Grouping))
+	for _, l := range agg.Grouping {
+		grouping[l] = true
+	}
+	keep := func(name string) bool {
+		if agg.Without {
+			return !grouping[name] && name != metricNameLabel
+		}
+		return grouping[name]
+	}
+
+	type group struct {
+		metric Labels
+		value  float64
+		count  int
+	}
+	groups := make(map[string]*group)
+	var order []string
+
+	for _, s := range vec {
+		key := s.Metric.signature(keep)
+		g, ok := groups[key]
+		if !ok {
+			metric := make(Labels)
+			for k, v := range s.Metric {
+				if keep(k) {
+					metric[k] = v
+				}
+			}
+			g = &group{metric: metric, value: s.V}
+			groups[key] = g
+			order = append(order, key)
+		} else {
+			switch agg.Op {
+			case "sum", "avg":
+				g.value += s.V
+			case "min":
+				g.value = math.Min(g.value, s.V)
+			case "max":
+				g.value = math.Max(g.value, s.V)
+			}
+		}
+		g.count++
+	}
+
+	sort.Strings(order)
+	result := make(Vector, 0, len(order))
+	for _, key := range order {
+		g := groups[key]
+		switch agg.Op {
+		case "avg":
+			g.value /= float64(g.count)
+		case "count":
+			g.value = float64(g.count)
+		}
+		result = append(result, Sample{Metric: g.metric, Point: Point{T: ts, V: g.value}})
+	}
+	return result
+}
+
+func evalBinary(op string, lhs, rhs Value, ts time.Time) (Value, error) {
+	if lhs.Type() == ValueMatrix || rhs.Type() == ValueMatrix {
+		return nil, fmt.Errorf("%w: binary expression must contain only scalar and instant vector types", ErrEval)
+	}
+	comparison := binaryPrecedence[op] == 1
+
+	switch l := lhs.(type) {
+	case Scalar:
+		switch r := rhs.(type) {
+		case Scalar:
+			if comparison {
+				return nil, fmt.Errorf("%w: comparisons between scalars are not supported", ErrEval)
+			}
+			v, _ := apply(op, l.V, r.V)
+			return Scalar{T: ts, V: v}, nil
+		case Vector:
+			return vectorScalar(op, r, l.V, true), nil
+		}
+	case Vector:
+		switch r := rhs.(type) {
+		case Scalar:
+			return vectorScalar(op, l, r.V, false), nil
+		case Vector:
+			return vectorVector(op, l, r)
+		}
+	}
+	return nil, fmt.Errorf("%w: unsupported operands for %s", ErrEval, op)
+}
+
+// vectorScalar применяет операцию к каждому элементу вектора. Сравнения работают как фильтр
+func vectorScalar(op string, vec Vector, scalar float64, scalarLeft bool) Vector {
+	comparison := binaryPrecedence[op] == 1
+	result := make(Vector, 0, len(vec))
+	for _, s := range vec {
+		a, b := s.V, scalar
+		if scalarLeft {
+			a, b = b, a
+		}
+		v, keep := apply(op, a, b)
+		if comparison {
+			if keep {
+				result = append(result, s)
+			}
+			continue
+		}
+		result = append(result, Sample{Metric: s.Metric.withoutName(), Point: Point{T: s.T, V: v}})
+	}
+	return result
+}
+
+// vectorVector сопоставляет ряды один к одному по всем меткам, кроме имени и типа метрики,
+// чтобы counter и gauge можно было сочетать в одном выражении
+func vectorVector(op string, lhs, rhs Vector) (Vector, error) {
+	comparison := binaryPrecedence[op] == 1
+	noName := func(name string) bool { return name != metricNameLabel && name != TypeLabel }
+
+	right := make(map[string]Sample, len(rhs))
+	for _, s := range rhs {
+		key := s.Metric.signature(noName)
+		if _, dup := right[key]; dup {
+			return nil, fmt.Errorf("%w: many-to-many matching not allowed: found duplicate series on the right side of %s", ErrEval, op)
+		}
+		right[key] = s
+	}
+
+	var result Vector
+	for _, s := range lhs {
+		r, ok := right[s.Metric.signature(noName)]
+		if !ok {
+			continue
+		}
+		v, keep := apply(op, s.V, r.V)
+		if comparison {
+			if keep {
+				result = append(result, s)
+			}
+			continue
+		}
+		result = append(result, Sample{Metric: s.Metric.withoutName(), Point: Point{T: s.T, V: v}})
+	}
+	return result, nil
+}
+
+// apply возвращает результат арифметики либо, для сравнений, признак истинности
+func apply(op string, a, b float64) (float64, bool) {
+	switch op {
+	case "+":
+		return a + b, true
+	case "-":
+		return a - b, true
+	case "*":
+		return a * b, true
+	case "/":
+		return a / b, true
+	case "==":
+		return a, a == b
+	case "!=":
+		return a, a != b
+	case ">":
+		return a, a > b
+	case "<":
+		return a, a < b
+	case ">=":
+		return a, a >= b
+	default:
+		return a, a <= b
+	}
+}
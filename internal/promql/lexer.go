@@ -0,0 +1,140 @@
+package promql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokDuration
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// lex разбивает выражение на токены. Длительности распознаются только внутри [...]
+func lex(input string) ([]token, error) {
+	var tokens []token
+	inRange := false
+
+	for i := 0; i < len(input); {
+		c := rune(input[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case inRange && isDigit(c):
+			start := i
+			for i < len(input) && (isDigit(rune(input[i])) || unicode.IsLetter(rune(input[i]))) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokDuration, text: input[start:i], pos: start})
+		case isDigit(c) || (c == '.' && i+1 < len(input) && isDigit(rune(input[i+1]))):
+			start := i
+			for i < len(input) && (isDigit(rune(input[i])) || input[i] == '.' || input[i] == 'e' || input[i] == 'E' ||
+				((input[i] == '+' || input[i] == '-') && (input[i-1] == 'e' || input[i-1] == 'E'))) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: input[start:i], pos: start})
+		case isIdentStart(c):
+			start := i
+			for i < len(input) && isIdentChar(rune(input[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: input[start:i], pos: start})
+		case c == '"' || c == '\'':
+			start := i
+			i++
+			var b strings.Builder
+			for ; i < len(input) && rune(input[i]) != c; i++ {
+				if input[i] == '\\' && i+1 < len(input) {
+					i++
+				}
+				b.WriteByte(input[i])
+			}
+			if i >= len(input) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			i++
+			tokens = append(tokens, token{kind: tokString, text: b.String(), pos: start})
+		default:
+			start := i
+			if i+1 < len(input) {
+				switch input[i : i+2] {
+				case "!=", "=~", "!~", "==", ">=", "<=":
+					tokens = append(tokens, token{kind: tokOp, text: input[i : i+2], pos: start})
+					i += 2
+					continue
+				}
+			}
+			if !strings.ContainsRune("(){}[],=+-*/<>", c) {
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+			}
+			switch c {
+			case '[':
+				inRange = true
+			case ']':
+				inRange = false
+			}
+			tokens = append(tokens, token{kind: tokOp, text: string(c), pos: start})
+			i++
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(input)}), nil
+}
+
+func isDigit(c rune) bool { return c >= '0' && c <= '9' }
+
+func isIdentStart(c rune) bool { return c == '_' || c == ':' || unicode.IsLetter(c) }
+
+// Помимо символов PromQL в именах допускается точка: так называются метрики из StatsD и OTLP
+func isIdentChar(c rune) bool { return isIdentStart(c) || isDigit(c) || c == '.' }
+
+// ParseDuration разбирает длительности в формате Prometheus: 30s, 5m, 1h30m, 1d, 1w
+func ParseDuration(s string) (time.Duration, error) {
+	units := map[string]time.Duration{
+		"ms": time.Millisecond,
+		"s":  time.Second,
+		"m":  time.Minute,
+		"h":  time.Hour,
+		"d":  24 * time.Hour,
+		"w":  7 * 24 * time.Hour,
+		"y":  365 * 24 * time.Hour,
+	}
+
+	var total time.Duration
+	rest := s
+	for rest != "" {
+		i := 0
+		for i < len(rest) && isDigit(rune(rest[i])) {
+			i++
+		}
+		j := i
+		for j < len(rest) && !isDigit(rune(rest[j])) {
+			j++
+		}
+		n, err := strconv.Atoi(rest[:i])
+		unit, ok := units[rest[i:j]]
+		if err != nil || !ok {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		total += time.Duration(n) * unit
+		rest = rest[j:]
+	}
+	if total <= 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return total, nil
+}
@@ -0,0 +1,356 @@
+package promql
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var ErrParse = errors.New("parse error")
+
+type Expr interface{}
+
+type NumberLiteral struct {
+	Val float64
+}
+
+type MatchType string
+
+const (
+	MatchEqual     MatchType = "="
+	MatchNotEqual  MatchType = "!="
+	MatchRegexp    MatchType = "=~"
+	MatchNotRegexp MatchType = "!~"
+)
+
+type Matcher struct {
+	Name  string
+	Type  MatchType
+	Value string
+	re    *regexp.Regexp
+}
+
+func (m *Matcher) Matches(v string) bool {
+	switch m.Type {
+	case MatchEqual:
+		return v == m.Value
+	case MatchNotEqual:
+		return v != m.Value
+	case MatchRegexp:
+		return m.re.MatchString(v)
+	default:
+		return !m.re.MatchString(v)
+	}
+}
+
+// VectorSelector выбирает ряды по имени и меткам. С Range > 0 это range vector (metric[5m])
+type VectorSelector struct {
+	Name     string
+	Matchers []*Matcher
+	Range    time.Duration
+}
+
+type Call struct {
+	Func string
+	Arg  Expr
+}
+
+type Aggregate struct {
+	Op       string
+	Grouping []string
+	Without  bool
+	Expr     Expr
+}
+
+type Binary struct {
+	Op       string
+	LHS, RHS Expr
+}
+
+var aggregateOps = map[string]bool{"sum": true, "avg": true, "min": true, "max": true, "count": true}
+
+// Функции, принимающие range vector
+var rangeFuncs = map[string]bool{
+	"rate":            true,
+	"increase":        true,
+	"avg_over_time":   true,
+	"min_over_time":   true,
+	"max_over_time":   true,
+	"sum_over_time":   true,
+	"count_over_time": true,
+}
+
+var binaryPrecedence = map[string]int{
+	"==": 1, "!=": 1, ">": 1, "<": 1, ">=": 1, "<=": 1,
+	"+": 2, "-": 2,
+	"*": 3, "/": 3,
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// Parse разбирает выражение поддерживаемого подмножества PromQL
+func Parse(input string) (Expr, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrParse, err)
+	}
+	p := &parser{tokens: tokens}
+	expr, err := p.parseBinary(1)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrParse, err)
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("%w: unexpected %q at position %d", ErrParse, t.text, t.pos)
+	}
+	return expr, nil
+}
+
+func (p *parser) peek() token { return p.tokens[p.pos] }
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) expect(op string) error {
+	t := p.next()
+	if t.kind != tokOp || t.text != op {
+		return unexpected(t, op)
+	}
+	return nil
+}
+
+func unexpected(t token, want string) error {
+	if t.kind == tokEOF {
+		return fmt.Errorf("unexpected end of input, expected %s", want)
+	}
+	return fmt.Errorf("unexpected %q at position %d, expected %s", t.text, t.pos, want)
+}
+
+// parseBinary разбирает бинарные операции методом precedence climbing
+func (p *parser) parseBinary(minPrec int) (Expr, error) {
+	lhs, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		prec, ok := binaryPrecedence[t.text]
+		if t.kind != tokOp || !ok || prec < minPrec {
+			return lhs, nil
+		}
+		p.next()
+		rhs, err := p.parseBinary(prec + 1)
+		if err != nil {
+			return nil, err
+		}
+		lhs = &Binary{Op: t.text, LHS: lhs, RHS: rhs}
+	}
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	t := p.peek()
+	if t.kind == tokOp && (t.text == "-" || t.text == "+") {
+		p.next()
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if t.text == "+" {
+			return expr, nil
+		}
+		if n, ok := expr.(*NumberLiteral); ok {
+			return &NumberLiteral{Val: -n.Val}, nil
+		}
+		return &Binary{Op: "*", LHS: &NumberLiteral{Val: -1}, RHS: expr}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	t := p.peek()
+	switch {
+	case t.kind == tokNumber:
+		p.next()
+		v, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", t.text)
+		}
+		return &NumberLiteral{Val: v}, nil
+	case t.kind == tokOp && t.text == "(":
+		p.next()
+		expr, err := p.parseBinary(1)
+		if err != nil {
+			return nil, err
+		}
+		return expr, p.expect(")")
+	case t.kind == tokOp && t.text == "{":
+		return p.parseSelector("")
+	case t.kind == tokIdent:
+		p.next()
+		next := p.peek()
+		opensCall := next.kind == tokOp && next.text == "("
+		if aggregateOps[t.text] && (opensCall || next.kind == tokIdent && (next.text == "by" || next.text == "without")) {
+			return p.parseAggregate(t.text)
+		}
+		if rangeFuncs[t.text] && opensCall {
+			return p.parseCall(t.text)
+		}
+		if opensCall {
+			return nil, fmt.Errorf("unknown function %q", t.text)
+		}
+		return p.parseSelector(t.text)
+	default:
+		return nil, unexpected(t, "expression")
+	}
+}
+
+func (p *parser) parseCall(name string) (Expr, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	arg, err := p.parseBinary(1)
+	if err != nil {
+		return nil, err
+	}
+	if sel, ok := arg.(*VectorSelector); !ok || sel.Range == 0 {
+		return nil, fmt.Errorf("function %s expects a range vector argument", name)
+	}
+	return &Call{Func: name, Arg: arg}, p.expect(")")
+}
+
+// parseAggregate поддерживает обе формы: sum by (l) (expr) и sum (expr) by (l)
+func (p *parser) parseAggregate(op string) (Expr, error) {
+	agg := &Aggregate{Op: op}
+
+	grouping := func() error {
+		t := p.peek()
+		if t.kind != tokIdent || (t.text != "by" && t.text != "without") {
+			return nil
+		}
+		p.next()
+		agg.Without = t.text == "without"
+		if err := p.expect("("); err != nil {
+			return err
+		}
+		for {
+			t := p.next()
+			if t.kind == tokOp && t.text == ")" && len(agg.Grouping) == 0 {
+				return nil
+			}
+			if t.kind != tokIdent {
+				return unexpected(t, "label name")
+			}
+			agg.Grouping = append(agg.Grouping, t.text)
+			sep := p.next()
+			if sep.kind == tokOp && sep.text == ")" {
+				return nil
+			}
+			if sep.kind != tokOp || sep.text != "," {
+				return unexpected(sep, "\",\" or \")\"")
+			}
+		}
+	}
+
+	if err := grouping(); err != nil {
+		return nil, err
+	}
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	expr, err := p.parseBinary(1)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	if agg.Grouping == nil && !agg.Without {
+		if err := grouping(); err != nil {
+			return nil, err
+		}
+	}
+	agg.Expr = expr
+	return agg, nil
+}
+
+func (p *parser) parseSelector(name string) (Expr, error) {
+	sel := &VectorSelector{Name: name}
+
+	if t := p.peek(); t.kind == tokOp && t.text == "{" {
+		p.next()
+		for {
+			t := p.next()
+			if t.kind == tokOp && t.text == "}" {
+				break
+			}
+			if t.kind != tokIdent {
+				return nil, unexpected(t, "label name")
+			}
+			op := p.next()
+			mt := MatchType(op.text)
+			if op.kind != tokOp || (mt != MatchEqual && mt != MatchNotEqual && mt != MatchRegexp && mt != MatchNotRegexp) {
+				return nil, unexpected(op, "label matcher")
+			}
+			value := p.next()
+			if value.kind != tokString {
+				return nil, unexpected(value, "string")
+			}
+			m := &Matcher{Name: t.text, Type: mt, Value: value.text}
+			if mt == MatchRegexp || mt == MatchNotRegexp {
+				re, err := regexp.Compile("^(?:" + value.text + ")$")
+				if err != nil {
+					return nil, fmt.Errorf("invalid regexp %q: %v", value.text, err)
+				}
+				m.re = re
+			}
+			sel.Matchers = append(sel.Matchers, m)
+
+			sep := p.next()
+			if sep.kind == tokOp && sep.text == "}" {
+				break
+			}
+			if sep.kind != tokOp || sep.text != "," {
+				return nil, unexpected(sep, "\",\" or \"}\"")
+			}
+		}
+	}
+
+	if sel.Name == "" {
+		// Как и в Prometheus, селектор без имени должен иметь хотя бы одно непустое условие
+		nonEmpty := false
+		for _, m := range sel.Matchers {
+			if !m.Matches("") {
+				nonEmpty = true
+			}
+		}
+		if !nonEmpty {
+			return nil, fmt.Errorf("vector selector must contain at least one non-empty matcher")
+		}
+	}
+
+	if t := p.peek(); t.kind == tokOp && t.text == "[" {
+		p.next()
+		d := p.next()
+		if d.kind != tokDuration {
+			return nil, unexpected(d, "duration")
+		}
+		rng, err := ParseDuration(d.text)
+		if err != nil {
+			return nil, err
+		}
+		sel.Range = rng
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+	}
+	return sel, nil
+}
@@ -0,0 +1,255 @@
+package promql_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/config"
+	"github.com/akorablin/yandex-practicum-metrics/internal/history"
+	"github.com/akorablin/yandex-practicum-metrics/internal/promql"
+	"github.com/akorablin/yandex-practicum-metrics/internal/repository/memory"
+)
+
+// newEngine заполняет историю снимками раз в 10 секунд: счётчики растут на 10 за шаг,
+// а у requests с code=500 на четвёртом шаге происходит сброс
+func newEngine(t *testing.T) (*promql.Engine, time.Time) {
+	t.Helper()
+	cfg := &config.ServerConfig{HistoryInterval: 10, HistoryRetention: 3600}
+	repo := memory.New(cfg)
+	store := history.New(cfg, repo)
+
+	start := time.Unix(1700000000, 0)
+	for i := 0; i <= 6; i++ {
+		repo.UpdateGauge("temp;room=kitchen", float64(20+i))
+		repo.UpdateGauge("temp;room=hall", 10)
+		repo.UpdateCounter("requests;code=200", 10)
+		if i == 3 {
+			// Имитация перезапуска источника: значение счётчика падает до 5
+			repo.UpdateCounter("requests;code=500", -25)
+		} else {
+			repo.UpdateCounter("requests;code=500", 10)
+		}
+		store.Snapshot(start.Add(time.Duration(i) * 10 * time.Second))
+	}
+	return promql.NewEngine(store), start.Add(60 * time.Second)
+}
+
+func values(t *testing.T, v promql.Value) map[string]float64 {
+	t.Helper()
+	vec, ok := v.(promql.Vector)
+	if !ok {
+		t.Fatalf("Expected vector, got %s", v.Type())
+	}
+	out := make(map[string]float64, len(vec))
+	for _, s := range vec {
+		key := s.Metric["__name__"] + "{room=" + s.Metric["room"] + ",code=" + s.Metric["code"] + "}"
+		out[key] = s.V
+	}
+	return out
+}
+
+func TestInstant(t *testing.T) {
+	engine, now := newEngine(t)
+
+	tests := []struct {
+		name  string
+		query string
+		want  map[string]float64
+	}{
+		{
+			name:  "Селектор по имени",
+			query: "temp",
+			want:  map[string]float64{"temp{room=kitchen,code=}": 26, "temp{room=hall,code=}": 10},
+		},
+		{
+			name:  "Метка с равенством",
+			query: `temp{room="hall"}`,
+			want:  map[string]float64{"temp{room=hall,code=}": 10},
+		},
+		{
+			name:  "Метка с неравенством и регулярным выражением",
+			query: `{__name__=~"req.*", code!="200"}`,
+			want:  map[string]float64{"requests{room=,code=500}": 35},
+		},
+		{
+			name:  "Отрицательное регулярное выражение",
+			query: `requests{code!~"5.."}`,
+			want:  map[string]float64{"requests{room=,code=200}": 70},
+		},
+		{
+			name:  "rate учитывает сброс счётчика",
+			query: "rate(requests[1m])",
+			// code=500: 10,20,30,5,15,25,35 — прирост 20 + 5 + 30 = 55 за 60 секунд
+			want: map[string]float64{"{room=,code=200}": 1, "{room=,code=500}": 55.0 / 60},
+		},
+		{
+			name:  "increase за окно",
+			query: `increase(requests{code="200"}[30s])`,
+			want:  map[string]float64{"{room=,code=200}": 30},
+		},
+		{
+			name:  "avg_over_time",
+			query: `avg_over_time(temp{room="kitchen"}[1m])`,
+			want:  map[string]float64{"{room=kitchen,code=}": 23},
+		},
+		{
+			name:  "sum by",
+			query: "sum by (room) (temp)",
+			want:  map[string]float64{"{room=kitchen,code=}": 26, "{room=hall,code=}": 10},
+		},
+		{
+			name:  "sum без группировки и постфиксный without",
+			query: "sum(requests) + count(temp) without (room)",
+			want:  map[string]float64{"{room=,code=}": 107},
+		},
+		{
+			name:  "Арифметика с приоритетом",
+			query: `temp{room="hall"} * 2 + 1`,
+			want:  map[string]float64{"{room=hall,code=}": 21},
+		},
+		{
+			name:  "Вектор с вектором",
+			query: `temp - temp / 2`,
+			want:  map[string]float64{"{room=kitchen,code=}": 13, "{room=hall,code=}": 5},
+		},
+		{
+			name:  "Сравнение фильтрует ряды",
+			query: "temp > 15",
+			want:  map[string]float64{"temp{room=kitchen,code=}": 26},
+		},
+		{
+			name:  "Неизвестная метрика",
+			query: "missing",
+			want:  map[string]float64{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := engine.Instant(tt.query, now)
+			if err != nil {
+				t.Fatalf("Instant(%q) failed: %v", tt.query, err)
+			}
+			got := values(t, v)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Instant(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+			for k, want := range tt.want {
+				if math.Abs(got[k]-want) > 1e-9 {
+					t.Errorf("Instant(%q)[%s] = %v, want %v", tt.query, k, got[k], want)
+				}
+			}
+		})
+	}
+}
+
+func TestInstantScalarAndMatrix(t *testing.T) {
+	engine, now := newEngine(t)
+
+	v, err := engine.Instant("-(1 + 2) * 4", now)
+	if err != nil {
+		t.Fatalf("Instant() failed: %v", err)
+	}
+	if s, ok := v.(promql.Scalar); !ok || s.V != -12 {
+		t.Errorf("Expected scalar -12, got %#v", v)
+	}
+
+	v, err = engine.Instant(`temp{room="hall"}[25s]`, now)
+	if err != nil {
+		t.Fatalf("Instant() failed: %v", err)
+	}
+	m, ok := v.(promql.Matrix)
+	if !ok || len(m) != 1 || len(m[0].Points) != 3 {
+		t.Errorf("Expected one series with 3 points, got %#v", v)
+	}
+}
+
+func TestRange(t *testing.T) {
+	engine, now := newEngine(t)
+
+	m, err := engine.Range(`temp{room="kitchen"}`, now.Add(-20*time.Second), now, 10*time.Second)
+	if err != nil {
+		t.Fatalf("Range() failed: %v", err)
+	}
+	if len(m) != 1 || len(m[0].Points) != 3 {
+		t.Fatalf("Expected one series with 3 points, got %#v", m)
+	}
+	for i, want := range []float64{24, 25, 26} {
+		if m[0].Points[i].V != want {
+			t.Errorf("Point %d = %v, want %v", i, m[0].Points[i].V, want)
+		}
+	}
+
+	if _, err := engine.Range("temp[1m]", now.Add(-time.Minute), now, time.Second); !errors.Is(err, promql.ErrEval) {
+		t.Errorf("Expected ErrEval for range vector, got %v", err)
+	}
+	if _, err := engine.Range("temp", now.Add(-24*time.Hour), now, time.Second); !errors.Is(err, promql.ErrEval) {
+		t.Errorf("Expected ErrEval for too many points, got %v", err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	invalid := []string{
+		"",
+		"temp{",
+		`temp{room="a"`,
+		`temp{room=~"("}`,
+		`{room=~".*"}`,
+		"rate(temp)",
+		"temp[5x]",
+		"unknown(temp[1m])",
+		"sum by (room temp",
+		"1 +",
+		"temp)",
+	}
+	for _, query := range invalid {
+		if _, err := promql.Parse(query); !errors.Is(err, promql.ErrParse) {
+			t.Errorf("Parse(%q): expected ErrParse, got %v", query, err)
+		}
+	}
+}
+
+func TestTypeLabel(t *testing.T) {
+	cfg := &config.ServerConfig{HistoryInterval: 10, HistoryRetention: 3600}
+	repo := memory.New(cfg)
+	store := history.New(cfg, repo)
+	now := time.Unix(1700000000, 0)
+
+	// gauge и counter с одним ID — разные ряды
+	repo.UpdateGauge("load", 1.5)
+	repo.UpdateCounter("load", 3)
+	store.Snapshot(now)
+	engine := promql.NewEngine(store)
+
+	tests := []struct {
+		name  string
+		query string
+		want  map[string]float64
+	}{
+		{"Оба ряда", "load", map[string]float64{"gauge": 1.5, "counter": 3}},
+		{"Отбор по типу", `load{type="counter"}`, map[string]float64{"counter": 3}},
+		{"Арифметика между типами", `load{type="counter"} * load{type="gauge"}`, map[string]float64{"counter": 4.5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := engine.Instant(tt.query, now)
+			if err != nil {
+				t.Fatalf("Instant(%q): %v", tt.query, err)
+			}
+			got := make(map[string]float64)
+			for _, s := range value.(promql.Vector) {
+				got[s.Metric[promql.TypeLabel]] = s.V
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected %v, got %v", tt.want, got)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("%s: expected %v, got %v", k, v, got[k])
+				}
+			}
+		})
+	}
+}
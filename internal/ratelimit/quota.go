@@ -0,0 +1,143 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
+)
+
+// SeriesWindow — окно, в котором считаются различные ряды клиента
+const SeriesWindow = time.Hour
+
+// Причины отказа, они же значения метки reason счётчика отказов
+const (
+	ReasonRate   = "rate"
+	ReasonBatch  = "batch"
+	ReasonSeries = "series"
+)
+
+// QuotaError описывает превышение квоты. RetryAfter равен нулю, если повтор того же запроса бесполезен
+type QuotaError struct {
+	Reason     string
+	Limit      int
+	RetryAfter time.Duration
+}
+
+func (e *QuotaError) Error() string {
+	switch e.Reason {
+	case ReasonBatch:
+		return fmt.Sprintf("batch exceeds quota of %d metrics", e.Limit)
+	case ReasonSeries:
+		return fmt.Sprintf("quota of %d distinct series per %s exceeded", e.Limit, SeriesWindow)
+	}
+	return "quota exceeded"
+}
+
+type tenantSeries struct {
+	windowStart time.Time
+	series      map[string]struct{}
+}
+
+// Quota ограничивает число метрик в одном запросе и число различных рядов клиента за SeriesWindow.
+// Нулевой лимит отключает соответствующую проверку
+type Quota struct {
+	mu        sync.Mutex
+	maxBatch  int
+	maxSeries int
+	tenants   map[string]*tenantSeries
+}
+
+func NewQuota(maxBatch, maxSeries int) *Quota {
+	return &Quota{
+		maxBatch:  maxBatch,
+		maxSeries: maxSeries,
+		tenants:   make(map[string]*tenantSeries),
+	}
+}
+
+// Admit проверяет квоты клиента, не запоминая ряды: их учитывает Record после успешной записи,
+// чтобы запрос, отклонённый хранилищем, не расходовал квоту. Параллельные запросы одного клиента
+// поэтому могут вместе превысить квоту рядов на размер одного пакета
+func (q *Quota) Admit(tenant string, metrics []models.Metrics) error {
+	if q.maxBatch > 0 && len(metrics) > q.maxBatch {
+		return &QuotaError{Reason: ReasonBatch, Limit: q.maxBatch}
+	}
+	if q.maxSeries <= 0 {
+		return nil
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	t := q.window(tenant, now)
+	added := make(map[string]struct{})
+	for _, m := range metrics {
+		key := seriesKey(m)
+		if _, ok := t.series[key]; ok {
+			continue
+		}
+		if _, ok := added[key]; ok {
+			continue
+		}
+		if len(t.series)+len(added) >= q.maxSeries {
+			return &QuotaError{Reason: ReasonSeries, Limit: q.maxSeries, RetryAfter: t.windowStart.Add(SeriesWindow).Sub(now)}
+		}
+		added[key] = struct{}{}
+	}
+	return nil
+}
+
+// Record запоминает ряды успешно записанного запроса
+func (q *Quota) Record(tenant string, metrics []models.Metrics) {
+	if q.maxSeries <= 0 {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	t := q.window(tenant, time.Now())
+	for _, m := range metrics {
+		t.series[seriesKey(m)] = struct{}{}
+	}
+}
+
+// window возвращает текущее окно клиента, начиная новое после истечения SeriesWindow
+func (q *Quota) window(tenant string, now time.Time) *tenantSeries {
+	t, ok := q.tenants[tenant]
+	if !ok || now.Sub(t.windowStart) >= SeriesWindow {
+		t = &tenantSeries{windowStart: now, series: make(map[string]struct{})}
+		q.tenants[tenant] = t
+	}
+	return t
+}
+
+func seriesKey(m models.Metrics) string {
+	return m.MType + ":" + m.ID
+}
+
+// Run удаляет истёкшие окна клиентов до отмены контекста
+func (q *Quota) Run(ctx context.Context) {
+	ticker := time.NewTicker(SeriesWindow)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			q.mu.Lock()
+			now := time.Now()
+			for tenant, t := range q.tenants {
+				if now.Sub(t.windowStart) >= SeriesWindow {
+					delete(q.tenants, tenant)
+				}
+			}
+			q.mu.Unlock()
+		}
+	}
+}
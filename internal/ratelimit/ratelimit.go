@@ -0,0 +1,100 @@
+// Package ratelimit ограничивает частоту запросов на приём метрик по клиентам (token bucket)
+// и квоты клиента на размер пакета и число различных рядов
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/config"
+)
+
+// AgentIDHeader — заголовок с идентификатором агента (в gRPC — метаданные x-agent-id)
+const AgentIDHeader = "X-Agent-ID"
+
+// ThrottledMetric — собственный счётчик сервера с числом отклонённых запросов по причинам
+const ThrottledMetric = "ThrottledRequests"
+
+// ClientKey строит ключ клиента для лимитов и квот способом by (ip, token, agent).
+// Идентификатор агента задаёт сам клиент, поэтому он учитывается только вместе с адресом
+// соединения: сменой X-Agent-ID нельзя выдать себя за агента с другого адреса.
+// Без токена или идентификатора клиентом считается адрес
+func ClientKey(by, ip, token, agentID string) string {
+	switch {
+	case by == config.RateLimitByToken && token != "":
+		return "token:" + token
+	case by == config.RateLimitByAgent && agentID != "":
+		return "agent:" + agentID + "@" + ip
+	}
+	return "ip:" + ip
+}
+
+// idleTimeout — через сколько удаляется корзина клиента, который перестал присылать запросы
+const idleTimeout = 10 * time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter — token bucket на каждого клиента: rate запросов в секунду, всплеск до burst
+type Limiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	buckets map[string]*bucket
+}
+
+func NewLimiter(rate float64, burst int) *Limiter {
+	return &Limiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow забирает токен клиента. Если токенов нет, возвращает время до появления следующего
+func (l *Limiter) Allow(client string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[client] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// Run удаляет корзины неактивных клиентов до отмены контекста
+func (l *Limiter) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			l.mu.Lock()
+			now := time.Now()
+			for client, b := range l.buckets {
+				if now.Sub(b.last) > idleTimeout {
+					delete(l.buckets, client)
+				}
+			}
+			l.mu.Unlock()
+		}
+	}
+}
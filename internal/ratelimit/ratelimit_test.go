@@ -0,0 +1,97 @@
+package ratelimit_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/config"
+	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
+	"github.com/akorablin/yandex-practicum-metrics/internal/ratelimit"
+)
+
+func TestLimiter(t *testing.T) {
+	limiter := ratelimit.NewLimiter(1, 2)
+
+	for i := range 2 {
+		if ok, _ := limiter.Allow("agent-1"); !ok {
+			t.Fatalf("Request %d within burst was rejected", i+1)
+		}
+	}
+	ok, wait := limiter.Allow("agent-1")
+	if ok {
+		t.Fatal("Request over burst was allowed")
+	}
+	if wait <= 0 || wait > time.Second {
+		t.Errorf("Expected wait in (0, 1s], got %v", wait)
+	}
+
+	// Корзины клиентов независимы
+	if ok, _ := limiter.Allow("agent-2"); !ok {
+		t.Error("Other client was throttled")
+	}
+}
+
+func TestQuota(t *testing.T) {
+	gauge := func(id string) models.Metrics { return models.Metrics{ID: id, MType: models.Gauge} }
+	quota := ratelimit.NewQuota(3, 4)
+
+	tests := []struct {
+		name       string
+		tenant     string
+		metrics    []models.Metrics
+		stored     bool
+		wantReason string
+	}{
+		{"Первый пакет", "a", []models.Metrics{gauge("A"), gauge("B"), gauge("B")}, true, ""},
+		{"Пакет больше квоты", "a", []models.Metrics{gauge("C"), gauge("D"), gauge("E"), gauge("F")}, false, ratelimit.ReasonBatch},
+		{"Повтор известных рядов", "a", []models.Metrics{gauge("A"), gauge("B")}, true, ""},
+		{"Запись не удалась — ряды не учитываются", "a", []models.Metrics{gauge("D"), gauge("E")}, false, ""},
+		{"Рядов ровно до квоты", "a", []models.Metrics{gauge("C"), {ID: "C", MType: models.Counter}}, true, ""},
+		{"Новый ряд сверх квоты", "a", []models.Metrics{gauge("A"), gauge("E")}, false, ratelimit.ReasonSeries},
+		{"Квоты клиентов независимы", "b", []models.Metrics{gauge("E")}, true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := quota.Admit(tt.tenant, tt.metrics)
+			var quotaErr *ratelimit.QuotaError
+			switch {
+			case tt.wantReason == "" && err != nil:
+				t.Fatalf("Admit() failed: %v", err)
+			case tt.wantReason != "" && (!errors.As(err, &quotaErr) || quotaErr.Reason != tt.wantReason):
+				t.Fatalf("Admit() error = %v, want reason %s", err, tt.wantReason)
+			}
+			if tt.wantReason == ratelimit.ReasonSeries && quotaErr.RetryAfter <= 0 {
+				t.Error("Series quota error without RetryAfter")
+			}
+			if tt.stored {
+				quota.Record(tt.tenant, tt.metrics)
+			}
+		})
+	}
+}
+
+func TestClientKey(t *testing.T) {
+	tests := []struct {
+		name  string
+		by    string
+		token string
+		agent string
+		want  string
+	}{
+		{"По адресу", config.RateLimitByIP, "agent", "host-1", "ip:10.0.0.1"},
+		{"По токену", config.RateLimitByToken, "agent", "host-1", "token:agent"},
+		{"Без токена — по адресу", config.RateLimitByToken, "", "host-1", "ip:10.0.0.1"},
+		{"Агент вместе с адресом", config.RateLimitByAgent, "", "host-1", "agent:host-1@10.0.0.1"},
+		{"Без идентификатора агента — по адресу", config.RateLimitByAgent, "", "", "ip:10.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ratelimit.ClientKey(tt.by, "10.0.0.1", tt.token, tt.agent); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -12,22 +12,42 @@ import (
 	"github.com/akorablin/yandex-practicum-metrics/internal/config"
 	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
 	"github.com/akorablin/yandex-practicum-metrics/internal/repository/db/errors"
+	"github.com/akorablin/yandex-practicum-metrics/internal/selfmetrics"
+	"github.com/akorablin/yandex-practicum-metrics/internal/storage"
 )
 
+// retriesMetric — число повторов запросов к Postgres после временных ошибок
+const retriesMetric = "PostgresRetries"
+
 type PostgresStorage struct {
 	db              *sql.DB
 	cfg             *config.ServerConfig
 	retryConfig     RetryConfig
 	errorClassifier *errors.PostgresErrorClassifier
+	recorder        *selfmetrics.Recorder
+}
+
+// Option подключает к хранилищу необязательные компоненты
+type Option func(*PostgresStorage)
+
+// WithSelfMetrics включает подсчёт повторов в retryExec
+func WithSelfMetrics(recorder *selfmetrics.Recorder) Option {
+	return func(p *PostgresStorage) {
+		p.recorder = recorder
+	}
 }
 
-func New(cfg *config.ServerConfig, db *sql.DB) *PostgresStorage {
-	return &PostgresStorage{
+func New(cfg *config.ServerConfig, db *sql.DB, opts ...Option) *PostgresStorage {
+	p := &PostgresStorage{
 		db:              db,
 		cfg:             cfg,
 		retryConfig:     DefaultRetryConfig(),
 		errorClassifier: errors.NewPostgresErrorClassifier(),
 	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
 }
 
 type RetryConfig struct {
@@ -45,16 +65,7 @@ func DefaultRetryConfig() RetryConfig {
 }
 
 func (p *PostgresStorage) UpdateGauge(name string, value float64) error {
-	_, err := p.db.Exec(`
-		INSERT INTO metrics (id, mtype, value, delta) 
-		VALUES ($1, $2, $3, NULL)
-		ON CONFLICT (id) 
-		DO UPDATE SET 
-			value = $3,
-			delta = NULL,
-			updated_at = CURRENT_TIMESTAMP
-	`, name, "gauge", value)
-
+	err := p.UpdateMetricsBatch(context.Background(), []models.Metrics{{ID: name, MType: models.Gauge, Value: &value}})
 	if err != nil {
 		log.Printf("Ошибка сохранения gauge метрики: %v", err)
 	}
@@ -63,16 +74,7 @@ func (p *PostgresStorage) UpdateGauge(name string, value float64) error {
 }
 
 func (p *PostgresStorage) UpdateCounter(name string, value int64) error {
-	_, err := p.db.Exec(`
-		INSERT INTO metrics (id, mtype, delta, value) 
-		VALUES ($1, $2, $3, NULL)
-		ON CONFLICT (id) 
-		DO UPDATE SET 
-			delta = COALESCE(metrics.delta, 0) + $3,
-			value = NULL,
-			updated_at = CURRENT_TIMESTAMP
-	`, name, "counter", value)
-
+	err := p.UpdateMetricsBatch(context.Background(), []models.Metrics{{ID: name, MType: models.Counter, Delta: &value}})
 	if err != nil {
 		log.Printf("Ошибка сохранения counter метрики: %v", err)
 	}
@@ -80,13 +82,23 @@ func (p *PostgresStorage) UpdateCounter(name string, value int64) error {
 	return err
 }
 
+// UpdateMetricsBatch сохраняет пакет в одной транзакции. Метрика идентифицируется парой (mtype, id),
+// counters накапливаются, gauges перезаписываются
 func (p *PostgresStorage) UpdateMetricsBatch(ctx context.Context, metrics []models.Metrics) error {
-	tx, err := p.db.Begin()
+	metrics = mergeBatch(metrics)
+
+	tx, err := p.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
+	if p.cfg.TypeConflictPolicy == config.TypeConflictReject {
+		if err := p.checkTypeConflicts(ctx, tx, metrics); err != nil {
+			return err
+		}
+	}
+
 	row := ""
 	countAttr := 0
 	rowsSQL := make([]string, 0, len(metrics))
@@ -106,10 +118,10 @@ func (p *PostgresStorage) UpdateMetricsBatch(ctx context.Context, metrics []mode
 	}
 	sql := fmt.Sprintf(`INSERT INTO metrics (id, mtype, value, delta) 
 		VALUES %s 
-		ON CONFLICT (id) 
+		ON CONFLICT (mtype, id) 
 		DO UPDATE SET 
 			value = EXCLUDED.value,
-			delta = EXCLUDED.delta,
+			delta = COALESCE(metrics.delta, 0) + EXCLUDED.delta,
 			updated_at = CURRENT_TIMESTAMP`,
 		strings.Join(rowsSQL, ", "))
 	err = p.retryExec(ctx, tx, sql, argsSQL...)
@@ -120,13 +132,89 @@ func (p *PostgresStorage) UpdateMetricsBatch(ctx context.Context, metrics []mode
 	return tx.Commit()
 }
 
+// checkTypeConflicts реализует политику reject. Advisory-блокировки по ID держатся до конца
+// транзакции и не дают параллельному запросу создать метрику другого типа между проверкой и записью
+func (p *PostgresStorage) checkTypeConflicts(ctx context.Context, tx *sql.Tx, metrics []models.Metrics) error {
+	batchTypes := make(map[string]string, len(metrics))
+	values := make([]string, 0, len(metrics))
+	placeholders := make([]string, 0, len(metrics))
+	args := make([]any, 0, len(metrics))
+	for _, metric := range metrics {
+		existing, ok := batchTypes[metric.ID]
+		if ok && existing != metric.MType {
+			return storage.TypeConflictError(metric.ID, existing)
+		}
+		if ok {
+			continue
+		}
+		batchTypes[metric.ID] = metric.MType
+		args = append(args, metric.ID)
+		values = append(values, fmt.Sprintf("($%d::text)", len(args)))
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+	}
+
+	// Блокировки берутся в порядке ключей, чтобы параллельные пакеты не блокировали друг друга взаимно
+	_, err := tx.ExecContext(ctx, fmt.Sprintf(`SELECT pg_advisory_xact_lock(key)
+		FROM (SELECT DISTINCT hashtext(id) AS key FROM (VALUES %s) AS v(id) ORDER BY key) AS k`,
+		strings.Join(values, ", ")), args...)
+	if err != nil {
+		return fmt.Errorf("ошибка блокировки метрик: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(
+		"SELECT id, mtype FROM metrics WHERE id IN (%s)", strings.Join(placeholders, ", ")), args...)
+	if err != nil {
+		return fmt.Errorf("ошибка проверки типов метрик: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, mtype string
+		if err := rows.Scan(&id, &mtype); err != nil {
+			return err
+		}
+		if batchTypes[id] != mtype {
+			return storage.TypeConflictError(id, mtype)
+		}
+	}
+	return rows.Err()
+}
+
+// mergeBatch объединяет повторы (mtype, id) внутри пакета: INSERT ... ON CONFLICT
+// не может обновить одну строку дважды. Для gauge остаётся последнее значение, counters суммируются
+func mergeBatch(metrics []models.Metrics) []models.Metrics {
+	type key struct{ mtype, id string }
+	index := make(map[key]int, len(metrics))
+	merged := make([]models.Metrics, 0, len(metrics))
+	for _, metric := range metrics {
+		k := key{metric.MType, metric.ID}
+		i, ok := index[k]
+		if !ok {
+			index[k] = len(merged)
+			merged = append(merged, models.Metrics{ID: metric.ID, MType: metric.MType})
+			i = len(merged) - 1
+		}
+		switch metric.MType {
+		case models.Gauge:
+			value := *metric.Value
+			merged[i].Value = &value
+		case models.Counter:
+			delta := *metric.Delta
+			if merged[i].Delta != nil {
+				delta += *merged[i].Delta
+			}
+			merged[i].Delta = &delta
+		}
+	}
+	return merged
+}
+
 func (p *PostgresStorage) GetGauge(name string) (float64, error) {
 	var value float64
 	err := p.db.QueryRow(
 		"SELECT value FROM metrics WHERE mtype = $1 AND id = $2 AND value IS NOT NULL",
 		"gauge", name).Scan(&value)
 	if err == sql.ErrNoRows {
-		return 0, nil
+		return 0, storage.ErrMetricNotFound
 	}
 	if err != nil {
 		log.Printf("Ошибка получения gauge метрики: %v", err)
@@ -143,7 +231,7 @@ func (p *PostgresStorage) GetCounter(name string) (int64, error) {
 		"counter", name).Scan(&value)
 
 	if err == sql.ErrNoRows {
-		return 0, nil
+		return 0, storage.ErrMetricNotFound
 	}
 	if err != nil {
 		log.Printf("Ошибка получения counter метрики: %v", err)
@@ -202,6 +290,81 @@ func (p *PostgresStorage) GetAllMetrics() (map[string]float64, map[string]int64)
 	return gauges, counters
 }
 
+func (p *PostgresStorage) DeleteMetric(mtype, name string) error {
+	if mtype != models.Gauge && mtype != models.Counter {
+		return storage.ErrInvalidType
+	}
+	result, err := p.db.Exec("DELETE FROM metrics WHERE mtype = $1 AND id = $2", mtype, name)
+	if err != nil {
+		log.Printf("Ошибка удаления метрики: %v", err)
+		return err
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return storage.ErrMetricNotFound
+	}
+	return nil
+}
+
+func (p *PostgresStorage) SetMetadata(meta models.Metadata) error {
+	_, err := p.db.Exec(`
+		INSERT INTO metadata (name, description, unit, owner, mtype)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (name)
+		DO UPDATE SET
+			description = $2,
+			unit = $3,
+			owner = $4,
+			mtype = $5,
+			updated_at = CURRENT_TIMESTAMP
+	`, meta.Name, meta.Description, meta.Unit, meta.Owner, meta.Type)
+
+	if err != nil {
+		log.Printf("Ошибка сохранения метаданных: %v", err)
+	}
+
+	return err
+}
+
+func (p *PostgresStorage) GetMetadata(name string) (models.Metadata, error) {
+	meta := models.Metadata{Name: name}
+	err := p.db.QueryRow(
+		"SELECT description, unit, owner, mtype FROM metadata WHERE name = $1",
+		name).Scan(&meta.Description, &meta.Unit, &meta.Owner, &meta.Type)
+	if err == sql.ErrNoRows {
+		return models.Metadata{}, storage.ErrMetadataNotFound
+	}
+	if err != nil {
+		log.Printf("Ошибка получения метаданных: %v", err)
+		return models.Metadata{}, err
+	}
+
+	return meta, nil
+}
+
+func (p *PostgresStorage) GetAllMetadata() map[string]models.Metadata {
+	all := make(map[string]models.Metadata)
+
+	rows, err := p.db.Query("SELECT name, description, unit, owner, mtype FROM metadata")
+	if err != nil {
+		log.Printf("Ошибка получения метаданных: %v", err)
+		return all
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var meta models.Metadata
+		if err := rows.Scan(&meta.Name, &meta.Description, &meta.Unit, &meta.Owner, &meta.Type); err != nil {
+			log.Printf("Ошибка сканирования метаданных: %v", err)
+			continue
+		}
+		all[meta.Name] = meta
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Ошибка при итерации метаданных: %v", err)
+	}
+
+	return all
+}
+
 func (p *PostgresStorage) retryExec(ctx context.Context, tx *sql.Tx, sql string, argsSQL ...any) error {
 	var lastErr error
 	for attempt := 0; attempt < p.retryConfig.MaxAttempts; attempt++ {
@@ -216,6 +379,9 @@ func (p *PostgresStorage) retryExec(ctx context.Context, tx *sql.Tx, sql string,
 		}
 
 		log.Printf("Попытка %d завершилась ошибкой: %v", attempt+1, err)
+		if attempt+1 < p.retryConfig.MaxAttempts {
+			p.recorder.Add(retriesMetric, nil, 1)
+		}
 
 		delay := p.retryConfig.InitialDelay + (time.Duration(attempt) * p.retryConfig.DelayStep)
 		select {
@@ -3,6 +3,7 @@ package memory
 import (
 	"context"
 	"maps"
+	"sync"
 
 	"github.com/akorablin/yandex-practicum-metrics/internal/config"
 	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
@@ -10,8 +11,10 @@ import (
 )
 
 type MemStorage struct {
+	mu       sync.RWMutex
 	gauges   map[string]float64
 	counters map[string]int64
+	metadata map[string]models.Metadata
 	cfg      *config.ServerConfig
 }
 
@@ -19,34 +22,86 @@ func New(cfg *config.ServerConfig) *MemStorage {
 	return &MemStorage{
 		gauges:   make(map[string]float64),
 		counters: make(map[string]int64),
+		metadata: make(map[string]models.Metadata),
 		cfg:      cfg,
 	}
 }
 
 func (m *MemStorage) UpdateGauge(name string, value float64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkConflict(name, models.Gauge); err != nil {
+		return err
+	}
 	m.gauges[name] = value
 	return nil
 }
 
 func (m *MemStorage) UpdateCounter(name string, value int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkConflict(name, models.Counter); err != nil {
+		return err
+	}
 	m.counters[name] += value
 	return nil
 }
 
+// UpdateMetricsBatch применяет пакет атомарно: при конфликте типов не сохраняется ничего.
+// Counters накапливаются так же, как в UpdateCounter
 func (m *MemStorage) UpdateMetricsBatch(ctx context.Context, metrics []models.Metrics) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.rejectConflicts() {
+		batchTypes := make(map[string]string, len(metrics))
+		for _, metric := range metrics {
+			if existing, ok := batchTypes[metric.ID]; ok && existing != metric.MType {
+				return storage.TypeConflictError(metric.ID, existing)
+			}
+			batchTypes[metric.ID] = metric.MType
+			if err := m.checkConflict(metric.ID, metric.MType); err != nil {
+				return err
+			}
+		}
+	}
+
 	for _, metric := range metrics {
 		switch metric.MType {
-		case "gauge":
+		case models.Gauge:
 			m.gauges[metric.ID] = *metric.Value
-		case "counter":
-			m.counters[metric.ID] = *metric.Delta
+		case models.Counter:
+			m.counters[metric.ID] += *metric.Delta
 		}
 	}
 
 	return nil
 }
 
+func (m *MemStorage) rejectConflicts() bool {
+	return m.cfg != nil && m.cfg.TypeConflictPolicy == config.TypeConflictReject
+}
+
+// checkConflict вызывается под блокировкой
+func (m *MemStorage) checkConflict(name, mtype string) error {
+	if !m.rejectConflicts() {
+		return nil
+	}
+	if _, ok := m.gauges[name]; ok && mtype != models.Gauge {
+		return storage.TypeConflictError(name, models.Gauge)
+	}
+	if _, ok := m.counters[name]; ok && mtype != models.Counter {
+		return storage.TypeConflictError(name, models.Counter)
+	}
+	return nil
+}
+
 func (m *MemStorage) GetGauge(name string) (float64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	value, exists := m.gauges[name]
 	if !exists {
 		return 0, storage.ErrMetricNotFound
@@ -55,6 +110,9 @@ func (m *MemStorage) GetGauge(name string) (float64, error) {
 }
 
 func (m *MemStorage) GetCounter(name string) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	value, exists := m.counters[name]
 	if !exists {
 		return 0, storage.ErrMetricNotFound
@@ -63,6 +121,9 @@ func (m *MemStorage) GetCounter(name string) (int64, error) {
 }
 
 func (m *MemStorage) GetAllMetrics() (map[string]float64, map[string]int64) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	gaugesCopy := make(map[string]float64)
 	countersCopy := make(map[string]int64)
 
@@ -71,3 +132,50 @@ func (m *MemStorage) GetAllMetrics() (map[string]float64, map[string]int64) {
 
 	return gaugesCopy, countersCopy
 }
+
+func (m *MemStorage) DeleteMetric(mtype, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch mtype {
+	case models.Gauge:
+		if _, ok := m.gauges[name]; !ok {
+			return storage.ErrMetricNotFound
+		}
+		delete(m.gauges, name)
+	case models.Counter:
+		if _, ok := m.counters[name]; !ok {
+			return storage.ErrMetricNotFound
+		}
+		delete(m.counters, name)
+	default:
+		return storage.ErrInvalidType
+	}
+	return nil
+}
+
+func (m *MemStorage) SetMetadata(meta models.Metadata) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.metadata[meta.Name] = meta
+	return nil
+}
+
+func (m *MemStorage) GetMetadata(name string) (models.Metadata, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	meta, ok := m.metadata[name]
+	if !ok {
+		return models.Metadata{}, storage.ErrMetadataNotFound
+	}
+	return meta, nil
+}
+
+func (m *MemStorage) GetAllMetadata() map[string]models.Metadata {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return maps.Clone(m.metadata)
+}
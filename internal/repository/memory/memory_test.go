@@ -0,0 +1,108 @@
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/config"
+	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
+	"github.com/akorablin/yandex-practicum-metrics/internal/repository/memory"
+	"github.com/akorablin/yandex-practicum-metrics/internal/storage"
+)
+
+func gauge(id string, v float64) models.Metrics {
+	return models.Metrics{ID: id, MType: models.Gauge, Value: &v}
+}
+
+func counter(id string, d int64) models.Metrics {
+	return models.Metrics{ID: id, MType: models.Counter, Delta: &d}
+}
+
+func TestBatchAccumulatesCounters(t *testing.T) {
+	repo := memory.New(&config.ServerConfig{TypeConflictPolicy: config.TypeConflictSeparate})
+	repo.UpdateCounter("PollCount", 5)
+
+	// Повторы внутри пакета и между пакетами суммируются так же, как в UpdateCounter
+	batch := []models.Metrics{counter("PollCount", 1), counter("PollCount", 2), gauge("Alloc", 1), gauge("Alloc", 2)}
+	if err := repo.UpdateMetricsBatch(context.Background(), batch); err != nil {
+		t.Fatalf("UpdateMetricsBatch() failed: %v", err)
+	}
+
+	if v, _ := repo.GetCounter("PollCount"); v != 8 {
+		t.Errorf("PollCount = %d, want 8", v)
+	}
+	if v, _ := repo.GetGauge("Alloc"); v != 2 {
+		t.Errorf("Alloc = %v, want 2", v)
+	}
+}
+
+func TestTypeConflictPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		update  func(repo *memory.MemStorage) error
+		wantErr bool
+	}{
+		{
+			name:   "separate: counter с именем существующего gauge",
+			policy: config.TypeConflictSeparate,
+			update: func(repo *memory.MemStorage) error { return repo.UpdateCounter("Alloc", 1) },
+		},
+		{
+			name:    "reject: counter с именем существующего gauge",
+			policy:  config.TypeConflictReject,
+			update:  func(repo *memory.MemStorage) error { return repo.UpdateCounter("Alloc", 1) },
+			wantErr: true,
+		},
+		{
+			name:    "reject: gauge с именем существующего counter",
+			policy:  config.TypeConflictReject,
+			update:  func(repo *memory.MemStorage) error { return repo.UpdateGauge("PollCount", 1) },
+			wantErr: true,
+		},
+		{
+			name:   "reject: тот же тип",
+			policy: config.TypeConflictReject,
+			update: func(repo *memory.MemStorage) error { return repo.UpdateGauge("Alloc", 2) },
+		},
+		{
+			name:   "reject: конфликт внутри пакета",
+			policy: config.TypeConflictReject,
+			update: func(repo *memory.MemStorage) error {
+				return repo.UpdateMetricsBatch(context.Background(), []models.Metrics{gauge("New", 1), counter("New", 1)})
+			},
+			wantErr: true,
+		},
+		{
+			name:   "reject: пакет с конфликтом не сохраняется частично",
+			policy: config.TypeConflictReject,
+			update: func(repo *memory.MemStorage) error {
+				return repo.UpdateMetricsBatch(context.Background(), []models.Metrics{gauge("Other", 1), counter("Alloc", 1)})
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := memory.New(&config.ServerConfig{TypeConflictPolicy: tt.policy})
+			repo.UpdateGauge("Alloc", 1)
+			repo.UpdateCounter("PollCount", 1)
+
+			err := tt.update(repo)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("Expected error: %v, got %v", tt.wantErr, err)
+			}
+			if err != nil && !errors.Is(err, storage.ErrTypeMismatch) {
+				t.Errorf("Expected ErrTypeMismatch, got %v", err)
+			}
+			if err != nil {
+				gauges, counters := repo.GetAllMetrics()
+				if len(gauges) != 1 || len(counters) != 1 {
+					t.Errorf("Rejected update changed storage: %v %v", gauges, counters)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package selfmetrics
+
+import (
+	"context"
+	"errors"
+	"log"
+	"maps"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/config"
+	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
+	"github.com/akorablin/yandex-practicum-metrics/internal/storage"
+)
+
+// Buckets — верхние границы корзин гистограмм длительности в секундах
+var Buckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+// Recorder накапливает собственные метрики сервера в памяти и периодически записывает
+// их одним пакетом в хранилище, чтобы измерения не добавляли запись на каждый запрос.
+// Нулевой *Recorder ничего не измеряет, поэтому инструментирование можно не подключать
+type Recorder struct {
+	cfg *config.ServerConfig
+
+	mu       sync.Mutex
+	counters map[string]int64   // приращения counter с последней записи
+	sums     map[string]float64 // приращения сумм гистограмм (хранятся как gauge)
+	gauges   map[string]float64 // последние значения gauge
+	totals   map[string]float64 // записанные суммы гистограмм, чтобы не читать их из хранилища
+}
+
+func New(cfg *config.ServerConfig) *Recorder {
+	return &Recorder{
+		cfg:      cfg,
+		counters: make(map[string]int64),
+		sums:     make(map[string]float64),
+		gauges:   make(map[string]float64),
+		totals:   make(map[string]float64),
+	}
+}
+
+// Add увеличивает counter name с метками labels
+func (r *Recorder) Add(name string, labels map[string]string, delta int64) {
+	if r == nil {
+		return
+	}
+	id := models.FlattenLabels(name, labels)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.counters[id] += delta
+}
+
+// Set запоминает текущее значение gauge
+func (r *Recorder) Set(name string, labels map[string]string, value float64) {
+	if r == nil {
+		return
+	}
+	id := models.FlattenLabels(name, labels)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.gauges[id] = value
+}
+
+// Observe добавляет длительность в гистограмму в стиле Prometheus:
+// name_bucket;le=... (накопительно), name_count — counter, name_sum — gauge в секундах
+func (r *Recorder) Observe(name string, labels map[string]string, d time.Duration) {
+	if r == nil {
+		return
+	}
+	seconds := d.Seconds()
+	bucketLabels := maps.Clone(labels)
+	if bucketLabels == nil {
+		bucketLabels = make(map[string]string, 1)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, le := range Buckets {
+		if seconds <= le {
+			bucketLabels["le"] = strconv.FormatFloat(le, 'f', -1, 64)
+			r.counters[models.FlattenLabels(name+"_bucket", bucketLabels)]++
+		}
+	}
+	bucketLabels["le"] = "+Inf"
+	r.counters[models.FlattenLabels(name+"_bucket", bucketLabels)]++
+	r.counters[models.FlattenLabels(name+"_count", labels)]++
+	r.sums[models.FlattenLabels(name+"_sum", labels)] += seconds
+}
+
+// Flush записывает накопленное в хранилище; при ошибке измерения сохраняются до следующей записи
+func (r *Recorder) Flush(ctx context.Context, repo storage.Storage) error {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	counters, sums, gauges := r.counters, r.sums, r.gauges
+	r.counters, r.sums, r.gauges = make(map[string]int64), make(map[string]float64), make(map[string]float64)
+	r.mu.Unlock()
+
+	batch := make([]models.Metrics, 0, len(counters)+len(sums)+len(gauges))
+	for id, delta := range counters {
+		batch = append(batch, models.Metrics{ID: id, MType: models.Counter, Delta: &delta})
+	}
+	// Сумма — gauge, поэтому приращение добавляется к итогу. Итог читается из хранилища
+	// один раз за жизнь процесса, чтобы после перезапуска сумма не отставала от _count
+	totals := make(map[string]float64, len(sums))
+	for id, delta := range sums {
+		total, err := r.total(id, repo)
+		if err != nil {
+			r.restore(counters, sums, gauges)
+			return err
+		}
+		total += delta
+		totals[id] = total
+		batch = append(batch, models.Metrics{ID: id, MType: models.Gauge, Value: &total})
+	}
+	for id, value := range gauges {
+		batch = append(batch, models.Metrics{ID: id, MType: models.Gauge, Value: &value})
+	}
+	if len(batch) == 0 {
+		return nil
+	}
+
+	if err := repo.UpdateMetricsBatch(ctx, batch); err != nil {
+		r.restore(counters, sums, gauges)
+		return err
+	}
+
+	r.mu.Lock()
+	maps.Copy(r.totals, totals)
+	r.mu.Unlock()
+	return nil
+}
+
+// total возвращает записанную сумму гистограммы; хранилище читается только для нового ряда
+func (r *Recorder) total(id string, repo storage.Storage) (float64, error) {
+	r.mu.Lock()
+	total, ok := r.totals[id]
+	r.mu.Unlock()
+	if ok {
+		return total, nil
+	}
+
+	total, err := repo.GetGauge(id)
+	if err != nil && !errors.Is(err, storage.ErrMetricNotFound) {
+		return 0, err
+	}
+	return total, nil
+}
+
+// restore возвращает незаписанные измерения, не затирая более свежие значения gauge
+func (r *Recorder) restore(counters map[string]int64, sums, gauges map[string]float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, delta := range counters {
+		r.counters[id] += delta
+	}
+	for id, delta := range sums {
+		r.sums[id] += delta
+	}
+	for id, value := range gauges {
+		if _, ok := r.gauges[id]; !ok {
+			r.gauges[id] = value
+		}
+	}
+}
+
+// Run записывает метрики каждые SelfMetricsInterval секунд и последний раз — при отмене контекста
+func (r *Recorder) Run(ctx context.Context, repo storage.Storage) {
+	log.Printf("Self metrics started with interval %ds", r.cfg.SelfMetricsInterval)
+	ticker := time.NewTicker(time.Duration(r.cfg.SelfMetricsInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := r.Flush(context.Background(), repo); err != nil {
+				log.Printf("Failed to flush self metrics: %v", err)
+			}
+			log.Println("Self metrics stopped")
+			return
+		case <-ticker.C:
+			if err := r.Flush(ctx, repo); err != nil {
+				log.Printf("Failed to flush self metrics: %v", err)
+			}
+		}
+	}
+}
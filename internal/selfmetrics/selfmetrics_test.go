@@ -0,0 +1,140 @@
+package selfmetrics_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/config"
+	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
+	"github.com/akorablin/yandex-practicum-metrics/internal/repository/memory"
+	"github.com/akorablin/yandex-practicum-metrics/internal/selfmetrics"
+	"github.com/akorablin/yandex-practicum-metrics/internal/storage"
+)
+
+// failingStorage отклоняет пакетную запись
+type failingStorage struct {
+	storage.Storage
+}
+
+func (failingStorage) UpdateMetricsBatch(context.Context, []models.Metrics) error {
+	return errors.New("connection refused")
+}
+
+// countingStorage считает чтения gauge
+type countingStorage struct {
+	storage.Storage
+	reads int
+}
+
+func (s *countingStorage) GetGauge(name string) (float64, error) {
+	s.reads++
+	return s.Storage.GetGauge(name)
+}
+
+func TestRecorderFlush(t *testing.T) {
+	cfg := &config.ServerConfig{}
+	repo := &countingStorage{Storage: memory.New(cfg)}
+	recorder := selfmetrics.New(cfg)
+	labels := map[string]string{"route": "/updates/"}
+
+	recorder.Observe("HTTPRequestDuration", labels, 3*time.Millisecond)
+	recorder.Observe("HTTPRequestDuration", labels, 2*time.Second)
+	recorder.Set("SnapshotSizeBytes", nil, 512)
+	if err := recorder.Flush(context.Background(), repo); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	// Повторная запись добавляет только новые измерения
+	recorder.Observe("HTTPRequestDuration", labels, 3*time.Millisecond)
+	if err := recorder.Flush(context.Background(), repo); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	counters := []struct {
+		id   string
+		want int64
+	}{
+		{"HTTPRequestDuration_count;route=/updates/", 3},
+		{"HTTPRequestDuration_bucket;le=0.001;route=/updates/", 0},
+		{"HTTPRequestDuration_bucket;le=0.005;route=/updates/", 2},
+		{"HTTPRequestDuration_bucket;le=2.5;route=/updates/", 3},
+		{"HTTPRequestDuration_bucket;le=+Inf;route=/updates/", 3},
+	}
+	for _, c := range counters {
+		got, err := repo.GetCounter(c.id)
+		if c.want == 0 {
+			if !errors.Is(err, storage.ErrMetricNotFound) {
+				t.Errorf("%s: expected no series, got %d", c.id, got)
+			}
+			continue
+		}
+		if err != nil || got != c.want {
+			t.Errorf("%s: expected %d, got %d (%v)", c.id, c.want, got, err)
+		}
+	}
+
+	// Итог суммы читается из хранилища только при первой записи ряда
+	if repo.reads != 1 {
+		t.Errorf("Expected 1 sum read from storage, got %d", repo.reads)
+	}
+	sum, err := repo.GetGauge("HTTPRequestDuration_sum;route=/updates/")
+	if err != nil || sum < 2.005 || sum > 2.007 {
+		t.Errorf("Unexpected sum %v (%v)", sum, err)
+	}
+	if size, err := repo.GetGauge("SnapshotSizeBytes"); err != nil || size != 512 {
+		t.Errorf("Unexpected snapshot size %v (%v)", size, err)
+	}
+}
+
+func TestRecorderFlushError(t *testing.T) {
+	cfg := &config.ServerConfig{}
+	repo := memory.New(cfg)
+	recorder := selfmetrics.New(cfg)
+
+	recorder.Add("PostgresRetries", nil, 2)
+	if err := recorder.Flush(context.Background(), failingStorage{repo}); err == nil {
+		t.Fatal("Expected flush error")
+	}
+	recorder.Add("PostgresRetries", nil, 1)
+	if err := recorder.Flush(context.Background(), repo); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if got, err := repo.GetCounter("PostgresRetries"); err != nil || got != 3 {
+		t.Errorf("Expected retries kept after failed flush, got %d (%v)", got, err)
+	}
+}
+
+func TestInstrumentedStorage(t *testing.T) {
+	cfg := &config.ServerConfig{TypeConflictPolicy: config.TypeConflictReject}
+	inner := memory.New(cfg)
+	recorder := selfmetrics.New(cfg)
+	repo := selfmetrics.NewStorage(inner, recorder, "memory")
+
+	repo.UpdateGauge("Alloc", 1)
+	repo.UpdateCounter("Alloc", 1) // конфликт типов
+	repo.GetGauge("Missing")       // «не найдено» не считается ошибкой
+	if err := recorder.Flush(context.Background(), inner); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		id   string
+		want int64
+	}{
+		{"Вызовы UpdateGauge", "StorageOperationDuration_count;backend=memory;method=UpdateGauge", 1},
+		{"Вызовы GetGauge", "StorageOperationDuration_count;backend=memory;method=GetGauge", 1},
+		{"Ошибки UpdateCounter", "StorageOperationErrors;backend=memory;method=UpdateCounter", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := inner.GetCounter(tt.id); err != nil || got != tt.want {
+				t.Errorf("Expected %d, got %d (%v)", tt.want, got, err)
+			}
+		})
+	}
+	if _, err := inner.GetCounter("StorageOperationErrors;backend=memory;method=GetGauge"); !errors.Is(err, storage.ErrMetricNotFound) {
+		t.Errorf("Not found must not be counted as error: %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package selfmetrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
+	"github.com/akorablin/yandex-practicum-metrics/internal/storage"
+)
+
+const (
+	storageDurationMetric = "StorageOperationDuration"
+	storageErrorsMetric   = "StorageOperationErrors"
+)
+
+// InstrumentedStorage измеряет длительность и ошибки каждого метода хранилища
+// с метками backend (memory, postgres) и method
+type InstrumentedStorage struct {
+	storage.Storage
+
+	recorder *Recorder
+	backend  string
+}
+
+func NewStorage(repo storage.Storage, recorder *Recorder, backend string) *InstrumentedStorage {
+	return &InstrumentedStorage{
+		Storage:  repo,
+		recorder: recorder,
+		backend:  backend,
+	}
+}
+
+// observe учитывает вызов; «не найдено» — обычный ответ, а не ошибка хранилища
+func (s *InstrumentedStorage) observe(method string, start time.Time, err error) {
+	labels := map[string]string{"backend": s.backend, "method": method}
+	s.recorder.Observe(storageDurationMetric, labels, time.Since(start))
+	if err != nil && !errors.Is(err, storage.ErrMetricNotFound) && !errors.Is(err, storage.ErrMetadataNotFound) {
+		s.recorder.Add(storageErrorsMetric, labels, 1)
+	}
+}
+
+func (s *InstrumentedStorage) UpdateGauge(name string, value float64) error {
+	start := time.Now()
+	err := s.Storage.UpdateGauge(name, value)
+	s.observe("UpdateGauge", start, err)
+	return err
+}
+
+func (s *InstrumentedStorage) UpdateCounter(name string, value int64) error {
+	start := time.Now()
+	err := s.Storage.UpdateCounter(name, value)
+	s.observe("UpdateCounter", start, err)
+	return err
+}
+
+func (s *InstrumentedStorage) UpdateMetricsBatch(ctx context.Context, metrics []models.Metrics) error {
+	start := time.Now()
+	err := s.Storage.UpdateMetricsBatch(ctx, metrics)
+	s.observe("UpdateMetricsBatch", start, err)
+	return err
+}
+
+func (s *InstrumentedStorage) GetGauge(name string) (float64, error) {
+	start := time.Now()
+	value, err := s.Storage.GetGauge(name)
+	s.observe("GetGauge", start, err)
+	return value, err
+}
+
+func (s *InstrumentedStorage) GetCounter(name string) (int64, error) {
+	start := time.Now()
+	value, err := s.Storage.GetCounter(name)
+	s.observe("GetCounter", start, err)
+	return value, err
+}
+
+func (s *InstrumentedStorage) GetAllMetrics() (map[string]float64, map[string]int64) {
+	start := time.Now()
+	gauges, counters := s.Storage.GetAllMetrics()
+	s.observe("GetAllMetrics", start, nil)
+	return gauges, counters
+}
+
+func (s *InstrumentedStorage) DeleteMetric(mtype, name string) error {
+	start := time.Now()
+	err := s.Storage.DeleteMetric(mtype, name)
+	s.observe("DeleteMetric", start, err)
+	return err
+}
+
+func (s *InstrumentedStorage) SetMetadata(meta models.Metadata) error {
+	start := time.Now()
+	err := s.Storage.SetMetadata(meta)
+	s.observe("SetMetadata", start, err)
+	return err
+}
+
+func (s *InstrumentedStorage) GetMetadata(name string) (models.Metadata, error) {
+	start := time.Now()
+	meta, err := s.Storage.GetMetadata(name)
+	s.observe("GetMetadata", start, err)
+	return meta, err
+}
+
+func (s *InstrumentedStorage) GetAllMetadata() map[string]models.Metadata {
+	start := time.Now()
+	all := s.Storage.GetAllMetadata()
+	s.observe("GetAllMetadata", start, nil)
+	return all
+}
@@ -1 +1,46 @@
 package service
+
+import (
+	"context"
+
+	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
+	"github.com/akorablin/yandex-practicum-metrics/internal/storage"
+)
+
+// SetGauge записывает значение gauge и возвращает сохранённую метрику
+func (s *Metrics) SetGauge(name string, value float64) (models.Metrics, error) {
+	if name == "" {
+		return models.Metrics{}, ErrEmptyName
+	}
+	if err := s.storage.UpdateGauge(name, value); err != nil {
+		return models.Metrics{}, err
+	}
+	return models.Metrics{ID: name, MType: models.Gauge, Value: &value}, nil
+}
+
+// AddCounter увеличивает counter на delta и возвращает итоговое значение
+func (s *Metrics) AddCounter(name string, delta int64) (models.Metrics, error) {
+	if name == "" {
+		return models.Metrics{}, ErrEmptyName
+	}
+	if err := s.storage.UpdateCounter(name, delta); err != nil {
+		return models.Metrics{}, err
+	}
+	return s.Get(models.Counter, name)
+}
+
+// Update применяет метрику в формате models.Metrics: gauge перезаписывается, counter накапливается
+func (s *Metrics) Update(m models.Metrics) (models.Metrics, error) {
+	switch {
+	case m.MType == models.Gauge && m.Value != nil:
+		return s.SetGauge(m.ID, *m.Value)
+	case m.MType == models.Counter && m.Delta != nil:
+		return s.AddCounter(m.ID, *m.Delta)
+	}
+	return models.Metrics{}, storage.ErrInvalidType
+}
+
+// UpdateBatch сохраняет пакет атомарно, если это поддерживает хранилище
+func (s *Metrics) UpdateBatch(ctx context.Context, metrics []models.Metrics) error {
+	return s.storage.UpdateMetricsBatch(ctx, metrics)
+}
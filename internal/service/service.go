@@ -0,0 +1,78 @@
+package service
+
+import (
+	"errors"
+	"sort"
+
+	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
+	"github.com/akorablin/yandex-practicum-metrics/internal/storage"
+)
+
+var ErrEmptyName = errors.New("metric name is required")
+
+// Metrics — операции с метриками, общие для всех версий HTTP API.
+// Обработчики /api/v1 и прежние маршруты /update, /value работают через один и тот же сервис
+type Metrics struct {
+	storage storage.Storage
+}
+
+func New(repo storage.Storage) *Metrics {
+	return &Metrics{storage: repo}
+}
+
+// Get возвращает текущее значение метрики
+func (s *Metrics) Get(mtype, name string) (models.Metrics, error) {
+	if name == "" {
+		return models.Metrics{}, ErrEmptyName
+	}
+
+	m := models.Metrics{ID: name, MType: mtype}
+	switch mtype {
+	case models.Gauge:
+		value, err := s.storage.GetGauge(name)
+		if err != nil {
+			return models.Metrics{}, err
+		}
+		m.Value = &value
+	case models.Counter:
+		delta, err := s.storage.GetCounter(name)
+		if err != nil {
+			return models.Metrics{}, err
+		}
+		m.Delta = &delta
+	default:
+		return models.Metrics{}, storage.ErrInvalidType
+	}
+	return m, nil
+}
+
+// List возвращает все метрики, упорядоченные по ID, а при совпадении ID — по типу
+func (s *Metrics) List() []models.Metrics {
+	gauges, counters := s.storage.GetAllMetrics()
+
+	metrics := make([]models.Metrics, 0, len(gauges)+len(counters))
+	for id, value := range gauges {
+		metrics = append(metrics, models.Metrics{ID: id, MType: models.Gauge, Value: &value})
+	}
+	for id, delta := range counters {
+		metrics = append(metrics, models.Metrics{ID: id, MType: models.Counter, Delta: &delta})
+	}
+	sort.Slice(metrics, func(i, j int) bool {
+		if metrics[i].ID != metrics[j].ID {
+			return metrics[i].ID < metrics[j].ID
+		}
+		return metrics[i].MType < metrics[j].MType
+	})
+	return metrics
+}
+
+// Delete удаляет метрику
+func (s *Metrics) Delete(mtype, name string) error {
+	if name == "" {
+		return ErrEmptyName
+	}
+	if mtype != models.Gauge && mtype != models.Counter {
+		return storage.ErrInvalidType
+	}
+	return s.storage.DeleteMetric(mtype, name)
+}
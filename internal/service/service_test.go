@@ -0,0 +1,63 @@
+package service_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/config"
+	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
+	"github.com/akorablin/yandex-practicum-metrics/internal/repository/memory"
+	"github.com/akorablin/yandex-practicum-metrics/internal/service"
+	"github.com/akorablin/yandex-practicum-metrics/internal/storage"
+)
+
+func TestMetrics(t *testing.T) {
+	svc := service.New(memory.New(&config.ServerConfig{}))
+
+	if _, err := svc.SetGauge("Alloc", 1.5); err != nil {
+		t.Fatalf("SetGauge() failed: %v", err)
+	}
+	svc.AddCounter("PollCount", 2)
+	m, err := svc.AddCounter("PollCount", 3)
+	if err != nil || *m.Delta != 5 {
+		t.Fatalf("AddCounter() = %v (%v), want total 5", m, err)
+	}
+	if _, err := svc.Update(models.Metrics{ID: "Alloc", MType: models.Counter}); !errors.Is(err, storage.ErrInvalidType) {
+		t.Errorf("Update() without delta = %v, want ErrInvalidType", err)
+	}
+
+	list := svc.List()
+	if len(list) != 2 || list[0].ID != "Alloc" || list[1].ID != "PollCount" {
+		t.Fatalf("List() = %v, want Alloc and PollCount", list)
+	}
+
+	tests := []struct {
+		name  string
+		mtype string
+		id    string
+		err   error
+	}{
+		{"Существующий gauge", models.Gauge, "Alloc", nil},
+		{"Тот же ID другого типа", models.Counter, "Alloc", storage.ErrMetricNotFound},
+		{"Неизвестный тип", "histogram", "Alloc", storage.ErrInvalidType},
+		{"Пустое имя", models.Gauge, "", service.ErrEmptyName},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := svc.Get(tt.mtype, tt.id); !errors.Is(err, tt.err) {
+				t.Errorf("Get() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+
+	if err := svc.Delete(models.Gauge, "Alloc"); err != nil {
+		t.Fatalf("Delete() failed: %v", err)
+	}
+	if err := svc.Delete(models.Gauge, "Alloc"); !errors.Is(err, storage.ErrMetricNotFound) {
+		t.Errorf("Repeated Delete() = %v, want ErrMetricNotFound", err)
+	}
+	if list := svc.List(); len(list) != 1 {
+		t.Errorf("List() after delete = %v", list)
+	}
+}
@@ -0,0 +1,79 @@
+// Package sign реализует подпись данных HMAC-SHA256 общим ключом агента и сервера
+package sign
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
+)
+
+// Header — заголовок с подписью тела запроса или ответа
+const Header = "HashSHA256"
+
+// MetadataKey — ключ метаданных gRPC с подписью пакета метрик (см. BatchHash)
+const MetadataKey = "hashsha256"
+
+// Sum возвращает подпись данных в шестнадцатеричном виде
+func Sum(data []byte, key string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Valid сравнивает подпись с ожидаемой за постоянное время
+func Valid(data []byte, key, hash string) bool {
+	expected, err := hex.DecodeString(hash)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(data)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// MetricHash подписывает метрику по строке "id:gauge:value" или "id:counter:delta"
+func MetricHash(m models.Metrics, key string) string {
+	return Sum([]byte(metricData(m)), key)
+}
+
+// ValidMetric проверяет поле Hash метрики
+func ValidMetric(m models.Metrics, key string) bool {
+	return Valid([]byte(metricData(m)), key, m.Hash)
+}
+
+// BatchHash подписывает пакет метрик по строкам metricData, разделённым переводом строки.
+// Так подписываются gRPC-вызовы, у которых нет тела в виде байтов
+func BatchHash(metrics []models.Metrics, key string) string {
+	return Sum(batchData(metrics), key)
+}
+
+// ValidBatch проверяет подпись пакета метрик
+func ValidBatch(metrics []models.Metrics, key, hash string) bool {
+	return Valid(batchData(metrics), key, hash)
+}
+
+func batchData(metrics []models.Metrics) []byte {
+	var b []byte
+	for i, m := range metrics {
+		if i > 0 {
+			b = append(b, '\n')
+		}
+		b = append(b, metricData(m)...)
+	}
+	return b
+}
+
+func metricData(m models.Metrics) string {
+	switch {
+	case m.MType == models.Gauge && m.Value != nil:
+		// Кратчайшая точная запись, чтобы подпись покрывала значение без округления
+		return m.ID + ":gauge:" + strconv.FormatFloat(*m.Value, 'g', -1, 64)
+	case m.MType == models.Counter && m.Delta != nil:
+		return fmt.Sprintf("%s:counter:%d", m.ID, *m.Delta)
+	}
+	return fmt.Sprintf("%s:%s", m.ID, m.MType)
+}
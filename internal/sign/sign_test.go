@@ -0,0 +1,62 @@
+package sign_test
+
+import (
+	"testing"
+
+	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
+	"github.com/akorablin/yandex-practicum-metrics/internal/sign"
+)
+
+func TestValid(t *testing.T) {
+	data := []byte(`[{"id":"PollCount","type":"counter","delta":1}]`)
+	hash := sign.Sum(data, "secret")
+
+	tests := []struct {
+		name string
+		data []byte
+		key  string
+		hash string
+		want bool
+	}{
+		{"Верная подпись", data, "secret", hash, true},
+		{"Другой ключ", data, "other", hash, false},
+		{"Изменённое тело", []byte(`[{"id":"PollCount","type":"counter","delta":100}]`), "secret", hash, false},
+		{"Не hex", data, "secret", "zz", false},
+		{"Пустая подпись", data, "secret", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sign.Valid(tt.data, tt.key, tt.hash); got != tt.want {
+				t.Errorf("Valid() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidMetric(t *testing.T) {
+	value, delta := 1.5, int64(3)
+	gauge := models.Metrics{ID: "Alloc", MType: models.Gauge, Value: &value}
+	gauge.Hash = sign.MetricHash(gauge, "secret")
+	if !sign.ValidMetric(gauge, "secret") {
+		t.Error("ValidMetric() rejected signed gauge")
+	}
+
+	counter := models.Metrics{ID: "Alloc", MType: models.Counter, Delta: &delta}
+	counter.Hash = gauge.Hash
+	if sign.ValidMetric(counter, "secret") {
+		t.Error("ValidMetric() accepted gauge signature for counter")
+	}
+
+	// Значения различаются только после шестого знака
+	precise, changed := 0.1234561, 0.1234564
+	signed := models.Metrics{ID: "Alloc", MType: models.Gauge, Value: &precise}
+	signed.Hash = sign.MetricHash(signed, "secret")
+	signed.Value = &changed
+	if sign.ValidMetric(signed, "secret") {
+		t.Error("ValidMetric() accepted signature for a different gauge value")
+	}
+	if sign.BatchHash([]models.Metrics{signed}, "secret") == sign.BatchHash([]models.Metrics{{ID: "Alloc", MType: models.Gauge, Value: &precise}}, "secret") {
+		t.Error("BatchHash() ignores gauge digits after the sixth decimal")
+	}
+}
@@ -0,0 +1,151 @@
+package statsd
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+
+	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
+	"github.com/akorablin/yandex-practicum-metrics/internal/storage"
+)
+
+type gaugeState struct {
+	value float64
+	set   bool
+	delta float64
+}
+
+type timerState struct {
+	count float64
+	sum   float64
+	min   float64
+	max   float64
+}
+
+// Aggregator накапливает значения в течение интервала и записывает их в хранилище при Flush
+type Aggregator struct {
+	mu       sync.Mutex
+	counters map[string]float64
+	gauges   map[string]*gaugeState
+	timers   map[string]*timerState
+}
+
+func NewAggregator() *Aggregator {
+	return &Aggregator{
+		counters: make(map[string]float64),
+		gauges:   make(map[string]*gaugeState),
+		timers:   make(map[string]*timerState),
+	}
+}
+
+func (a *Aggregator) Add(s Sample) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	switch s.Type {
+	case TypeCounter:
+		a.counters[s.Name] += s.Value / s.SampleRate
+
+	case TypeGauge:
+		g, ok := a.gauges[s.Name]
+		if !ok {
+			g = &gaugeState{}
+			a.gauges[s.Name] = g
+		}
+		if s.Relative {
+			if g.set {
+				g.value += s.Value
+			} else {
+				g.delta += s.Value
+			}
+		} else {
+			g.value, g.set, g.delta = s.Value, true, 0
+		}
+
+	case TypeTimer:
+		t, ok := a.timers[s.Name]
+		if !ok {
+			t = &timerState{min: s.Value, max: s.Value}
+			a.timers[s.Name] = t
+		}
+		t.count += 1 / s.SampleRate
+		t.sum += s.Value / s.SampleRate
+		t.min = math.Min(t.min, s.Value)
+		t.max = math.Max(t.max, s.Value)
+	}
+}
+
+// Flush записывает накопленные значения в хранилище одним пакетом и сбрасывает состояние.
+// Таймеры сохраняются как gauge <name>.mean/.min/.max и counter <name>.count.
+// Дробная часть счётчиков с частотой выборки переносится в следующий сброс, а не теряется
+func (a *Aggregator) Flush(ctx context.Context, repo storage.Storage) error {
+	a.mu.Lock()
+	counters, gauges, timers := a.counters, a.gauges, a.timers
+	a.counters = make(map[string]float64)
+	a.gauges = make(map[string]*gaugeState)
+	a.timers = make(map[string]*timerState)
+	a.mu.Unlock()
+
+	for name, t := range timers {
+		counters[name+".count"] += t.count
+	}
+
+	var batch []models.Metrics
+	remainders := make(map[string]float64)
+	for name, value := range counters {
+		whole := math.Trunc(value)
+		if rest := value - whole; rest != 0 {
+			remainders[name] = rest
+		}
+		if whole != 0 {
+			delta := int64(whole)
+			batch = append(batch, models.Metrics{ID: name, MType: models.Counter, Delta: &delta})
+		}
+	}
+
+	for name, g := range gauges {
+		value := g.value
+		if !g.set {
+			// Относительное изменение применяется к текущему значению в хранилище
+			current, err := repo.GetGauge(name)
+			if err != nil && !errors.Is(err, storage.ErrMetricNotFound) {
+				a.restore(counters)
+				return err
+			}
+			value = current + g.delta
+		}
+		batch = append(batch, gaugeMetric(name, value))
+	}
+
+	for name, t := range timers {
+		batch = append(batch,
+			gaugeMetric(name+".mean", t.sum/t.count),
+			gaugeMetric(name+".min", t.min),
+			gaugeMetric(name+".max", t.max),
+		)
+	}
+
+	if len(batch) > 0 {
+		if err := repo.UpdateMetricsBatch(ctx, batch); err != nil {
+			// Приращения счётчиков не теряются, а gauge и таймеры заменит следующий интервал
+			a.restore(counters)
+			return err
+		}
+	}
+	a.restore(remainders)
+	return nil
+}
+
+// restore возвращает незаписанные приращения счётчиков в текущий интервал
+func (a *Aggregator) restore(counters map[string]float64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for name, value := range counters {
+		a.counters[name] += value
+	}
+}
+
+func gaugeMetric(name string, value float64) models.Metrics {
+	return models.Metrics{ID: name, MType: models.Gauge, Value: &value}
+}
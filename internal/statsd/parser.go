@@ -0,0 +1,93 @@
+package statsd
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Типы метрик StatsD
+const (
+	TypeCounter = "c"
+	TypeGauge   = "g"
+	TypeTimer   = "ms"
+)
+
+var ErrInvalidLine = errors.New("invalid statsd line")
+
+type Sample struct {
+	Name       string
+	Type       string
+	Value      float64
+	SampleRate float64
+	// Relative — значение gauge передано со знаком (+N/-N) и изменяет текущее значение
+	Relative bool
+}
+
+// ParseLine разбирает строку вида name:value|type[|@rate]
+func ParseLine(line string) (Sample, error) {
+	line = strings.TrimSpace(line)
+
+	name, rest, ok := strings.Cut(line, ":")
+	if !ok || name == "" {
+		return Sample{}, fmt.Errorf("%w: %q", ErrInvalidLine, line)
+	}
+
+	parts := strings.Split(rest, "|")
+	if len(parts) < 2 || len(parts) > 3 {
+		return Sample{}, fmt.Errorf("%w: %q", ErrInvalidLine, line)
+	}
+
+	sample := Sample{
+		Name:       name,
+		Type:       parts[1],
+		SampleRate: 1,
+	}
+
+	switch sample.Type {
+	case TypeCounter, TypeGauge, TypeTimer:
+	default:
+		return Sample{}, fmt.Errorf("%w: unknown type %q", ErrInvalidLine, sample.Type)
+	}
+
+	value, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return Sample{}, fmt.Errorf("%w: bad value %q", ErrInvalidLine, parts[0])
+	}
+	sample.Value = value
+	sample.Relative = sample.Type == TypeGauge && (strings.HasPrefix(parts[0], "+") || strings.HasPrefix(parts[0], "-"))
+
+	// Частота выборки (@0.1) имеет смысл только для счётчиков и таймеров
+	if len(parts) == 3 {
+		rate, ok := strings.CutPrefix(parts[2], "@")
+		if !ok {
+			return Sample{}, fmt.Errorf("%w: bad sample rate %q", ErrInvalidLine, parts[2])
+		}
+		sample.SampleRate, err = strconv.ParseFloat(rate, 64)
+		if err != nil || math.IsNaN(sample.SampleRate) || sample.SampleRate <= 0 || sample.SampleRate > 1 {
+			return Sample{}, fmt.Errorf("%w: bad sample rate %q", ErrInvalidLine, parts[2])
+		}
+	}
+
+	return sample, nil
+}
+
+// ParsePacket разбирает UDP-пакет, в котором строки разделены переводом строки
+func ParsePacket(packet []byte) ([]Sample, []error) {
+	var samples []Sample
+	var errs []error
+	for _, line := range strings.Split(string(packet), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		sample, err := ParseLine(line)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		samples = append(samples, sample)
+	}
+	return samples, errs
+}
@@ -0,0 +1,93 @@
+package statsd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/config"
+	"github.com/akorablin/yandex-practicum-metrics/internal/storage"
+)
+
+// Максимальный размер UDP-датаграммы
+const maxPacketSize = 65535
+
+type Server struct {
+	cfg        *config.ServerConfig
+	storage    storage.Storage
+	aggregator *Aggregator
+}
+
+func New(cfg *config.ServerConfig, repo storage.Storage) *Server {
+	return &Server{
+		cfg:        cfg,
+		storage:    repo,
+		aggregator: NewAggregator(),
+	}
+}
+
+// Run принимает UDP-пакеты до отмены контекста, затем выполняет финальный сброс агрегатов
+func (s *Server) Run(ctx context.Context) error {
+	conn, err := net.ListenPacket("udp", s.cfg.StatsDAddress)
+	if err != nil {
+		return fmt.Errorf("failed to listen statsd: %w", err)
+	}
+
+	// Закрываем соединение при остановке, чтобы прервать ReadFrom
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	// Периодический сброс агрегатов в хранилище
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(time.Duration(s.cfg.StatsDFlushInterval) * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				s.flush()
+				return
+			case <-ticker.C:
+				s.flush()
+			}
+		}
+	}()
+
+	log.Printf("StatsD listener started on %s", conn.LocalAddr())
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				break
+			}
+			log.Printf("StatsD read error: %v", err)
+			continue
+		}
+
+		samples, errs := ParsePacket(buf[:n])
+		for _, err := range errs {
+			log.Printf("StatsD parse error: %v", err)
+		}
+		for _, sample := range samples {
+			s.aggregator.Add(sample)
+		}
+	}
+
+	<-done
+	log.Println("StatsD listener stopped")
+	return nil
+}
+
+func (s *Server) flush() {
+	if err := s.aggregator.Flush(context.Background(), s.storage); err != nil {
+		log.Printf("Failed to flush statsd metrics: %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package statsd_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/config"
+	"github.com/akorablin/yandex-practicum-metrics/internal/repository/memory"
+	"github.com/akorablin/yandex-practicum-metrics/internal/statsd"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want statsd.Sample
+	}{
+		{"hits:3|c", statsd.Sample{Name: "hits", Type: "c", Value: 3, SampleRate: 1}},
+		{"hits:1|c|@0.1", statsd.Sample{Name: "hits", Type: "c", Value: 1, SampleRate: 0.1}},
+		{"temp:21.5|g", statsd.Sample{Name: "temp", Type: "g", Value: 21.5, SampleRate: 1}},
+		{"temp:-2|g", statsd.Sample{Name: "temp", Type: "g", Value: -2, SampleRate: 1, Relative: true}},
+		{"req.time:320|ms", statsd.Sample{Name: "req.time", Type: "ms", Value: 320, SampleRate: 1}},
+	}
+
+	for _, tt := range tests {
+		got, err := statsd.ParseLine(tt.line)
+		if err != nil {
+			t.Errorf("ParseLine(%q) failed: %v", tt.line, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseLine(%q) = %+v, want %+v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestParseLineInvalid(t *testing.T) {
+	lines := []string{"", "hits", "hits:1", "hits:x|c", "hits:1|h", "hits:1|c|0.1", "hits:1|c|@2",
+		"x:NaN|c", "x:Inf|g", "x:-Inf|ms", "x:1|c|@NaN"}
+
+	for _, line := range lines {
+		if _, err := statsd.ParseLine(line); !errors.Is(err, statsd.ErrInvalidLine) {
+			t.Errorf("ParseLine(%q): expected ErrInvalidLine, got %v", line, err)
+		}
+	}
+}
+
+func TestAggregatorFlush(t *testing.T) {
+	repo := memory.New(&config.ServerConfig{})
+	repo.UpdateGauge("queue", 10)
+
+	samples, errs := statsd.ParsePacket([]byte("hits:1|c|@0.5\nhits:2|c\nqueue:+5|g\nload:1|g\nload:3|g\nreq:10|ms\nreq:30|ms\n"))
+	if len(errs) > 0 {
+		t.Fatalf("ParsePacket() errors: %v", errs)
+	}
+
+	aggregator := statsd.NewAggregator()
+	for _, sample := range samples {
+		aggregator.Add(sample)
+	}
+	if err := aggregator.Flush(context.Background(), repo); err != nil {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+
+	// Проверяем записанные значения
+	if v, _ := repo.GetCounter("hits"); v != 4 {
+		t.Errorf("Expected hits = 4, got %d", v)
+	}
+	if v, _ := repo.GetGauge("queue"); v != 15 {
+		t.Errorf("Expected queue = 15, got %g", v)
+	}
+	if v, _ := repo.GetGauge("load"); v != 3 {
+		t.Errorf("Expected load = 3, got %g", v)
+	}
+	if v, _ := repo.GetGauge("req.mean"); v != 20 {
+		t.Errorf("Expected req.mean = 20, got %g", v)
+	}
+	if v, _ := repo.GetCounter("req.count"); v != 2 {
+		t.Errorf("Expected req.count = 2, got %d", v)
+	}
+
+	// Повторный сброс не должен ничего менять
+	if err := aggregator.Flush(context.Background(), repo); err != nil {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+	if v, _ := repo.GetCounter("hits"); v != 4 {
+		t.Errorf("Expected hits = 4 after empty flush, got %d", v)
+	}
+}
+
+func TestAggregatorCarriesFraction(t *testing.T) {
+	repo := memory.New(&config.ServerConfig{})
+	aggregator := statsd.NewAggregator()
+
+	// Каждая выборка с частотой 0.4 даёт 2.5; дробная часть переносится в следующий сброс
+	for _, want := range []int64{2, 5, 7, 10} {
+		samples, _ := statsd.ParsePacket([]byte("sampled:1|c|@0.4"))
+		aggregator.Add(samples[0])
+		if err := aggregator.Flush(context.Background(), repo); err != nil {
+			t.Fatalf("Flush() failed: %v", err)
+		}
+		if v, _ := repo.GetCounter("sampled"); v != want {
+			t.Errorf("Expected sampled = %d, got %d", want, v)
+		}
+	}
+}
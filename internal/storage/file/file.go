@@ -5,22 +5,50 @@ import (
 	"errors"
 	"log"
 	"os"
+	"path/filepath"
+	"sync"
+	"time"
 
 	"github.com/akorablin/yandex-practicum-metrics/internal/config"
 	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
+	"github.com/akorablin/yandex-practicum-metrics/internal/selfmetrics"
 	"github.com/akorablin/yandex-practicum-metrics/internal/storage"
 )
 
+const (
+	snapshotDurationMetric = "SnapshotDuration"
+	snapshotSizeMetric     = "SnapshotSizeBytes"
+	snapshotErrorsMetric   = "SnapshotErrors"
+)
+
 type Files struct {
-	cfg     *config.ServerConfig
-	storage storage.Storage
+	// mu не даёт параллельным Save (периодический снимок, middleware.SyncSaving, SyncStorage)
+	// перемешать записи в одном файле
+	mu       sync.Mutex
+	cfg      *config.ServerConfig
+	storage  storage.Storage
+	recorder *selfmetrics.Recorder
 }
 
-func New(cfg *config.ServerConfig, repo storage.Storage) *Files {
-	return &Files{
+// Option подключает к снимкам необязательные компоненты
+type Option func(*Files)
+
+// WithSelfMetrics включает измерение длительности и размера снимков
+func WithSelfMetrics(recorder *selfmetrics.Recorder) Option {
+	return func(f *Files) {
+		f.recorder = recorder
+	}
+}
+
+func New(cfg *config.ServerConfig, repo storage.Storage, opts ...Option) *Files {
+	f := &Files{
 		cfg:     cfg,
 		storage: repo,
 	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
 }
 
 func (f *Files) Load() error {
@@ -43,6 +71,10 @@ func (f *Files) Load() error {
 		return err
 	}
 
+	if err := f.loadMetadata(); err != nil {
+		return err
+	}
+
 	for _, metric := range loadedMetrics {
 		mType, ID, value, delta := metric.MType, metric.ID, metric.Value, metric.Delta
 		if mType == "gauge" {
@@ -56,11 +88,32 @@ func (f *Files) Load() error {
 	return nil
 }
 
+// Save записывает снимок и учитывает его длительность, размер и ошибки.
+// Без FILE_STORAGE_PATH метрики хранятся только в памяти, и сохранять нечего
 func (f *Files) Save() error {
+	if f.cfg.FileStoragePath == "" {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	start := time.Now()
+	size, err := f.save()
+	f.recorder.Observe(snapshotDurationMetric, nil, time.Since(start))
+	if err != nil {
+		f.recorder.Add(snapshotErrorsMetric, nil, 1)
+		return err
+	}
+	f.recorder.Set(snapshotSizeMetric, nil, float64(size))
+	return nil
+}
+
+// save возвращает суммарный размер файла метрик и метаданных
+func (f *Files) save() (int, error) {
 	path := f.cfg.FileStoragePath
 	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0o644)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer file.Close()
 
@@ -78,14 +131,82 @@ func (f *Files) Save() error {
 	bytes, err := json.Marshal(all)
 	if err != nil {
 		log.Printf("Marshal error")
-		return err
+		return 0, err
 	}
 
 	WriteFileError := os.WriteFile(path, bytes, 0o644)
 	if WriteFileError != nil {
 		log.Printf("os.WriteFile error for path %s", path)
-		return WriteFileError
+		return 0, WriteFileError
+	}
+
+	metaSize, err := f.saveMetadata()
+	return len(bytes) + metaSize, err
+}
+
+// Writable проверяет, что снимок можно записать: каталог доступен на запись,
+// а существующий файл открывается для записи
+func (f *Files) Writable() error {
+	path := f.cfg.FileStoragePath
+	probe, err := os.CreateTemp(filepath.Dir(path), ".writable-*")
+	if err != nil {
+		return err
 	}
+	probe.Close()
+	os.Remove(probe.Name())
 
+	file, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// Метаданные хранятся рядом с файлом метрик: metrics.json -> metrics.json.meta
+func (f *Files) metadataPath() string {
+	return f.cfg.FileStoragePath + ".meta"
+}
+
+// loadMetadata загружается до метрик, чтобы при включённой проверке типов
+// восстановленные значения проверялись по зарегистрированным метаданным
+func (f *Files) loadMetadata() error {
+	data, err := os.ReadFile(f.metadataPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var loaded []models.Metadata
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return err
+	}
+	for _, meta := range loaded {
+		if err := f.storage.SetMetadata(meta); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+func (f *Files) saveMetadata() (int, error) {
+	all := f.storage.GetAllMetadata()
+	if len(all) == 0 {
+		return 0, nil
+	}
+
+	list := make([]models.Metadata, 0, len(all))
+	for _, meta := range all {
+		list = append(list, meta)
+	}
+
+	bytes, err := json.Marshal(list)
+	if err != nil {
+		return 0, err
+	}
+	return len(bytes), os.WriteFile(f.metadataPath(), bytes, 0o644)
+}
@@ -0,0 +1,72 @@
+package file_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/config"
+	"github.com/akorablin/yandex-practicum-metrics/internal/repository/memory"
+	"github.com/akorablin/yandex-practicum-metrics/internal/storage/file"
+)
+
+func TestSave(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"Без пути снимок не пишется", ""},
+		{"Снимок в файл", filepath.Join(t.TempDir(), "metrics.json")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.ServerConfig{FileStoragePath: tt.path, Restore: true}
+			repo := memory.New(cfg)
+			repo.UpdateCounter("PollCount", 5)
+			if err := file.New(cfg, repo).Save(); err != nil {
+				t.Fatalf("Save failed: %v", err)
+			}
+			if tt.path == "" {
+				return
+			}
+
+			restored := memory.New(cfg)
+			if err := file.New(cfg, restored).Load(); err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+			if value, err := restored.GetCounter("PollCount"); err != nil || value != 5 {
+				t.Errorf("Expected PollCount = 5, got %v (%v)", value, err)
+			}
+		})
+	}
+}
+
+func TestSyncStorage(t *testing.T) {
+	cfg := &config.ServerConfig{FileStoragePath: filepath.Join(t.TempDir(), "metrics.json"), Restore: true}
+	repo := memory.New(cfg)
+	syncRepo := file.NewSyncStorage(repo, file.New(cfg, repo))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go syncRepo.Run(ctx)
+
+	if err := syncRepo.UpdateCounter("PollCount", 5); err != nil {
+		t.Fatalf("UpdateCounter failed: %v", err)
+	}
+
+	// Снимок записывается в фоне вскоре после записи
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		restored := memory.New(cfg)
+		if err := file.New(cfg, restored).Load(); err == nil {
+			if value, err := restored.GetCounter("PollCount"); err == nil && value == 5 {
+				return
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Snapshot was not saved after write")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,65 @@
+package file
+
+import (
+	"context"
+	"log"
+
+	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
+	"github.com/akorablin/yandex-practicum-metrics/internal/storage"
+)
+
+// SyncStorage сохраняет снимок после записей, пришедших не через HTTP (StatsD, Graphite, gRPC),
+// когда STORE_INTERVAL=0. HTTP-запросы сохраняет middleware.SyncSaving, а здесь записи идут
+// построчно, поэтому сохранение выполняется в Run: записи, пришедшие во время сохранения,
+// попадают в следующий снимок, а не запускают по снимку на каждую строку
+type SyncStorage struct {
+	storage.Storage
+	files *Files
+	dirty chan struct{}
+}
+
+func NewSyncStorage(repo storage.Storage, files *Files) *SyncStorage {
+	return &SyncStorage{
+		Storage: repo,
+		files:   files,
+		dirty:   make(chan struct{}, 1),
+	}
+}
+
+func (s *SyncStorage) UpdateGauge(name string, value float64) error {
+	return s.changed(s.Storage.UpdateGauge(name, value))
+}
+
+func (s *SyncStorage) UpdateCounter(name string, value int64) error {
+	return s.changed(s.Storage.UpdateCounter(name, value))
+}
+
+func (s *SyncStorage) UpdateMetricsBatch(ctx context.Context, metrics []models.Metrics) error {
+	return s.changed(s.Storage.UpdateMetricsBatch(ctx, metrics))
+}
+
+// changed отмечает, что после успешной записи нужен новый снимок
+func (s *SyncStorage) changed(err error) error {
+	if err == nil {
+		select {
+		case s.dirty <- struct{}{}:
+		default:
+		}
+	}
+	return err
+}
+
+// Run сохраняет снимок после каждой серии записей до отмены контекста.
+// Последний снимок при остановке сервера записывает main
+func (s *SyncStorage) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.dirty:
+			if err := s.files.Save(); err != nil {
+				log.Printf("Failed to save metrics: %v", err)
+			}
+		}
+	}
+}
@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
 )
@@ -10,6 +11,9 @@ import (
 var (
 	ErrMetricNotFound = errors.New("metric not found")
 	ErrInvalidType    = errors.New("invalid metric type")
+
+	ErrMetadataNotFound = errors.New("metadata not found")
+	ErrTypeMismatch     = errors.New("metric type mismatch")
 )
 
 type Storage interface {
@@ -19,4 +23,15 @@ type Storage interface {
 	GetGauge(name string) (float64, error)
 	GetCounter(name string) (int64, error)
 	GetAllMetrics() (map[string]float64, map[string]int64)
+	// DeleteMetric удаляет метрику типа mtype; ErrMetricNotFound, если её нет
+	DeleteMetric(mtype, name string) error
+
+	SetMetadata(meta models.Metadata) error
+	GetMetadata(name string) (models.Metadata, error)
+	GetAllMetadata() map[string]models.Metadata
+}
+
+// TypeConflictError возвращается при политике reject, когда ID уже занят метрикой другого типа
+func TypeConflictError(id, existingType string) error {
+	return fmt.Errorf("%w: %s already exists as %s", ErrTypeMismatch, id, existingType)
 }
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
+)
+
+// TypeCheckingStorage отклоняет обновления, тип которых противоречит зарегистрированным
+// метаданным. Ожидаемые типы кэшируются при создании и обновляются через SetMetadata,
+// поэтому изменения, сделанные в общей базе другим экземпляром сервера, видны только после перезапуска
+type TypeCheckingStorage struct {
+	Storage
+
+	mu    sync.RWMutex
+	types map[string]string
+}
+
+func NewTypeCheckingStorage(repo Storage) *TypeCheckingStorage {
+	types := make(map[string]string)
+	for name, meta := range repo.GetAllMetadata() {
+		if meta.Type != "" {
+			types[name] = meta.Type
+		}
+	}
+	return &TypeCheckingStorage{
+		Storage: repo,
+		types:   types,
+	}
+}
+
+func (s *TypeCheckingStorage) UpdateGauge(name string, value float64) error {
+	if err := s.check(name, models.Gauge); err != nil {
+		return err
+	}
+	return s.Storage.UpdateGauge(name, value)
+}
+
+func (s *TypeCheckingStorage) UpdateCounter(name string, value int64) error {
+	if err := s.check(name, models.Counter); err != nil {
+		return err
+	}
+	return s.Storage.UpdateCounter(name, value)
+}
+
+// UpdateMetricsBatch отклоняет весь пакет, если хотя бы одна метрика не проходит проверку
+func (s *TypeCheckingStorage) UpdateMetricsBatch(ctx context.Context, metrics []models.Metrics) error {
+	for _, m := range metrics {
+		if err := s.check(m.ID, m.MType); err != nil {
+			return err
+		}
+	}
+	return s.Storage.UpdateMetricsBatch(ctx, metrics)
+}
+
+func (s *TypeCheckingStorage) SetMetadata(meta models.Metadata) error {
+	if err := s.Storage.SetMetadata(meta); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if meta.Type == "" {
+		delete(s.types, meta.Name)
+	} else {
+		s.types[meta.Name] = meta.Type
+	}
+	return nil
+}
+
+func (s *TypeCheckingStorage) check(id, mtype string) error {
+	name, _ := models.ParseLabels(id)
+
+	s.mu.RLock()
+	expected, ok := s.types[name]
+	s.mu.RUnlock()
+
+	if ok && expected != mtype {
+		return fmt.Errorf("%w: %s is registered as %s, got %s", ErrTypeMismatch, name, expected, mtype)
+	}
+	return nil
+}
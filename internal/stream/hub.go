@@ -0,0 +1,139 @@
+package stream
+
+import (
+	"path"
+	"sync"
+
+	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
+)
+
+// Event — событие потока: новое значение метрики или её удаление (Deleted, без значения)
+type Event struct {
+	models.Metrics
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+// Subscription получает события метрик, ID которых подходит под шаблон.
+// Канал C закрывается при отписке, остановке Hub или переполнении буфера (см. Lagged)
+type Subscription struct {
+	C       <-chan Event
+	ch      chan Event
+	pattern string
+	lagged  bool
+}
+
+func (s *Subscription) matches(id string) bool {
+	if s.pattern == "" {
+		return true
+	}
+	ok, _ := path.Match(s.pattern, id)
+	return ok
+}
+
+// Lagged сообщает, что подписка закрыта из-за того, что клиент не успевал читать события
+func (s *Subscription) Lagged() bool {
+	return s.lagged
+}
+
+// Hub рассылает обновления метрик подписчикам.
+// Публикация никогда не блокирует запись метрик: отстающий подписчик отключается
+type Hub struct {
+	mu         sync.Mutex
+	subs       map[*Subscription]struct{}
+	bufferSize int
+	closed     bool
+}
+
+func NewHub(bufferSize int) *Hub {
+	return &Hub{
+		subs:       make(map[*Subscription]struct{}),
+		bufferSize: bufferSize,
+	}
+}
+
+// Subscribe создаёт подписку; пустой шаблон соответствует всем метрикам.
+// Шаблон должен быть проверен заранее через ValidPattern
+func (h *Hub) Subscribe(pattern string) *Subscription {
+	ch := make(chan Event, h.bufferSize)
+	sub := &Subscription{C: ch, ch: ch, pattern: pattern}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		close(ch)
+		return sub
+	}
+	h.subs[sub] = struct{}{}
+	return sub
+}
+
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subs[sub]; ok {
+		delete(h.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// Interested сообщает, что есть подписчик, чей шаблон подходит под ID метрики
+func (h *Hub) Interested(id string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subs {
+		if sub.matches(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// Publish рассылает новое значение метрики
+func (h *Hub) Publish(m models.Metrics) {
+	h.publish(Event{Metrics: m})
+}
+
+// PublishDeleted рассылает удаление метрики
+func (h *Hub) PublishDeleted(mtype, name string) {
+	h.publish(Event{Metrics: models.Metrics{ID: name, MType: mtype}, Deleted: true})
+}
+
+func (h *Hub) publish(e Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subs {
+		if !sub.matches(e.ID) {
+			continue
+		}
+
+		select {
+		case sub.ch <- e:
+		default:
+			// Буфер переполнен — отключаем подписчика, клиент переподключится
+			sub.lagged = true
+			delete(h.subs, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+// Close закрывает все подписки, чтобы долгоживущие запросы завершились при остановке сервера
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for sub := range h.subs {
+		delete(h.subs, sub)
+		close(sub.ch)
+	}
+}
+
+func ValidPattern(pattern string) bool {
+	_, err := path.Match(pattern, "")
+	return err == nil
+}
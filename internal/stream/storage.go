@@ -0,0 +1,79 @@
+package stream
+
+import (
+	"context"
+
+	models "github.com/akorablin/yandex-practicum-metrics/internal/model"
+	"github.com/akorablin/yandex-practicum-metrics/internal/storage"
+)
+
+// PublishingStorage — обёртка над хранилищем, публикующая в Hub каждое успешное обновление и удаление.
+// Через неё проходят все пути записи: HTTP-обработчики, приёмники протоколов и загрузка из файла
+type PublishingStorage struct {
+	storage.Storage
+	hub *Hub
+}
+
+func NewPublishingStorage(repo storage.Storage, hub *Hub) *PublishingStorage {
+	return &PublishingStorage{
+		Storage: repo,
+		hub:     hub,
+	}
+}
+
+func (p *PublishingStorage) UpdateGauge(name string, value float64) error {
+	if err := p.Storage.UpdateGauge(name, value); err != nil {
+		return err
+	}
+
+	p.hub.Publish(models.Metrics{ID: name, MType: models.Gauge, Value: &value})
+	return nil
+}
+
+func (p *PublishingStorage) UpdateCounter(name string, value int64) error {
+	if err := p.Storage.UpdateCounter(name, value); err != nil {
+		return err
+	}
+
+	p.publishCounter(name)
+	return nil
+}
+
+func (p *PublishingStorage) UpdateMetricsBatch(ctx context.Context, metrics []models.Metrics) error {
+	if err := p.Storage.UpdateMetricsBatch(ctx, metrics); err != nil {
+		return err
+	}
+
+	for _, m := range metrics {
+		switch m.MType {
+		case models.Gauge:
+			value := *m.Value
+			p.hub.Publish(models.Metrics{ID: m.ID, MType: models.Gauge, Value: &value})
+		case models.Counter:
+			p.publishCounter(m.ID)
+		}
+	}
+	return nil
+}
+
+func (p *PublishingStorage) DeleteMetric(mtype, name string) error {
+	if err := p.Storage.DeleteMetric(mtype, name); err != nil {
+		return err
+	}
+
+	p.hub.PublishDeleted(mtype, name)
+	return nil
+}
+
+// publishCounter публикует итоговое значение счётчика, а не приращение.
+// Итог читается из хранилища, только если ряд кому-то нужен
+func (p *PublishingStorage) publishCounter(name string) {
+	if !p.hub.Interested(name) {
+		return
+	}
+	total, err := p.Storage.GetCounter(name)
+	if err != nil {
+		return
+	}
+	p.hub.Publish(models.Metrics{ID: name, MType: models.Counter, Delta: &total})
+}
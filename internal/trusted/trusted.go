@@ -0,0 +1,81 @@
+// Package trusted проверяет, что адрес клиента входит в доверенную подсеть.
+// Заголовкам X-Real-IP и X-Forwarded-For верят, только если соединение пришло от доверенного прокси
+package trusted
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Header — заголовок, в котором агент передаёт адрес своего исходящего интерфейса
+const Header = "X-Real-IP"
+
+// Checker хранит доверенную подсеть агентов и подсети прокси
+type Checker struct {
+	subnet  *net.IPNet
+	proxies []*net.IPNet
+}
+
+// New разбирает подсеть агентов и список подсетей прокси через запятую
+func New(subnet, proxies string) (*Checker, error) {
+	_, ipNet, err := net.ParseCIDR(strings.TrimSpace(subnet))
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted subnet: %w", err)
+	}
+	c := &Checker{subnet: ipNet}
+	for _, cidr := range strings.Split(proxies, ",") {
+		if cidr = strings.TrimSpace(cidr); cidr == "" {
+			continue
+		}
+		_, proxyNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy subnet: %w", err)
+		}
+		c.proxies = append(c.proxies, proxyNet)
+	}
+	return c, nil
+}
+
+// Allowed сообщает, входит ли адрес в доверенную подсеть
+func (c *Checker) Allowed(ip net.IP) bool {
+	return ip != nil && c.subnet.Contains(ip)
+}
+
+// ClientIP определяет адрес клиента: адрес соединения, а если соединение от доверенного прокси —
+// X-Real-IP или ближайший к серверу недоверенный адрес X-Forwarded-For
+func (c *Checker) ClientIP(remoteAddr, realIP, forwardedFor string) net.IP {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	peer := net.ParseIP(host)
+	if peer == nil || !c.isProxy(peer) {
+		return peer
+	}
+
+	if ip := net.ParseIP(strings.TrimSpace(realIP)); ip != nil {
+		return ip
+	}
+	hops := strings.Split(forwardedFor, ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(hops[i]))
+		if ip == nil {
+			break
+		}
+		if !c.isProxy(ip) {
+			return ip
+		}
+		peer = ip
+	}
+	return peer
+}
+
+func (c *Checker) isProxy(ip net.IP) bool {
+	for _, proxy := range c.proxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package trusted_test
+
+import (
+	"testing"
+
+	"github.com/akorablin/yandex-practicum-metrics/internal/trusted"
+)
+
+func TestClientIP(t *testing.T) {
+	checker, err := trusted.New("10.0.0.0/24", "127.0.0.0/8,192.168.1.0/24")
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		realIP       string
+		forwardedFor string
+		want         string
+		allowed      bool
+	}{
+		{"Прямое соединение из подсети", "10.0.0.5:51000", "", "", "10.0.0.5", true},
+		{"Прямое соединение вне подсети", "10.0.1.5:51000", "", "", "10.0.1.5", false},
+		{"Заголовок от недоверенного адреса игнорируется", "10.0.1.5:51000", "10.0.0.5", "", "10.0.1.5", false},
+		{"X-Real-IP от доверенного прокси", "127.0.0.1:51000", "10.0.0.7", "", "10.0.0.7", true},
+		{"X-Forwarded-For через цепочку прокси", "192.168.1.10:51000", "", "10.0.1.9, 10.0.0.8, 192.168.1.20", "10.0.0.8", true},
+		{"Прокси без заголовков", "127.0.0.1:51000", "", "", "127.0.0.1", false},
+		{"IPv6", "[::1]:51000", "", "", "::1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ip := checker.ClientIP(tt.remoteAddr, tt.realIP, tt.forwardedFor)
+			if ip.String() != tt.want {
+				t.Errorf("ClientIP() = %v, want %s", ip, tt.want)
+			}
+			if got := checker.Allowed(ip); got != tt.allowed {
+				t.Errorf("Allowed(%v) = %v, want %v", ip, got, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	if _, err := trusted.New("10.0.0.0", ""); err == nil {
+		t.Error("New() accepted subnet without mask")
+	}
+	if _, err := trusted.New("10.0.0.0/24", "proxy"); err == nil {
+		t.Error("New() accepted invalid proxy subnet")
+	}
+}
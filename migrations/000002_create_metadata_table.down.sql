@@ -0,0 +1 @@
+DROP TABLE IF EXISTS metadata;
\ No newline at end of file
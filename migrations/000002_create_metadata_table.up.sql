@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS metadata (
+    name VARCHAR(255) NOT NULL PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    unit VARCHAR(50) NOT NULL DEFAULT '',
+    owner VARCHAR(255) NOT NULL DEFAULT '',
+    mtype VARCHAR(50) NOT NULL DEFAULT '',
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
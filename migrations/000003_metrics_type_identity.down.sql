@@ -0,0 +1,5 @@
+-- Возврат к ключу по id невозможен при совпадающих именах: такие counters удаляются
+DELETE FROM metrics c USING metrics g
+WHERE c.id = g.id AND c.mtype = 'counter' AND g.mtype = 'gauge';
+ALTER TABLE metrics DROP CONSTRAINT IF EXISTS metrics_pkey;
+ALTER TABLE metrics ADD PRIMARY KEY (id);
@@ -0,0 +1,3 @@
+-- Метрика идентифицируется парой (mtype, id): gauge и counter с одним именем больше не перезаписывают друг друга
+ALTER TABLE metrics DROP CONSTRAINT IF EXISTS metrics_pkey;
+ALTER TABLE metrics ADD PRIMARY KEY (mtype, id);